@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.4;
+
+import "forge-std/Test.sol";
+import "../src/CartesianMerkleTree.sol";
+
+// Pins the priorities, roots and proofs in merkleGo/testdata/solidity_v1.json,
+// which the Go tree built by NewCartesianMerkleTreeSolidityV1 must reproduce.
+// Regenerate both together if the library changes.
+contract CartesianMerkleTreeFixturesTest is Test {
+    using CartesianMerkleTree for CartesianMerkleTree.UintCMT;
+
+    CartesianMerkleTree.UintCMT internal uintTreaple;
+
+    function setUp() public {
+        uintTreaple.initialize(40);
+    }
+
+    function testFixturePriorities() public {
+        uintTreaple.add(1);
+        uintTreaple.add(2);
+        uintTreaple.add(3);
+        assertEq(bytes32(uintTreaple.getNodeByKey(1).priority), bytes32(bytes16(0xb10e2d527612073b26eecdfd717e6a32)));
+        assertEq(bytes32(uintTreaple.getNodeByKey(2).priority), bytes32(bytes16(0x405787fa12a823e0f2b7631cc41b3ba8)));
+        assertEq(bytes32(uintTreaple.getNodeByKey(3).priority), bytes32(bytes16(0xc2575a0e9e593c00f959f8c92f12db28)));
+    }
+
+    function testFixtureRootsAndProofs() public {
+        bytes32[] memory siblings;
+
+        uintTreaple.add(7);
+        assertEq(uintTreaple.getRoot(), 0xe84ec332e3c2809f3fac920f7ba64b5510abb058ab01ec4418ccc94549cf47a1);
+        uintTreaple.add(14);
+        assertEq(uintTreaple.getRoot(), 0x0a0f2a92511a2a65db13cb83406c75372b8147e6bec368414a58eb9cd63b7b04);
+        uintTreaple.add(21);
+        assertEq(uintTreaple.getRoot(), 0x978620634a57ef5a2522b00f7f0db608569d77e1312fce2b45cf89493023c03e);
+        uintTreaple.add(5);
+        assertEq(uintTreaple.getRoot(), 0x274903fe81d7d304dd1b4d045e8eebcda6a41162552d4797d2ae9b9ff39d19f7);
+        uintTreaple.add(12);
+        assertEq(uintTreaple.getRoot(), 0xd845b533c4ec86281fde01fff4cb452f60c5d75329f3b9dfdde07205fd204b0e);
+        uintTreaple.add(19);
+        assertEq(uintTreaple.getRoot(), 0xadb6785561a560054526d79a010f57569b7b4ed37b8f1eb6851ea73644a3da44);
+        uintTreaple.add(3);
+        assertEq(uintTreaple.getRoot(), 0x7d7e901297324f6e00c5853f248054a85c21798b98bf5f61185c7efe07dbab25);
+        uintTreaple.add(10);
+        assertEq(uintTreaple.getRoot(), 0x9ccd8c210a0ccf37a8b5d4de7f864ffe7fd6a1ca1c13edfbc4ebf022c717ba8f);
+        uintTreaple.add(17);
+        assertEq(uintTreaple.getRoot(), 0x2e4763beee387e7b6c7179ec45418d115f679c34cf15e4f433c331bf11d23690);
+        uintTreaple.add(1);
+        assertEq(uintTreaple.getRoot(), 0x5b37d06df6b969715ef5ed6185e1be88f3a091b83fb217f4ee835be08d24cfea);
+        uintTreaple.add(8);
+        assertEq(uintTreaple.getRoot(), 0x6764fa12cce95ce8df4651eba2a6621d3dfc6495dd47984c20b797adcc24890d);
+        uintTreaple.add(15);
+        assertEq(uintTreaple.getRoot(), 0x522b0b37736a9d5bf04b38583352fafd371be04778ac02936732b0dfa017a42d);
+        uintTreaple.add(22);
+        assertEq(uintTreaple.getRoot(), 0xcc0579ae09ccf03162ed5612dcd922da835c5ce8a9ba0b361a76df4240cb4a71);
+        uintTreaple.add(6);
+        assertEq(uintTreaple.getRoot(), 0x1024d7bc5150a78d318a41dfed991e6464f12acc547387a1718ff92f188c52d3);
+        uintTreaple.add(13);
+        assertEq(uintTreaple.getRoot(), 0x04ef89541b4a330c46a0fe505fbdf276278bec22ba5c960292b07c3819b87a5d);
+        uintTreaple.add(20);
+        assertEq(uintTreaple.getRoot(), 0xd5dc52cdbabf7ca90acdb76af8bb661a97d0ac915b0a6f333b334bc8dd265b55);
+        uintTreaple.add(4);
+        assertEq(uintTreaple.getRoot(), 0x2e15d534df32d45a38e5d29b3f37dd0630816500fce5a01c3ec21fe5e002063b);
+        uintTreaple.add(11);
+        assertEq(uintTreaple.getRoot(), 0xb4ac3ff9b0268d673fff40636b0d54ebaaebf8236bb2b206629d1a393c619c3f);
+        uintTreaple.add(18);
+        assertEq(uintTreaple.getRoot(), 0xbc9878c6a728bf025b469e8559628b6f086de58f0154a3c2423db9351e699dcd);
+        uintTreaple.add(2);
+        assertEq(uintTreaple.getRoot(), 0xb883ec6060bfbe771cda3ae7256852bc703715e2c69f02092fbab6367f0479f8);
+        uintTreaple.add(9);
+        assertEq(uintTreaple.getRoot(), 0x9b6d6be790fe0926c35d563cc5694482475de68748f079996f30cef4640257ff);
+        uintTreaple.add(16);
+        assertEq(uintTreaple.getRoot(), 0x8e7c6ae19625c03d6ada0ca812d4fc0ad1eea31913f23db77231feee643a04a0);
+        siblings = new bytes32[](6);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xa75985555cc03518b86097b1626ba1e561ecc28df6c86e824f9c8ec4671b59b0;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = 0xe84ec332e3c2809f3fac920f7ba64b5510abb058ab01ec4418ccc94549cf47a1;
+        siblings[4] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[5] = 0xc8d31ff27c0ce315d015cc71da9792c2a1410eec3c88da8885f0c21954a75a23;
+        _assertProof(12, true, bytes32(0), siblings);
+
+        siblings = new bytes32[](4);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xcb48e8365cd396e13a4b617eae5917fa3f53f63ab2bae9e3c9ed967a54b0558f;
+        siblings[2] = 0x2c49fee34e2c6644570d1f008f4c27c0709c1b9a946be12685ec890ee577e217;
+        siblings[3] = 0x2eaa414c7c5119d6d5c0968883eb54c4502744fdde0e6a6e1824fba0331ca551;
+        _assertProof(3, true, bytes32(0), siblings);
+
+        siblings = new bytes32[](8);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xa75985555cc03518b86097b1626ba1e561ecc28df6c86e824f9c8ec4671b59b0;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = 0xe84ec332e3c2809f3fac920f7ba64b5510abb058ab01ec4418ccc94549cf47a1;
+        siblings[4] = bytes32(uint256(12));
+        siblings[5] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[6] = 0x6771c0da7ada40cc1382563d9f2addaec143c9b6c4091c12b11590595ff1c948;
+        siblings[7] = bytes32(0);
+        _assertProof(22, true, bytes32(0), siblings);
+
+        siblings = new bytes32[](8);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xa75985555cc03518b86097b1626ba1e561ecc28df6c86e824f9c8ec4671b59b0;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = 0xe84ec332e3c2809f3fac920f7ba64b5510abb058ab01ec4418ccc94549cf47a1;
+        siblings[4] = bytes32(uint256(12));
+        siblings[5] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[6] = 0x6771c0da7ada40cc1382563d9f2addaec143c9b6c4091c12b11590595ff1c948;
+        siblings[7] = bytes32(0);
+        _assertProof(23, false, bytes32(uint256(22)), siblings);
+
+        siblings = new bytes32[](8);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xa75985555cc03518b86097b1626ba1e561ecc28df6c86e824f9c8ec4671b59b0;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = 0xe84ec332e3c2809f3fac920f7ba64b5510abb058ab01ec4418ccc94549cf47a1;
+        siblings[4] = bytes32(uint256(12));
+        siblings[5] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[6] = 0x6771c0da7ada40cc1382563d9f2addaec143c9b6c4091c12b11590595ff1c948;
+        siblings[7] = bytes32(0);
+        _assertProof(100, false, bytes32(uint256(22)), siblings);
+
+        uintTreaple.remove(12);
+        assertEq(uintTreaple.getRoot(), 0xb81e1216904e4408a02304883e1c6919bfc4a9924a1ce7d3e350bd37ed8460f7);
+        uintTreaple.remove(7);
+        assertEq(uintTreaple.getRoot(), 0x0d61334ee1a7b43020ba4dfcf08dc3ea02a8c1591028fb7d9477dbabfcc9c52a);
+        uintTreaple.remove(22);
+        assertEq(uintTreaple.getRoot(), 0xbd9fb92be3083ceb1f6121544bffc5da9dbf1b2ef193e5349bf722dc0c3aa63a);
+        uintTreaple.remove(1);
+        assertEq(uintTreaple.getRoot(), 0x056590a59ef5eb341d1c8d336352948c6cdbcf1baf3656a1bc08d98bc75bff80);
+        uintTreaple.remove(15);
+        assertEq(uintTreaple.getRoot(), 0xc379abdb1c7c543f792c154020022e75f04ed02dfafd3836bd41bd25abbec538);
+        siblings = new bytes32[](4);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xdc93feb3f7d8afcabdf1382d6d60e349eb8f140175b83288ea3fb35a61619253;
+        siblings[2] = bytes32(0);
+        siblings[3] = 0xd4f0fbcaa5f5fb89aa3fc130e3bf17db2d8a753cd089deb23560f4ef097d38b8;
+        _assertProof(7, false, bytes32(uint256(8)), siblings);
+
+        siblings = new bytes32[](6);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xdc93feb3f7d8afcabdf1382d6d60e349eb8f140175b83288ea3fb35a61619253;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = bytes32(0);
+        siblings[4] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[5] = 0xb80ec124befafa0bcfb39b277fa52d77c2f98e8b2189559de1fd95531afebd2a;
+        _assertProof(13, true, bytes32(0), siblings);
+
+        siblings = new bytes32[](6);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0x5ff7ee754533c3a6608083e44eaa98d082c4ea402a37e4a31ab1dad45688ecd0;
+        siblings[2] = bytes32(uint256(3));
+        siblings[3] = 0x2eaa414c7c5119d6d5c0968883eb54c4502744fdde0e6a6e1824fba0331ca551;
+        siblings[4] = bytes32(0);
+        siblings[5] = bytes32(0);
+        _assertProof(2, true, bytes32(0), siblings);
+
+        siblings = new bytes32[](10);
+        siblings[0] = bytes32(uint256(6));
+        siblings[1] = 0xdc93feb3f7d8afcabdf1382d6d60e349eb8f140175b83288ea3fb35a61619253;
+        siblings[2] = bytes32(uint256(8));
+        siblings[3] = bytes32(0);
+        siblings[4] = bytes32(uint256(13));
+        siblings[5] = 0xdf791208ddf8dc46cc7f78631cee070efead9573ae7ccca688545a71ebd9f056;
+        siblings[6] = bytes32(uint256(20));
+        siblings[7] = 0xb3241d72519b63d1d4cda92e1a7063a3c9cc7affb73c7b115e51a90148cb2806;
+        siblings[8] = bytes32(0);
+        siblings[9] = bytes32(0);
+        _assertProof(50, false, bytes32(uint256(21)), siblings);
+    }
+
+    function _assertProof(uint256 key_, bool existence_, bytes32 nonExistenceKey_, bytes32[] memory siblings_)
+        internal
+        view
+    {
+        CartesianMerkleTree.Proof memory proof_ = uintTreaple.getProof(key_, 40);
+
+        assertEq(proof_.existence, existence_);
+        assertEq(proof_.nonExistenceKey, nonExistenceKey_);
+        assertEq(proof_.siblingsLength, siblings_.length);
+
+        for (uint256 i = 0; i < siblings_.length; i++) {
+            assertEq(proof_.siblings[i], siblings_[i]);
+        }
+    }
+}
@@ -2,7 +2,10 @@ module merkleTrees
 
 go 1.21.6
 
-require github.com/iden3/go-merkletree-sql/v2 v2.0.6
+require (
+	github.com/iden3/go-merkletree-sql/v2 v2.0.6
+	golang.org/x/crypto v0.7.0
+)
 
 require (
 	github.com/iden3/go-iden3-crypto v0.0.15 // indirect
@@ -4,7 +4,9 @@ import (
     "crypto/sha256"
     "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
+    "io"
     "log"
     "math/big"
     "net/http"
@@ -49,8 +51,39 @@ func main() {
     // Instead of (depth, proofSize, hashFunc), we now just instantiate our treap-based CMT:
     cmt := merkleGo.NewCartesianMerkleTree()
 
+    // Start the HTTP server
+    fmt.Println("Server running on port 8080")
+    log.Fatal(http.ListenAndServe(":8080", newMux(simpleTree, cmt)))
+}
+
+// maxImportBytes caps the body /cmt/import reads, so a client can't make the
+// server buffer an arbitrarily large upload
+const maxImportBytes = 64 << 20
+
+// newMux registers every route on a fresh ServeMux, so the handlers can be
+// exercised with httptest without starting a server
+func newMux(simpleTree *merkleGo.SimpleMerkleTree, cmt *merkleGo.CartesianMerkleTree) *http.ServeMux {
+    mux := http.NewServeMux()
+
     // ROUTES FOR Simple Merkle Tree (unchanged)
-    http.HandleFunc("/simple/add", func(w http.ResponseWriter, r *http.Request) {
+    mux.HandleFunc("/simple/add", handleSimpleAdd(simpleTree))
+    mux.HandleFunc("/simple/proof", handleSimpleProof(simpleTree))
+
+    // ---------------------
+    // ROUTES FOR Treap-based Cartesian Merkle Tree
+    // ---------------------
+    mux.HandleFunc("/cmt/add", handleCMTAdd(cmt))
+    mux.HandleFunc("/cmt/remove", handleCMTRemove(cmt))
+    mux.HandleFunc("/cmt/proof", handleCMTProof(cmt))
+    mux.HandleFunc("/cmt/proofs", handleCMTProofs(cmt))
+    mux.HandleFunc("/cmt/export", handleCMTExport(cmt))
+    mux.HandleFunc("/cmt/import", handleCMTImport(cmt))
+    return mux
+}
+
+// /simple/add: Insert a fixed key and value into the Simple Merkle Tree
+func handleSimpleAdd(simpleTree *merkleGo.SimpleMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
         key := big.NewInt(1)
         value := big.NewInt(100)
 
@@ -72,9 +105,12 @@ func main() {
                 "root":  fmt.Sprintf("%x", root),
             },
         })
-    })
+    }
+}
 
-    http.HandleFunc("/simple/proof", func(w http.ResponseWriter, r *http.Request) {
+// /simple/proof: Generate a proof for the fixed key, then verify it
+func handleSimpleProof(simpleTree *merkleGo.SimpleMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
         key := big.NewInt(1)
         value := big.NewInt(100)
 
@@ -95,20 +131,23 @@ func main() {
                 "valid": valid,
             },
         })
-    })
-
-    // ---------------------
-    // ROUTES FOR Treap-based Cartesian Merkle Tree
-    // ---------------------
+    }
+}
 
-    // /cmt/add: Insert a string "key" into our Treap
-    http.HandleFunc("/cmt/add", func(w http.ResponseWriter, r *http.Request) {
+// /cmt/add: Insert a string "key" into our Treap
+func handleCMTAdd(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
         // For demonstration, let's add a fixed key, e.g. "hello"
         keyStr := "hello"
 
-        err := cmt.Add([]byte(keyStr))
+        inserted, err := cmt.Add([]byte(keyStr))
         if err != nil {
-            writeJSONResponse(w, http.StatusInternalServerError, Response{
+            status := http.StatusInternalServerError
+            if errors.Is(err, merkleGo.ErrTreeFull) {
+                // backpressure: the client may retry once keys are removed
+                status = http.StatusServiceUnavailable
+            }
+            writeJSONResponse(w, status, Response{
                 Message: "Failed to add to Cartesian Merkle Tree",
                 Error:   err.Error(),
             })
@@ -119,14 +158,17 @@ func main() {
         writeJSONResponse(w, http.StatusOK, Response{
             Message: "Added to Cartesian Merkle Tree",
             Data: map[string]interface{}{
-                "key":  keyStr,
-                "root": hex.EncodeToString(root),
+                "key":      keyStr,
+                "inserted": inserted,
+                "root":     hex.EncodeToString(root),
             },
         })
-    })
+    }
+}
 
-    // /cmt/remove: Remove a given key from the Treap
-    http.HandleFunc("/cmt/remove", func(w http.ResponseWriter, r *http.Request) {
+// /cmt/remove: Remove a given key from the Treap
+func handleCMTRemove(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
         keyStr := "hello"
 
         err := cmt.Remove([]byte(keyStr))
@@ -146,14 +188,18 @@ func main() {
                 "root": hex.EncodeToString(root),
             },
         })
-    })
+    }
+}
 
-    // /cmt/proof: Generate a proof for a given key, then verify it
-    http.HandleFunc("/cmt/proof", func(w http.ResponseWriter, r *http.Request) {
+// /cmt/proof: Generate a proof for a given key, then verify it
+func handleCMTProof(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
         keyStr := "hello"
 
-        // GenerateProof returns a struct with siblings, existence, etc.
-        proof, err := cmt.GenerateProof([]byte(keyStr))
+        // GenerateAndVerify returns a struct with siblings, existence, etc.,
+        // verified against the same root under one lock: verifying separately
+        // could fail if another request changed the tree in between
+        proof, valid, root, err := cmt.GenerateAndVerify([]byte(keyStr))
         if err != nil {
             writeJSONResponse(w, http.StatusInternalServerError, Response{
                 Message: "Failed to generate proof for Cartesian Merkle Tree",
@@ -162,20 +208,131 @@ func main() {
             return
         }
 
-        // Then we demonstrate local verification
-        valid := cmt.VerifyProof([]byte(keyStr), proof)
-
         writeJSONResponse(w, http.StatusOK, Response{
             Message: "Generated proof for Cartesian Merkle Tree",
             Data: map[string]interface{}{
                 "key":   keyStr,
                 "proof": proof,
                 "valid": valid,
+                "root":  hex.EncodeToString(root),
             },
         })
-    })
+    }
+}
 
-    // Start the HTTP server
-    fmt.Println("Server running on port 8080")
-    log.Fatal(http.ListenAndServe(":8080", nil))
+// /cmt/proofs: Stream the proof of every key as NDJSON, one {"key","proof"} line each.
+// The root they all verify against is sent in the X-CMT-Root trailer once the stream ends;
+// a stream cut short by a proof error carries X-CMT-Error instead.
+func handleCMTProofs(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            writeJSONResponse(w, http.StatusMethodNotAllowed, Response{
+                Message: "Use GET to stream Cartesian Merkle Tree proofs",
+            })
+            return
+        }
+
+        // stream from a copy: EachProof holds the read lock of the tree it
+        // walks, and a slow client must not keep the live tree's writers waiting.
+        // Proofs are encoded one at a time, none is kept once written.
+        type keyProof struct {
+            Key   string          `json:"key"`
+            Proof *merkleGo.Proof `json:"proof"`
+        }
+        w.Header().Set("Content-Type", "application/x-ndjson")
+        w.Header().Set("Trailer", "X-CMT-Root, X-CMT-Error")
+        flusher, _ := w.(http.Flusher)
+        enc := json.NewEncoder(w)
+        written := 0
+        var writeErr error
+        root, err := cmt.Clone().EachProof(func(key []byte, proof *merkleGo.Proof) bool {
+            if writeErr = enc.Encode(keyProof{hex.EncodeToString(key), proof}); writeErr != nil {
+                // client went away
+                return false
+            }
+            written++
+            if flusher != nil {
+                flusher.Flush()
+            }
+            return true
+        })
+        switch {
+        case writeErr != nil:
+            return
+        case err != nil && written == 0:
+            w.Header().Del("Trailer")
+            writeJSONResponse(w, http.StatusInternalServerError, Response{
+                Message: "Failed to generate Cartesian Merkle Tree proofs",
+                Error:   err.Error(),
+            })
+        case err != nil:
+            // too late for a status: the missing root tells the client the stream is incomplete
+            w.Header().Set("X-CMT-Error", err.Error())
+        default:
+            w.Header().Set("X-CMT-Root", hex.EncodeToString(root))
+        }
+    }
+}
+
+// /cmt/export: Download the whole tree (entries + root) as JSON
+func handleCMTExport(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet {
+            writeJSONResponse(w, http.StatusMethodNotAllowed, Response{
+                Message: "Use GET to export the Cartesian Merkle Tree",
+            })
+            return
+        }
+
+        // Marshal before writing anything, so a failure can still be reported as a 500
+        data, err := json.Marshal(cmt)
+        if err != nil {
+            writeJSONResponse(w, http.StatusInternalServerError, Response{
+                Message: "Failed to export Cartesian Merkle Tree",
+                Error:   err.Error(),
+            })
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Content-Disposition", `attachment; filename="cmt.json"`)
+        w.Write(append(data, '\n'))
+    }
+}
+
+// /cmt/import: Replace the tree with one produced by /cmt/export.
+// The live tree is only swapped once the whole body decoded and its root checked out.
+func handleCMTImport(cmt *merkleGo.CartesianMerkleTree) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            writeJSONResponse(w, http.StatusMethodNotAllowed, Response{
+                Message: "Use POST to import a Cartesian Merkle Tree",
+            })
+            return
+        }
+
+        body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+        if err == nil {
+            err = cmt.UnmarshalJSON(body)
+        }
+        if err != nil {
+            status := http.StatusBadRequest
+            var tooLarge *http.MaxBytesError
+            if errors.As(err, &tooLarge) {
+                status = http.StatusRequestEntityTooLarge
+            }
+            writeJSONResponse(w, status, Response{
+                Message: "Failed to import Cartesian Merkle Tree",
+                Error:   err.Error(),
+            })
+            return
+        }
+
+        writeJSONResponse(w, http.StatusOK, Response{
+            Message: "Imported Cartesian Merkle Tree",
+            Data: map[string]interface{}{
+                "root": hex.EncodeToString(cmt.GetRoot()),
+            },
+        })
+    }
 }
@@ -0,0 +1,53 @@
+package merkleGo
+
+// AffectedKeysByAdd returns the present keys whose proofs would change if key
+// were inserted: every key if key is absent, none if it is already present.
+//
+// There is no smaller exact answer. A proof folds up to the root, so once the
+// root changes no old proof verifies any more, and every key's proof does carry
+// a changed hash: where its path leaves the insertion path, the sibling it
+// records is the subtree that received the new key (or, for keys on that path,
+// one of its own children). A proof cache can only be selective about non-root
+// changes, which inserts and removals never are.
+func (cmt *CartesianMerkleTree) AffectedKeysByAdd(key []byte) [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if len(key) == 0 || cmt.find(key) != nil {
+		return nil
+	}
+	return cmt.keys()
+}
+
+// AffectedKeysByRemove returns the keys whose proofs would change if key were
+// removed: every other key if key is present, none otherwise (see AffectedKeysByAdd).
+func (cmt *CartesianMerkleTree) AffectedKeysByRemove(key []byte) [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	key = cmt.treeKey(key)
+	if cmt.find(key) == nil {
+		return nil
+	}
+	var out [][]byte
+	for _, k := range cmt.keys() {
+		if cmt.compareKeys(k, key) != 0 {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// keys returns every key in ascending order
+func (cmt *CartesianMerkleTree) keys() [][]byte {
+	var out [][]byte
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		out = append(out, node.Key)
+		return true
+	})
+	return out
+}
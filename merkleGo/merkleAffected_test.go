@@ -0,0 +1,55 @@
+package merkleGo
+
+import (
+	"reflect"
+	"testing"
+)
+
+// changedProofs returns, in ascending order, the keys of before whose proofs
+// differ in after (or are gone from it)
+func changedProofs(before, after *CartesianMerkleTree) [][]byte {
+	var out [][]byte
+	for _, key := range before.Keys() {
+		old, _ := before.GenerateProof(key)
+		now, _ := after.GenerateProof(key)
+		if !reflect.DeepEqual(old, now) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+func TestAffectedKeys(t *testing.T) {
+	keys := strKeys(40)
+	cmt := buildTree(t, keys)
+
+	added := buildTree(t, append(append([][]byte{}, keys...), []byte("new")))
+	if got, want := cmt.AffectedKeysByAdd([]byte("new")), changedProofs(cmt, added); !reflect.DeepEqual(got, want) {
+		t.Fatalf("add: reported %q, proofs changed for %q", got, want)
+	}
+	if got := cmt.AffectedKeysByAdd(keys[5]); got != nil {
+		t.Fatalf("adding a present key affects %q", got)
+	}
+
+	for _, removed := range [][]byte{keys[5], cmt.Root.Key} {
+		after := buildTree(t, keys)
+		if err := after.Remove(removed); err != nil {
+			t.Fatal(err)
+		}
+		want := changedProofs(cmt, after)
+		// the removed key's own proof turns into an exclusion proof, which
+		// AffectedKeysByRemove leaves out
+		for i, key := range want {
+			if string(key) == string(removed) {
+				want = append(want[:i], want[i+1:]...)
+				break
+			}
+		}
+		if got := cmt.AffectedKeysByRemove(removed); !reflect.DeepEqual(got, want) {
+			t.Fatalf("remove %s: reported %q, proofs changed for %q", removed, got, want)
+		}
+	}
+	if got := cmt.AffectedKeysByRemove([]byte("absent")); got != nil {
+		t.Fatalf("removing an absent key affects %q", got)
+	}
+}
@@ -0,0 +1,114 @@
+package merkleGo
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// ProofArchive stores many proofs with their siblings deduplicated: every
+// distinct sibling is kept once in Hashes, sorted bytewise so the dictionary
+// only depends on the set of proofs, and each proof refers to its siblings by
+// index. Proofs of the same tree share their upper siblings (the root's
+// children and every key near the root), so bulk archives shrink a lot.
+type ProofArchive struct {
+	Hashes [][]byte
+	Proofs []ArchivedProof
+}
+
+// ArchivedProof is a Proof whose siblings are indexes into ProofArchive.Hashes
+type ArchivedProof struct {
+	Existence       bool
+	Key             []byte
+	Value           []byte
+	Siblings        []int    // index of each sibling in Hashes, in the original order
+	Sizes           []uint64 // carried over untouched, see Proof.Sizes
+	NonExistenceKey []byte   // carried over untouched, see Proof.NonExistenceKey
+}
+
+// ArchiveProofs builds the archive of proofs, in the same order.
+// Sibling order is kept as is, so proofs come back exactly as given.
+func ArchiveProofs(proofs []*Proof) *ProofArchive {
+	seen := make(map[string]bool)
+	var hashes [][]byte
+	for _, p := range proofs {
+		for _, s := range p.Siblings {
+			if !seen[string(s)] {
+				seen[string(s)] = true
+				hashes = append(hashes, s)
+			}
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i], hashes[j]) < 0 })
+	index := make(map[string]int, len(hashes))
+	for i, h := range hashes {
+		index[string(h)] = i
+	}
+
+	a := &ProofArchive{Hashes: hashes, Proofs: make([]ArchivedProof, len(proofs))}
+	for i, p := range proofs {
+		ap := ArchivedProof{
+			Existence:       p.Existence,
+			Key:             p.Key,
+			Value:           p.Value,
+			Siblings:        make([]int, len(p.Siblings)),
+			Sizes:           p.Sizes,
+			NonExistenceKey: p.NonExistenceKey,
+		}
+		for j, s := range p.Siblings {
+			ap.Siblings[j] = index[string(s)]
+		}
+		a.Proofs[i] = ap
+	}
+	return a
+}
+
+// Proof reconstructs the i-th archived proof. The siblings are shared with the
+// archive, so they must not be modified.
+func (a *ProofArchive) Proof(i int) (*Proof, error) {
+	if i < 0 || i >= len(a.Proofs) {
+		return nil, fmt.Errorf("proof %d out of range [0, %d)", i, len(a.Proofs))
+	}
+	ap := &a.Proofs[i]
+	p := &Proof{
+		Existence:       ap.Existence,
+		Key:             ap.Key,
+		Value:           ap.Value,
+		Siblings:        make([][]byte, len(ap.Siblings)),
+		Sizes:           ap.Sizes,
+		NonExistenceKey: ap.NonExistenceKey,
+	}
+	for j, idx := range ap.Siblings {
+		if idx < 0 || idx >= len(a.Hashes) {
+			return nil, fmt.Errorf("proof %d: sibling %d refers to hash %d of %d", i, j, idx, len(a.Hashes))
+		}
+		p.Siblings[j] = a.Hashes[idx]
+	}
+	return p, nil
+}
+
+// Expand reconstructs every archived proof, in order
+func (a *ProofArchive) Expand() ([]*Proof, error) {
+	proofs := make([]*Proof, len(a.Proofs))
+	for i := range a.Proofs {
+		p, err := a.Proof(i)
+		if err != nil {
+			return nil, err
+		}
+		proofs[i] = p
+	}
+	return proofs, nil
+}
+
+// DedupRatio returns how many siblings the archived proofs hold per distinct
+// hash stored: 1 means nothing was shared, 0 for an archive without siblings
+func (a *ProofArchive) DedupRatio() float64 {
+	if len(a.Hashes) == 0 {
+		return 0
+	}
+	total := 0
+	for _, ap := range a.Proofs {
+		total += len(ap.Siblings)
+	}
+	return float64(total) / float64(len(a.Hashes))
+}
@@ -0,0 +1,46 @@
+package merkleGo
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+func TestProofArchive(t *testing.T) {
+	keys := strKeys(1000)
+	cmt := buildTree(t, keys)
+	proofs := make([]*Proof, len(keys))
+	for i, key := range keys {
+		proofs[i], _ = cmt.GenerateProof(key)
+	}
+
+	archive := ArchiveProofs(proofs)
+	ratio := archive.DedupRatio()
+	t.Logf("%d proofs, %d distinct siblings, dedup ratio %.1f", len(proofs), len(archive.Hashes), ratio)
+	// every node's key and hash appear in its descendants' proofs, so the
+	// dictionary is bounded by the nodes while the proofs grow with the depth
+	if len(archive.Hashes) > 2*len(keys) || ratio < 5 {
+		t.Fatalf("%d distinct siblings, ratio %.1f", len(archive.Hashes), ratio)
+	}
+	if !sort.SliceIsSorted(archive.Hashes, func(i, j int) bool { return bytes.Compare(archive.Hashes[i], archive.Hashes[j]) < 0 }) {
+		t.Fatal("dictionary isn't sorted")
+	}
+
+	expanded, err := archive.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, proof := range expanded {
+		if !proofsEqual(proof, proofs[i]) || !cmt.VerifyProof(keys[i], proof) {
+			t.Fatalf("%s: reconstructed proof differs or doesn't verify", keys[i])
+		}
+	}
+
+	if _, err := archive.Proof(len(keys)); err == nil {
+		t.Fatal("proof out of range")
+	}
+	archive.Proofs[0].Siblings[0] = len(archive.Hashes)
+	if _, err := archive.Proof(0); err == nil {
+		t.Fatal("sibling index out of range")
+	}
+}
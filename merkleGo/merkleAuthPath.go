@@ -0,0 +1,90 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// AuthNode is one step of an authentication path, see Proof.AuthPath
+type AuthNode struct {
+	Hash   []byte // sibling hash
+	IsLeft bool   // Hash comes before the running hash in the node's hash input
+	Entry  []byte // entry of the node hashed at this step, nil on the first element
+}
+
+// AuthPath flattens an inclusion proof (TopDown, from a tree using the default
+// hasher) into the steps a plain Merkle verifier folds, leaf to root:
+//
+//	h := path[0].Hash // the proven node's first child
+//	for _, n := range path[1:] {
+//		a, b := h, n.Hash
+//		if n.IsLeft { a, b = b, a }
+//		h = sha256(n.Entry || a || b)
+//	}
+//
+// Each node still has to be prefixed with its entry, so the ancestors' entries
+// ride along; path[1].Entry is the proven node's own, which a verifier should
+// check against the key (and value) it expects. CMT nodes order their two
+// children by value, not by side, which is what IsLeft records.
+// nil for exclusion or malformed proofs.
+func (p *Proof) AuthPath() []AuthNode {
+	return p.AuthPathWithHasher(nil)
+}
+
+// AuthPathWithHasher is AuthPath for proofs of trees using another 3-arg
+// hasher (nil means the default): the IsLeft flags are worked out with it, and
+// the fold must then hash each step the way it does instead of plain sha256.
+func (p *Proof) AuthPathWithHasher(hasher func(a, b, c []byte) []byte) []AuthNode {
+	if p == nil || !p.Existence {
+		return nil
+	}
+	n := len(p.Siblings)
+	if n < 2 || n%2 != 0 || (len(p.Sizes) > 0 && len(p.Sizes) != n/2) {
+		return nil
+	}
+	if hasher == nil {
+		hasher = default3ArgHash
+	}
+	entryAt := func(entry []byte, level int) []byte {
+		if len(p.Sizes) > 0 {
+			return sizedEntry(entry, p.Sizes[level])
+		}
+		return entry
+	}
+
+	left, right := p.Siblings[n-2], p.Siblings[n-1]
+	leaf := entryAt(nodeEntry(p.Key, p.Value), n/2-1)
+	path := make([]AuthNode, 0, n/2+1)
+	path = append(path,
+		AuthNode{Hash: left},
+		AuthNode{Hash: right, IsLeft: bytes.Compare(right, left) < 0, Entry: leaf},
+	)
+	h := hasher(leaf, left, right)
+	for idx := n - 4; idx >= 0; idx -= 2 {
+		entry, sib := entryAt(p.Siblings[idx], idx/2), p.Siblings[idx+1]
+		path = append(path, AuthNode{Hash: sib, IsLeft: bytes.Compare(sib, h) < 0, Entry: entry})
+		h = hasher(entry, h, sib)
+	}
+	return path
+}
+
+// FoldAuthPath folds a path from AuthPath exactly as documented there, with
+// plain sha256 and no sorting, and returns the root it leads to
+func FoldAuthPath(path []AuthNode) []byte {
+	if len(path) < 2 {
+		return nil
+	}
+	h := path[0].Hash
+	for _, node := range path[1:] {
+		a, b := h, node.Hash
+		if node.IsLeft {
+			a, b = b, a
+		}
+		sum := sha256.New()
+		sum.Write(node.Entry)
+		sum.Write(a)
+		sum.Write(b)
+		h = sum.Sum(nil)
+	}
+	return h
+}
@@ -0,0 +1,46 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestAuthPathFoldsToRoot(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		keys := strKeys(40)
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, keys)
+		root := cmt.GetRoot()
+		for _, key := range keys {
+			proof, err := cmt.GenerateProof(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := proof.AuthPath()
+			if !bytes.Equal(FoldAuthPath(path), root) {
+				t.Fatalf("CommitSize %v, %s: FoldAuthPath doesn't give the root", commitSize, key)
+			}
+			// the fold as the doc comment spells it out
+			h := path[0].Hash
+			for _, n := range path[1:] {
+				a, b := h, n.Hash
+				if n.IsLeft {
+					a, b = b, a
+				}
+				sum := sha256.Sum256(append(append(append([]byte{}, n.Entry...), a...), b...))
+				h = sum[:]
+			}
+			if !bytes.Equal(h, root) {
+				t.Fatalf("CommitSize %v, %s: documented fold doesn't give the root", commitSize, key)
+			}
+		}
+	}
+
+	cmt := buildTree(t, strKeys(10))
+	absent, _ := cmt.GenerateProof([]byte("absent"))
+	if absent.AuthPath() != nil || (*Proof)(nil).AuthPath() != nil {
+		t.Fatal("AuthPath of an exclusion or nil proof")
+	}
+}
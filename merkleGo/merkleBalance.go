@@ -0,0 +1,77 @@
+package merkleGo
+
+// BalanceStrategy selects how a tree keeps its shape balanced
+type BalanceStrategy int
+
+const (
+	// TreapBalancing orders nodes by priority (and weight) like the contract:
+	// the shape, hence the root, depends only on the keys, and is balanced in
+	// expectation (O(log n) height with high probability).
+	TreapBalancing BalanceStrategy = iota
+	// SizeBalanced keeps a size-balanced tree instead, ignoring priorities and
+	// weights: every subtree is at least as large as its sibling's children,
+	// which bounds the height by about 1.44·log2(n) even for adversarial keys.
+	// Hashing and proofs are unchanged, but the shape depends on the order of
+	// insertions, so roots can't be reproduced on-chain or by re-inserting the
+	// keys (MarshalJSON/UnmarshalJSON round trips fail their root check).
+	// Removals don't rebalance, the height stays bounded by the largest size
+	// the tree ever reached.
+	SizeBalanced
+)
+
+// maintain restores the size-balanced invariant at node after an insert into
+// its right subtree (right) or left subtree, and returns the subtree's new root
+func (cmt *CartesianMerkleTree) maintain(node *TreapNode, right bool) *TreapNode {
+	if node == nil {
+		return nil
+	}
+	if !right {
+		if node.Left == nil {
+			return node
+		}
+		switch {
+		case sizeOf(node.Left.Left) > sizeOf(node.Right):
+			node = cmt.rotateRight(node)
+		case sizeOf(node.Left.Right) > sizeOf(node.Right):
+			node.Left = cmt.rotateLeft(node.Left)
+			node = cmt.rotateRight(node)
+		default:
+			return node
+		}
+	} else {
+		if node.Right == nil {
+			return node
+		}
+		switch {
+		case sizeOf(node.Right.Right) > sizeOf(node.Left):
+			node = cmt.rotateLeft(node)
+		case sizeOf(node.Right.Left) > sizeOf(node.Left):
+			node.Right = cmt.rotateRight(node.Right)
+			node = cmt.rotateLeft(node)
+		default:
+			return node
+		}
+	}
+	node.Left = cmt.maintain(node.Left, false)
+	node.Right = cmt.maintain(node.Right, true)
+	node.Size = subtreeSize(node)
+	node.MerkleHash = cmt.computeMerkleHash(node)
+	node = cmt.maintain(node, false)
+	return cmt.maintain(node, true)
+}
+
+// promoteRight tells remove which child of a two-child node to rotate up:
+// the higher ranked one for treaps, the larger one for size-balanced trees
+func (cmt *CartesianMerkleTree) promoteRight(node *TreapNode) bool {
+	if cmt.Balancing == SizeBalanced {
+		return sizeOf(node.Right) > sizeOf(node.Left)
+	}
+	return cmt.outranks(node.Right, node.Left)
+}
+
+func sizeOf(node *TreapNode) int {
+	if node == nil {
+		return 0
+	}
+	return node.Size
+}
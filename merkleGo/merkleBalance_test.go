@@ -0,0 +1,32 @@
+package merkleGo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizeBalancedHeight(t *testing.T) {
+	const n = 4096
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = uintKey(int64(i))
+	}
+	bound := int(1.44*math.Log2(n+2)) + 1
+	for name, order := range map[string][][]byte{"ascending": keys, "descending": reversed(keys)} {
+		cmt := NewCartesianMerkleTree()
+		cmt.Balancing = SizeBalanced
+		// priorities that would make a treap a single path
+		cmt.PriorityFunc = func(key []byte) []byte { return key }
+		fillTree(t, cmt, order)
+		mustValidate(t, cmt)
+		if h := cmt.Height(); h > bound {
+			t.Fatalf("%s: height %d, bound %d", name, h, bound)
+		}
+		for _, key := range keys[:50] {
+			proof, _ := cmt.GenerateProof(key)
+			if !cmt.VerifyProof(key, proof) {
+				t.Fatalf("%s: proof of %x doesn't verify", name, key)
+			}
+		}
+	}
+}
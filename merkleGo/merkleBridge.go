@@ -0,0 +1,147 @@
+package merkleGo
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-merkletree-sql/v2"
+)
+
+// Endianness selects the byte order of keys converted from big.Int.
+// Keys are compared as raw bytes, so only BigEndian keeps the numeric order:
+// with LittleEndian the least significant byte decides the tree order.
+type Endianness int
+
+const (
+	BigEndian Endianness = iota
+	LittleEndian
+)
+
+// KeyFromBigInt converts an iden3 big.Int key into the canonical CMT key:
+// 32 bytes, big-endian, left-padded with zeros (same as bytes32(uint256) in Solidity)
+func KeyFromBigInt(k *big.Int) []byte {
+	return keyFromBigInt(k, BigEndian)
+}
+
+// BigIntFromKey is the inverse of KeyFromBigInt
+func BigIntFromKey(key []byte) *big.Int {
+	return bigIntFromKey(key, BigEndian)
+}
+
+// KeyFromBigInt converts k using the tree's KeyEndianness (BigEndian on a nil tree)
+func (cmt *CartesianMerkleTree) KeyFromBigInt(k *big.Int) []byte {
+	return keyFromBigInt(k, cmt.endianness())
+}
+
+// BigIntFromKey converts key back using the tree's KeyEndianness (BigEndian on a nil tree)
+func (cmt *CartesianMerkleTree) BigIntFromKey(key []byte) *big.Int {
+	return bigIntFromKey(key, cmt.endianness())
+}
+
+func (cmt *CartesianMerkleTree) endianness() Endianness {
+	if cmt == nil {
+		return BigEndian
+	}
+	return cmt.KeyEndianness
+}
+
+func keyFromBigInt(k *big.Int, order Endianness) []byte {
+	key := k.FillBytes(make([]byte, 32))
+	if order == LittleEndian {
+		reverseBytes(key)
+	}
+	return key
+}
+
+func bigIntFromKey(key []byte, order Endianness) *big.Int {
+	if order == LittleEndian {
+		key = append([]byte{}, key...)
+		reverseBytes(key)
+	}
+	return new(big.Int).SetBytes(key)
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+// FromSimpleMerkleTree builds a new CartesianMerkleTree holding every entry of
+// the iden3 tree: keys are converted with KeyFromBigInt, and each value is
+// stored with AddKV as its 32-byte big-endian form (bytes32(uint256) in Solidity).
+// The two roots will NOT match: the SMT hashes (key, value) leaves with poseidon
+// in a sparse binary layout, while the CMT hashes entries into a treap with sha256.
+func FromSimpleMerkleTree(ctx context.Context, smt *SimpleMerkleTree) (*CartesianMerkleTree, error) {
+	var keys, values []*big.Int
+	err := smt.MerkleTree.Walk(ctx, nil, func(n *merkletree.Node) {
+		if n.Type == merkletree.NodeTypeLeaf {
+			keys = append(keys, n.Entry[0].BigInt())
+			values = append(values, n.Entry[1].BigInt())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmt := NewCartesianMerkleTree()
+	for i, k := range keys {
+		if _, err := cmt.AddKV(cmt.KeyFromBigInt(k), values[i].FillBytes(make([]byte, 32))); err != nil {
+			return nil, err
+		}
+	}
+	return cmt, nil
+}
+
+// VerifyProofAgainstRootBigInt is VerifyProofAgainstRoot for a root held as a
+// big.Int (as SimpleMerkleTree roots are): the root is widened back to its
+// 32-byte big-endian form first, restoring any leading zero bytes. Roots that
+// are negative or don't fit in 32 bytes never verify.
+func VerifyProofAgainstRootBigInt(key []byte, proof *Proof, root *big.Int, hasher func(a, b, c []byte) []byte) bool {
+	if root == nil || root.Sign() < 0 || root.BitLen() > 256 {
+		return false
+	}
+	return VerifyProofAgainstRoot(key, proof, root.FillBytes(make([]byte, 32)), hasher)
+}
+
+// RootsComparable reports whether a CMT and an iden3 SimpleMerkleTree commit to
+// the same data, with a reason either way. The two roots are never byte-equal,
+// even over identical data: the SMT hashes (key, value) leaves with poseidon in a
+// sparse binary layout, the CMT hashes keys into a treap with sha256. What can be
+// checked is that both hold the same key set once each CMT key is read as an
+// iden3 key (BigIntFromKey with the tree's KeyEndianness); ok is true only then.
+// Values are not compared: the CMT may hold none, or hold them pre-hashed.
+func RootsComparable(cmt *CartesianMerkleTree, smt *SimpleMerkleTree) (bool, string) {
+	if cmt == nil || smt == nil || smt.MerkleTree == nil {
+		return false, "not comparable: nil tree"
+	}
+	const differ = "roots differ by construction (poseidon sparse tree vs sha256 treap)"
+
+	smtKeys := make(map[string]bool)
+	err := smt.MerkleTree.Walk(context.Background(), nil, func(n *merkletree.Node) {
+		if n.Type == merkletree.NodeTypeLeaf {
+			smtKeys[n.Entry[0].BigInt().String()] = true
+		}
+	})
+	if err != nil {
+		return false, "not comparable: walking the SMT failed: " + err.Error()
+	}
+
+	cmt.mu.RLock()
+	keys := cmt.keys()
+	cmt.mu.RUnlock()
+
+	if len(keys) != len(smtKeys) {
+		return false, fmt.Sprintf("not comparable: CMT holds %d keys, SMT holds %d; %s", len(keys), len(smtKeys), differ)
+	}
+	for _, key := range keys {
+		if len(key) > 32 {
+			return false, fmt.Sprintf("not comparable: CMT key %x is wider than 32 bytes and has no iden3 form; %s", key, differ)
+		}
+		if !smtKeys[cmt.BigIntFromKey(key).String()] {
+			return false, fmt.Sprintf("not comparable: CMT key %x is missing from the SMT; %s", key, differ)
+		}
+	}
+	return true, fmt.Sprintf("same %d keys, so the trees correspond; %s, compare key sets rather than roots", len(keys), differ)
+}
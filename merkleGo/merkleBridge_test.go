@@ -0,0 +1,137 @@
+package merkleGo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"hash"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestFromSimpleMerkleTreeMigratesValues(t *testing.T) {
+	ctx := context.Background()
+	smt, err := NewSimpleMerkleTree(40, func(data []byte) []byte {
+		hash := sha256.Sum256(data)
+		return hash[:]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[int64]int64{1: 100, 7: 0, 42: 1 << 40}
+	for k, v := range entries {
+		if err := smt.Add(ctx, big.NewInt(k), big.NewInt(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmt, err := FromSimpleMerkleTree(ctx, smt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmt.Size() != len(entries) {
+		t.Fatalf("size %d, want %d", cmt.Size(), len(entries))
+	}
+	for k, v := range entries {
+		value, ok := cmt.Get(uintKey(k))
+		if !ok || !bytes.Equal(value, uintKey(v)) {
+			t.Errorf("key %d: value %x (present %v), want %x", k, value, ok, uintKey(v))
+		}
+	}
+	mustValidate(t, cmt)
+}
+
+func TestKeyEndianness(t *testing.T) {
+	numbers := []int64{1, 256, 65535}
+	orders := map[Endianness][]int64{
+		BigEndian:    {1, 256, 65535},
+		LittleEndian: {256, 1, 65535}, // 00 01 .., 01 00 .., ff ff ..
+	}
+	for order, want := range orders {
+		cmt := NewCartesianMerkleTree()
+		cmt.KeyEndianness = order
+		for _, n := range numbers {
+			key := cmt.KeyFromBigInt(big.NewInt(n))
+			if got := cmt.BigIntFromKey(key); got.Int64() != n {
+				t.Fatalf("order %d: %d round-trips to %s", order, n, got)
+			}
+			if _, err := cmt.Add(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, key := range cmt.Keys() {
+			if got := cmt.BigIntFromKey(key).Int64(); got != want[i] {
+				t.Fatalf("order %d: key %d is %d, want %d", order, i, got, want[i])
+			}
+		}
+	}
+	if !bytes.Equal(KeyFromBigInt(big.NewInt(1)), NewCartesianMerkleTree().KeyFromBigInt(big.NewInt(1))) {
+		t.Fatal("the zero value isn't BigEndian")
+	}
+}
+
+func TestVerifyProofAgainstRootBigInt(t *testing.T) {
+	factory := func() hash.Hash { return smallHash{sha256.New()} }
+	cmt := fillTree(t, NewCartesianMerkleTreeWithHashFactory(factory), strKeys(10))
+	root := new(big.Int).SetBytes(cmt.GetRoot())
+	if len(root.Bytes()) >= 32 {
+		t.Fatal("root has no leading zeros")
+	}
+	hasher := FactoryHasher(factory, nil)
+	proof, _ := cmt.GenerateProof([]byte("key-2"))
+	if !VerifyProofAgainstRootBigInt([]byte("key-2"), proof, root, hasher) {
+		t.Fatal("proof doesn't verify against the big.Int root")
+	}
+	if VerifyProofAgainstRoot([]byte("key-2"), proof, root.Bytes(), hasher) {
+		t.Fatal("proof verifies against the trimmed root bytes")
+	}
+	for name, bad := range map[string]*big.Int{
+		"nil":      nil,
+		"negative": new(big.Int).Neg(root),
+		"too wide": new(big.Int).Lsh(big.NewInt(1), 256),
+		"other":    new(big.Int).Add(root, big.NewInt(1)),
+	} {
+		if VerifyProofAgainstRootBigInt([]byte("key-2"), proof, bad, hasher) {
+			t.Errorf("%s root accepted", name)
+		}
+	}
+}
+
+func TestRootsComparable(t *testing.T) {
+	ctx := context.Background()
+	smt, err := NewSimpleMerkleTree(40, func(data []byte) []byte {
+		hash := sha256.Sum256(data)
+		return hash[:]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmt := NewCartesianMerkleTree()
+	for _, k := range []int64{3, 9, 27} {
+		if err := smt.Add(ctx, big.NewInt(k), big.NewInt(k)); err != nil {
+			t.Fatal(err)
+		}
+		cmt.Add(uintKey(k))
+	}
+	if ok, reason := RootsComparable(cmt, smt); !ok || !strings.Contains(reason, "same 3 keys") {
+		t.Fatalf("same keys: %v, %q", ok, reason)
+	}
+
+	cmt.Add(uintKey(81))
+	if ok, reason := RootsComparable(cmt, smt); ok || !strings.Contains(reason, "CMT holds 4 keys, SMT holds 3") {
+		t.Fatalf("extra CMT key: %v, %q", ok, reason)
+	}
+	cmt.Remove(uintKey(3))
+	if ok, reason := RootsComparable(cmt, smt); ok || !strings.Contains(reason, "missing from the SMT") {
+		t.Fatalf("different keys: %v, %q", ok, reason)
+	}
+	if ok, reason := RootsComparable(nil, smt); ok || !strings.Contains(reason, "nil tree") {
+		t.Fatalf("nil tree: %v, %q", ok, reason)
+	}
+	for _, reason := range []string{"poseidon", "sha256"} {
+		if _, got := RootsComparable(cmt, smt); !strings.Contains(got, reason) {
+			t.Fatalf("reason %q doesn't explain the construction", got)
+		}
+	}
+}
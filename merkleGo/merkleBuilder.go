@@ -0,0 +1,56 @@
+package merkleGo
+
+import (
+	"sync"
+)
+
+// TreeBuilder fills a private tree in the background and publishes it into a
+// live tree in one step. Readers of the live tree keep seeing the old state
+// (and are never blocked by the build) until Publish swaps the root.
+type TreeBuilder struct {
+	live *CartesianMerkleTree
+	// mu is held shared by staging calls and exclusively by Publish,
+	// so no staged key can slip in while the stage is being handed over
+	mu   sync.RWMutex
+	next *CartesianMerkleTree
+}
+
+// NewTreeBuilder returns a builder publishing into live, using live's configuration.
+// live must not be nil.
+func NewTreeBuilder(live *CartesianMerkleTree) *TreeBuilder {
+	return &TreeBuilder{live: live, next: live.emptyLike()}
+}
+
+// Add stages a key, safe to call from several goroutines.
+// inserted is false if the key was already staged.
+func (b *TreeBuilder) Add(key []byte) (inserted bool, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.next.Add(key)
+}
+
+// AddWeighted stages a key/value pair with a weight, see CartesianMerkleTree.AddWeighted.
+// inserted is false if the key was already staged.
+func (b *TreeBuilder) AddWeighted(key, value []byte, weight uint64) (inserted bool, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.next.AddWeighted(key, value, weight)
+}
+
+// Publish atomically replaces the live tree's content with the staged one and
+// starts a new empty stage. It returns the published root.
+func (b *TreeBuilder) Publish() []byte {
+	b.mu.Lock()
+	built := b.next
+	b.next = b.live.emptyLike()
+	b.mu.Unlock()
+
+	b.live.mu.Lock()
+	defer b.live.mu.Unlock()
+	defer b.live.recordRoot()
+	b.live.Root = built.Root
+	b.live.seq = built.seq
+	b.live.longKeys = built.longKeys
+	b.live.logOp(opReplace, nil, b.live.rootHash(), 0)
+	return b.live.rootHash()
+}
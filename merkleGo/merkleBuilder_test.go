@@ -0,0 +1,66 @@
+package merkleGo
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestTreeBuilderPublishesAtomically(t *testing.T) {
+	old := strKeys(50)
+	live := buildTree(t, old)
+	oldRoot := live.GetRoot()
+	staged := strKeys(400)
+	newRoot := buildTree(t, staged).GetRoot()
+
+	b := NewTreeBuilder(live)
+	stop := make(chan struct{})
+	var seen sync.WaitGroup
+	seen.Add(1)
+	go func() {
+		defer seen.Done()
+		for {
+			root, size := live.GetRoot(), live.Size()
+			if !bytes.Equal(root, oldRoot) && !bytes.Equal(root, newRoot) {
+				t.Errorf("reader saw a partial root %x", root)
+				return
+			}
+			if size != len(old) && size != len(staged) {
+				t.Errorf("reader saw size %d", size)
+				return
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	var adders sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		adders.Add(1)
+		go func(w int) {
+			defer adders.Done()
+			for i := w; i < len(staged); i += 4 {
+				if _, err := b.Add(staged[i]); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	adders.Wait()
+	if !bytes.Equal(live.GetRoot(), oldRoot) {
+		t.Fatal("staging changed the live tree")
+	}
+	if got := b.Publish(); !bytes.Equal(got, newRoot) || !bytes.Equal(live.GetRoot(), newRoot) {
+		t.Fatalf("published %x, live %x, want %x", got, live.GetRoot(), newRoot)
+	}
+	close(stop)
+	seen.Wait()
+	mustValidate(t, live)
+
+	if got := b.Publish(); got != nil {
+		t.Fatalf("publishing an empty stage gives root %x", got)
+	}
+}
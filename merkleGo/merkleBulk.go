@@ -0,0 +1,76 @@
+package merkleGo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RemoveFunc removes every key for which pred returns true and returns how many
+// were removed. Matching keys are collected in a first traversal and removed
+// afterwards, so pred never sees a tree that is being rotated.
+// On an AppendOnly tree it removes nothing and never calls pred.
+func (cmt *CartesianMerkleTree) RemoveFunc(pred func(key []byte) bool) (removed int) {
+	if cmt == nil || cmt.AppendOnly {
+		return 0
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+
+	var matches [][]byte
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		if pred(node.Key) {
+			matches = append(matches, node.Key)
+		}
+		return true
+	})
+	for _, key := range matches {
+		if cmt.removeKey(key) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Replace removes oldKey and inserts newKey with value under one write lock,
+// so no reader ever sees neither (or both) of them. newKey takes over oldKey's
+// Weight, and a nil value inserts a key-only node, as AddWeighted does. It
+// fails, leaving the tree untouched, if oldKey is absent or newKey is already
+// present (unless it is oldKey itself). The resulting root is the one
+// Remove(oldKey) then AddWeighted(newKey, value, weight) give.
+func (cmt *CartesianMerkleTree) Replace(oldKey, newKey, value []byte) error {
+	if cmt == nil {
+		return ErrNilTree
+	}
+	if len(newKey) == 0 {
+		return errors.New("key cannot be empty")
+	}
+	var alarm degenerateAlarm
+	defer alarm.fire()
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+
+	if cmt.AppendOnly {
+		return ErrRemovalDisabled
+	}
+	original := newKey
+	oldKey, newKey = cmt.treeKey(oldKey), cmt.treeKey(newKey)
+	old := cmt.find(oldKey)
+	if old == nil {
+		return fmt.Errorf("key %x not found", oldKey)
+	}
+	weight := old.Weight
+	if cmt.compareKeys(oldKey, newKey) != 0 && cmt.find(newKey) != nil {
+		return fmt.Errorf("key %x already present", newKey)
+	}
+	priority, err := cmt.priority(newKey)
+	if err != nil {
+		return err
+	}
+	if err := cmt.removeKey(oldKey); err != nil {
+		return err
+	}
+	_, alarm = cmt.insertWeighted(original, newKey, value, priority, weight)
+	return nil
+}
@@ -0,0 +1,116 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func weightedTree(t *testing.T) *CartesianMerkleTree {
+	t.Helper()
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(30) {
+		if _, err := cmt.AddWeighted(key, []byte{byte(i)}, uint64(i%4)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cmt
+}
+
+func TestReplaceKeepsWeight(t *testing.T) {
+	for _, newKey := range []string{"renamed", "key-7"} {
+		cmt, want := weightedTree(t), weightedTree(t)
+		if err := cmt.Replace([]byte("key-7"), []byte(newKey), []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, cmt)
+		if node := cmt.find([]byte(newKey)); node == nil || node.Weight != 3 {
+			t.Fatalf("%s: replaced node %+v, want weight 3", newKey, node)
+		}
+
+		if err := want.Remove([]byte("key-7")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := want.AddWeighted([]byte(newKey), []byte("v"), 3); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cmt.GetRoot(), want.GetRoot()) {
+			t.Fatalf("%s: root differs from Remove then AddWeighted", newKey)
+		}
+	}
+}
+
+func TestReplaceFailsUntouched(t *testing.T) {
+	cmt := weightedTree(t)
+	root := cmt.GetRoot()
+	if err := cmt.Replace([]byte("absent"), []byte("new"), nil); err == nil {
+		t.Fatal("replaced an absent key")
+	}
+	if err := cmt.Replace([]byte("key-1"), []byte("key-2"), nil); err == nil {
+		t.Fatal("replaced onto a present key")
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) {
+		t.Fatal("a failed Replace changed the root")
+	}
+}
+
+func TestRemoveFunc(t *testing.T) {
+	keys := make([][]byte, 100)
+	for i := range keys {
+		keys[i] = uintKey(int64(i))
+	}
+	cmt := buildTree(t, keys)
+	root := cmt.GetRoot()
+	if n := cmt.RemoveFunc(func([]byte) bool { return false }); n != 0 || !bytes.Equal(cmt.GetRoot(), root) {
+		t.Fatalf("always-false predicate removed %d keys", n)
+	}
+
+	lo, hi := uintKey(20), uintKey(50)
+	n := cmt.RemoveFunc(func(key []byte) bool {
+		return bytes.Compare(key, lo) >= 0 && bytes.Compare(key, hi) < 0
+	})
+	if n != 30 || cmt.Size() != 70 {
+		t.Fatalf("range predicate removed %d keys, %d left", n, cmt.Size())
+	}
+	mustValidate(t, cmt)
+	want := buildTree(t, append(append([][]byte{}, keys[:20]...), keys[50:]...))
+	if !bytes.Equal(cmt.GetRoot(), want.GetRoot()) {
+		t.Fatal("root differs from a tree of the remaining keys")
+	}
+
+	appendOnly := buildTree(t, keys)
+	appendOnly.AppendOnly = true
+	if n := appendOnly.RemoveFunc(func([]byte) bool { t.Fatal("pred called"); return true }); n != 0 {
+		t.Fatalf("append-only tree removed %d keys", n)
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	cmt := buildTree(t, strKeys(10))
+	cmt.AppendOnly = true
+	root, version := cmt.GetRoot(), cmt.Version()
+	removals := map[string]func() error{
+		"Remove": func() error { return cmt.Remove([]byte("key-1")) },
+		"RemoveWithTombstone": func() error {
+			_, err := cmt.RemoveWithTombstone([]byte("key-1"))
+			return err
+		},
+		"Replace": func() error { return cmt.Replace([]byte("key-1"), []byte("new"), nil) },
+	}
+	for name, remove := range removals {
+		if err := remove(); !errors.Is(err, ErrRemovalDisabled) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) || cmt.Version() != version || !cmt.Contains([]byte("key-1")) {
+		t.Fatal("a rejected removal touched the tree")
+	}
+	if _, err := cmt.Add([]byte("more")); err != nil {
+		t.Fatalf("Add on an append-only tree: %v", err)
+	}
+
+	cmt.AppendOnly = false
+	if err := cmt.Remove([]byte("key-1")); err != nil || cmt.Contains([]byte("key-1")) {
+		t.Fatalf("Remove once allowed again: %v", err)
+	}
+}
@@ -1,10 +1,17 @@
 package merkleGo
 
 import (
+    "context"
     "crypto/sha256"
+    "crypto/subtle"
+    "encoding/binary"
     "errors"
     "fmt"
+    "hash"
     "bytes"
+    "sync"
+    "sync/atomic"
+    "time"
 )
 
 // TreapNode defines each node in the Cartesian Merkle Tree (Treap)
@@ -12,15 +19,164 @@ type TreapNode struct {
     Left       *TreapNode
     Right      *TreapNode
     Key        []byte
+    Value      []byte // Optional; nil for key-only nodes (committed via nodeEntry)
     Priority   []byte // Deterministic priority = keccak(key), or poseidon, etc.
+    Weight     uint64 // Bias on top of Priority, see AddWeighted
+    Size       int    // Number of nodes in this subtree, including the node itself
     MerkleHash []byte
+    Meta       map[string][]byte // Application bookkeeping, never hashed, see SetMeta
+    Seq        uint64            // Insertion sequence number when TrackInsertionOrder is set, never hashed
 }
 
-// CartesianMerkleTree holds the root of the Treap
+// ErrNilTree is returned by mutating methods called on a nil *CartesianMerkleTree
+var ErrNilTree = errors.New("cartesian merkle tree is nil")
+
+// ErrProofTooDeep is returned by GenerateProof when the path exceeds MaxProofDepth
+var ErrProofTooDeep = errors.New("proof path exceeds MaxProofDepth")
+
+// ErrProofSelfCheck is returned by GenerateProof when SelfVerifyProofs is set
+// and the proof it built doesn't verify against the tree's own root
+var ErrProofSelfCheck = errors.New("generated proof does not verify against the root")
+
+// ErrInvalidPriority is returned by inserts when PriorityFunc yields an empty
+// priority or one whose length differs from the tree's, see PriorityLength
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// ErrTreeFull is returned by inserts of a new key into a tree holding MaxSize keys
+var ErrTreeFull = errors.New("tree is full")
+
+// ErrRemovalDisabled is returned by every removal on an AppendOnly tree
+var ErrRemovalDisabled = errors.New("removal disabled: tree is append-only")
+
+
+// Failure categories of VerifyProofDetailed, in the order they are checked
+var (
+    // ErrNotInclusion: the proof is nil or an exclusion proof
+    ErrNotInclusion = errors.New("not an inclusion proof")
+    // ErrEmptyKey: the key to verify is empty
+    ErrEmptyKey = errors.New("empty key")
+    // ErrKeyMismatch: the proof is for another key
+    ErrKeyMismatch = errors.New("proof is for another key")
+    // ErrEmptyTree: the tree has no keys, so nothing is included in it
+    ErrEmptyTree = errors.New("tree is empty")
+    // ErrMalformedProof: the siblings (or sizes) don't have the shape of a proof
+    ErrMalformedProof = errors.New("malformed proof")
+    // ErrRootMismatch: the proof is well formed but folds to another root
+    ErrRootMismatch = errors.New("proof does not match the root")
+)
+
+// CartesianMerkleTree holds the root of the Treap.
+// Read methods (GetRoot, Contains, Size, Select, Rank, GenerateProof, VerifyProof)
+// are safe on an empty or nil tree and behave as if it had no keys; on a nil tree
+// mutating methods (Add, AddWeighted, Remove, Update) return ErrNilTree.
 type CartesianMerkleTree struct {
     Root *TreapNode
-    // If you want to store desiredProofSize or a custom 3-arg hasher, do so here
-    // e.g. HashFunc func(a, b, c []byte) []byte
+    // mu guards Root: public methods take it, unexported helpers assume it is held
+    mu sync.RWMutex
+    // version counts the root changes so far, history holds the root of every version (see Version)
+    version int
+    history []rootRecord
+    // snapshots are the retained frozen copies, taken per snapshotPolicy (see SetSnapshotPolicy)
+    snapshots      []*TreeSnapshot
+    snapshotPolicy snapshotPolicy
+    // Clock returns the current time for time-based policies, nil means time.Now
+    Clock func() time.Time
+    // checksum is the rolling digest of the applied operations, see ApplyChecksum
+    checksum []byte
+    // PriorityFunc derives a node priority from its key, nil means sha256(key).
+    // Priorities are compared as big-endian byte strings, so they must all have
+    // the same length: inserts fail with ErrInvalidPriority otherwise.
+    // Must be set before the first insert.
+    PriorityFunc func(key []byte) []byte
+    // PriorityLength is the length every priority must have. 0 means the
+    // length of the priorities already in the tree (set by the first insert).
+    PriorityLength int
+    // CommitSize folds every node's subtree size into its hash:
+    // hash(entry || size, leftHash, rightHash). Proofs then carry the sizes along
+    // the path and the root attests to the number of keys (Proof.TreeSize).
+    // Must be set before the first insert.
+    CommitSize bool
+    // DomainSeparator is mixed into every node hash when set, see NewCartesianMerkleTreeWithDomain.
+    // Must be set before the first insert.
+    DomainSeparator []byte
+    // HashFactory replaces sha256 as the node hasher, see NewCartesianMerkleTreeWithHashFactory.
+    // Must be set before the first insert.
+    HashFactory func() hash.Hash
+    // KeyEndianness is the byte order used by KeyFromBigInt/BigIntFromKey
+    // when bridging to big.Int keys, BigEndian (the zero value) by default
+    KeyEndianness Endianness
+    // KeyEqual and KeyLess replace byte equality and ordering of keys everywhere
+    // (insert, remove, lookup, proofs), e.g. for case-insensitive keys. They must
+    // agree with each other; a nil one falls back to bytes.Equal / bytes.Compare.
+    // A node keeps the bytes of the first key inserted in its class, and that is
+    // what gets hashed and what Proof.Key holds. Must be set before the first insert.
+    KeyEqual func(a, b []byte) bool
+    KeyLess  func(a, b []byte) bool
+    // SiblingOrder is the layout GenerateProof produces and VerifyProof expects,
+    // TopDown (the contract's, and the zero value) by default. See ReorderSiblings.
+    SiblingOrder ProofSiblingOrder
+    // ValueHashFunc pre-hashes values before they are committed, so the entry
+    // commits ValueHashFunc(value) in place of the value (see nodeEntry); nil commits the raw value.
+    // Key-only nodes are unaffected. Proofs still carry the raw value: the
+    // stateless verifiers (VerifyProofAgainstRoot, ApplyUpdate) need it hashed
+    // by the caller first. Must be set before the first insert.
+    ValueHashFunc func(value []byte) []byte
+    // ValueLoader makes Get read values through from an external store: the
+    // tree then only holds each value's hash (sha256, or ValueHashFunc when set),
+    // and Get returns the raw value the loader returns for the key once it
+    // hashes to the stored one. See LoadValue.
+    ValueLoader func(key []byte) ([]byte, error)
+    // MaxProofDepth caps the number of nodes a proof may walk through:
+    // GenerateProof fails with ErrProofTooDeep beyond it. 0 means no limit.
+    MaxProofDepth int
+    // Balancing picks how the shape is kept balanced, TreapBalancing (the
+    // contract's) by default. Must be set before the first insert.
+    Balancing BalanceStrategy
+    // NegativeCacheSize enables a cache of up to that many non-membership proofs,
+    // keyed by (sha256(key), root) and dropped whenever the root changes, for
+    // workloads dominated by repeated "is this absent" queries. 0 disables it.
+    NegativeCacheSize int
+    negCache          negativeCache
+    // SelfVerifyProofs makes GenerateProof check every inclusion proof against
+    // the root before returning it (ErrProofSelfCheck), turning a corrupted tree
+    // or a generation bug into an error instead of a proof that won't verify.
+    SelfVerifyProofs bool
+    // ProvePriorities makes proofs carry the key, committed value, priority and
+    // weight of every path node (Proof.Path), for VerifyProofShape
+    ProvePriorities bool
+    // AppendOnly turns the tree into a log: Remove, RemoveWithTombstone and
+    // Replace fail with ErrRemovalDisabled and RemoveFunc removes nothing, all
+    // without touching the tree. Wholesale replacement (UnmarshalJSON,
+    // TreeBuilder.Publish) is not a removal and stays allowed.
+    AppendOnly bool
+    // TrackInsertionOrder numbers every newly inserted node (TreapNode.Seq)
+    // so KeysByInsertionOrder can replay the original order. Not hashed.
+    TrackInsertionOrder bool
+    seq                 uint64
+    // MaxKeyLength makes keys longer than it stored as sha256(key), see TreeKey.
+    // Values under 32 count as 32. 0 stores every key as given.
+    MaxKeyLength int
+    longKeys     map[string][]byte // sha256(key) -> key, for keys MaxKeyLength hashed
+    // HistoryLimit bounds the recorded roots (and snapshots) to the last that
+    // many versions, pruning as the tree changes, see PruneHistory. 0 keeps all.
+    HistoryLimit int
+    prunedBefore int // versions below it were pruned, see PruneHistory
+    // reserved holds the nodes preallocated by Reserve, handed out before nodePool's
+    reserved []TreapNode
+    // MaxSize caps the number of keys: inserting a new key into a full tree
+    // fails with ErrTreeFull, re-adding a present one still succeeds. Removals
+    // free room. 0 means unbounded.
+    MaxSize int
+    // DegenerateCallback, when set, is called after an insert leaves the tree
+    // with a BalanceFactor above DegenerateThreshold (4 when 0), with its height
+    // and size, hinting at priority grinding or a weak PriorityFunc so operators
+    // can react (rebuild, reseed). It runs once the tree is unlocked.
+    DegenerateCallback  func(height, size int)
+    DegenerateThreshold float64
+    degenerateHeight    int // height at the last DegenerateCallback call
+    // hasher caches the 3-arg hasher hash3 builds from HashFactory and
+    // DomainSeparator on first use, instead of rebuilding it per node hash
+    hasher atomic.Pointer[func(a, b, c []byte) []byte]
 }
 
 // A minimal struct to demonstrate proof data
@@ -29,7 +185,21 @@ type CartesianMerkleTree struct {
 type Proof struct {
     Existence bool
     Key       []byte
+    Value     []byte // value stored under Key, nil for key-only nodes
     Siblings  [][]byte
+    Sizes     []uint64 // subtree size of each visited node, in sibling order (CommitSize trees only)
+    // NonExistenceKey is, in a non-existence proof, the key of the last node on
+    // the search path (where key would be attached), like the contract's
+    // nonExistenceKey. nil in inclusion proofs and for an empty tree.
+    // Both kinds of proof share the contract's sibling layout: a
+    // (entry, otherChildHash) pair per node above the last one, then the last
+    // node's (leftHash, rightHash), the last node being the proven one or the
+    // one under NonExistenceKey.
+    NonExistenceKey []byte
+    // Path describes the path nodes, in sibling order, on trees with
+    // ProvePriorities (nil otherwise), so a verifier can check the shape too:
+    // see VerifyProofShape
+    Path []PathNode
 }
 
 // 3-argument hasher using keccak256 (like _hash3 in Solidity)
@@ -45,59 +215,336 @@ func default3ArgHash(a, b, c []byte) []byte {
     return h.Sum(nil)
 }
 
+// DomainHasher returns the 3-arg hasher of trees built with DomainSeparator sep:
+// default3ArgHash with sep written first. Pass it to the stateless verifiers.
+func DomainHasher(sep []byte) func(a, b, c []byte) []byte {
+    return func(a, b, c []byte) []byte {
+        if bytes.Compare(b, c) > 0 {
+            b, c = c, b
+        }
+        h := sha256.New()
+        h.Write(sep)
+        h.Write(a)
+        h.Write(b)
+        h.Write(c)
+        return h.Sum(nil)
+    }
+}
+
+// sizedEntry appends the subtree size to a node entry, so CommitSize trees hash
+// hash(entry || size, leftHash, rightHash) with the regular 3-arg hasher
+func sizedEntry(entry []byte, size uint64) []byte {
+    out := make([]byte, len(entry)+8)
+    copy(out, entry)
+    binary.BigEndian.PutUint64(out[len(entry):], size)
+    return out
+}
+
 // Constructor
 func NewCartesianMerkleTree() *CartesianMerkleTree {
     return &CartesianMerkleTree{}
 }
 
-// Insert a key into the Treap
-func (cmt *CartesianMerkleTree) Add(key []byte) error {
+// NewCartesianMerkleTreeWithMaxSize returns a tree holding at most maxSize keys,
+// see MaxSize
+func NewCartesianMerkleTreeWithMaxSize(maxSize int) *CartesianMerkleTree {
+    return &CartesianMerkleTree{MaxSize: maxSize}
+}
+
+// NewCartesianMerkleTreeWithDomain returns a tree whose every hash is bound to sep
+// (e.g. chain ID + contract address), so its roots and proofs can't be replayed
+// in another domain. Stateless verification needs DomainHasher(sep).
+func NewCartesianMerkleTreeWithDomain(sep []byte) *CartesianMerkleTree {
+    return &CartesianMerkleTree{DomainSeparator: sep}
+}
+
+// NewCartesianMerkleTreeWithHashFactory returns a tree whose node hashes use
+// hashes from f (e.g. sha512.New, or a keccak256 constructor) instead of sha256,
+// keeping the same layout: separator, entry, then the two children in ascending
+// order. Value commitments (see nodeEntry) still use sha256.
+// Stateless verification needs FactoryHasher(f, sep).
+func NewCartesianMerkleTreeWithHashFactory(f func() hash.Hash) *CartesianMerkleTree {
+    return &CartesianMerkleTree{HashFactory: f}
+}
+
+// FactoryHasher returns the 3-arg hasher of trees built with HashFactory f and
+// DomainSeparator sep (nil for none): the hash of sep || a || min(b, c) || max(b, c).
+func FactoryHasher(f func() hash.Hash, sep []byte) func(a, b, c []byte) []byte {
+    return func(a, b, c []byte) []byte {
+        if bytes.Compare(b, c) > 0 {
+            b, c = c, b
+        }
+        h := f()
+        h.Write(sep)
+        h.Write(a)
+        h.Write(b)
+        h.Write(c)
+        return h.Sum(nil)
+    }
+}
+
+// emptyLike returns an empty tree sharing cmt's configuration, so it builds the same shapes
+func (cmt *CartesianMerkleTree) emptyLike() *CartesianMerkleTree {
+    if cmt == nil {
+        return NewCartesianMerkleTree()
+    }
+    return &CartesianMerkleTree{
+        PriorityFunc:        cmt.PriorityFunc,
+        PriorityLength:      cmt.PriorityLength,
+        CommitSize:          cmt.CommitSize,
+        DomainSeparator:     cmt.DomainSeparator,
+        HashFactory:         cmt.HashFactory,
+        KeyEndianness:       cmt.KeyEndianness,
+        KeyEqual:            cmt.KeyEqual,
+        KeyLess:             cmt.KeyLess,
+        SiblingOrder:        cmt.SiblingOrder,
+        ValueHashFunc:       cmt.ValueHashFunc,
+        ValueLoader:         cmt.ValueLoader,
+        MaxProofDepth:       cmt.MaxProofDepth,
+        Balancing:           cmt.Balancing,
+        NegativeCacheSize:   cmt.NegativeCacheSize,
+        SelfVerifyProofs:    cmt.SelfVerifyProofs,
+        ProvePriorities:     cmt.ProvePriorities,
+        AppendOnly:          cmt.AppendOnly,
+        TrackInsertionOrder: cmt.TrackInsertionOrder,
+        MaxKeyLength:        cmt.MaxKeyLength,
+        HistoryLimit:        cmt.HistoryLimit,
+        MaxSize:             cmt.MaxSize,
+        DegenerateCallback:  cmt.DegenerateCallback,
+        DegenerateThreshold: cmt.DegenerateThreshold,
+    }
+}
+
+// Insert a key into the Treap.
+// inserted is false when the key was already present (the tree is left untouched).
+func (cmt *CartesianMerkleTree) Add(key []byte) (inserted bool, err error) {
+    if cmt == nil {
+        return false, ErrNilTree
+    }
     if len(key) == 0 {
-        return errors.New("key cannot be empty")
+        return false, errors.New("key cannot be empty")
     }
-    priority := sha256.Sum256(key) // or a poseidon-based approach
-    cmt.Root = cmt.insert(cmt.Root, key, priority[:])
-    return nil
+    var alarm degenerateAlarm
+    defer alarm.fire()
+    cmt.mu.Lock()
+    defer cmt.mu.Unlock()
+    defer cmt.recordRoot()
+    original, key := key, cmt.treeKey(key)
+    if cmt.full(key) {
+        return false, ErrTreeFull
+    }
+    priority, err := cmt.priority(key)
+    if err != nil {
+        return false, err
+    }
+    newNode := cmt.acquireNode()
+    newNode.Key = key
+    newNode.Priority = priority
+    cmt.Root, inserted = cmt.insert(cmt.Root, newNode)
+    if !inserted {
+        releaseNode(newNode)
+    } else {
+        cmt.rememberKey(original, key)
+        cmt.logOp(opAdd, key, nil, 0)
+        alarm = cmt.checkDegenerate(key)
+    }
+    return inserted, nil
+}
+
+// AddKV inserts a key/value pair, inserted is false if the key was already present.
+// The node always commits to a value: a nil value is stored as an empty one,
+// so Get reports it as present with a zero-length value.
+func (cmt *CartesianMerkleTree) AddKV(key, value []byte) (inserted bool, err error) {
+    if cmt == nil {
+        return false, ErrNilTree
+    }
+    if len(key) == 0 {
+        return false, errors.New("key cannot be empty")
+    }
+    if value == nil {
+        value = []byte{}
+    }
+    var alarm degenerateAlarm
+    defer alarm.fire()
+    cmt.mu.Lock()
+    defer cmt.mu.Unlock()
+    defer cmt.recordRoot()
+    original, key := key, cmt.treeKey(key)
+    if cmt.full(key) {
+        return false, ErrTreeFull
+    }
+    priority, err := cmt.priority(key)
+    if err != nil {
+        return false, err
+    }
+    newNode := cmt.acquireNode()
+    newNode.Key = key
+    newNode.Value = value
+    newNode.Priority = priority
+    cmt.Root, inserted = cmt.insert(cmt.Root, newNode)
+    if !inserted {
+        releaseNode(newNode)
+    } else {
+        cmt.rememberKey(original, key)
+        cmt.logOp(opAdd, key, value, 0)
+        alarm = cmt.checkDegenerate(key)
+    }
+    return inserted, nil
+}
+
+// AddWeighted inserts a key/value pair whose heap position is biased by weight:
+// a higher weight always outranks a lower one, and equal weights fall back to
+// the hash-derived priority. Hot keys therefore stay close to the root.
+// The shape (and root) is deterministic for the same keys, values and weights.
+// A nil value stores a key-only node, exactly like Add.
+// inserted is false when the key was already present (the tree, its value and
+// weight included, is left untouched).
+func (cmt *CartesianMerkleTree) AddWeighted(key, value []byte, weight uint64) (inserted bool, err error) {
+    if cmt == nil {
+        return false, ErrNilTree
+    }
+    if len(key) == 0 {
+        return false, errors.New("key cannot be empty")
+    }
+    var alarm degenerateAlarm
+    defer alarm.fire()
+    cmt.mu.Lock()
+    defer cmt.mu.Unlock()
+    defer cmt.recordRoot()
+    original, key := key, cmt.treeKey(key)
+    if cmt.full(key) {
+        return false, ErrTreeFull
+    }
+    priority, err := cmt.priority(key)
+    if err != nil {
+        return false, err
+    }
+    inserted, alarm = cmt.insertWeighted(original, key, value, priority, weight)
+    return inserted, nil
+}
+
+// insertWeighted links a node for key (a tree key, original as the caller
+// passed it) with value, priority and weight, the insert path AddWeighted and
+// Replace share. The lock must be held; the caller fires the returned alarm
+// once it is released.
+func (cmt *CartesianMerkleTree) insertWeighted(original, key, value, priority []byte, weight uint64) (inserted bool, alarm degenerateAlarm) {
+    newNode := cmt.acquireNode()
+    newNode.Key = key
+    newNode.Value = value
+    newNode.Priority = priority
+    newNode.Weight = weight
+    cmt.Root, inserted = cmt.insert(cmt.Root, newNode)
+    if !inserted {
+        releaseNode(newNode)
+        return false, alarm
+    }
+    cmt.rememberKey(original, key)
+    cmt.logOp(opAdd, key, value, weight)
+    return true, cmt.checkDegenerate(key)
+}
+
+// nodePool recycles the nodes dropped by remove (and by duplicate inserts)
+// for the next inserts, insert/remove churn otherwise allocates a node each time
+var nodePool = sync.Pool{New: func() interface{} { return new(TreapNode) }}
+
+// acquireNode returns a zeroed node, fresh or recycled
+func acquireNode() *TreapNode {
+    return nodePool.Get().(*TreapNode)
+}
+
+// acquireNode is the package acquireNode taking the nodes set aside by Reserve first
+func (cmt *CartesianMerkleTree) acquireNode() *TreapNode {
+    if len(cmt.reserved) == 0 {
+        return acquireNode()
+    }
+    node := &cmt.reserved[0]
+    cmt.reserved = cmt.reserved[1:]
+    return node
+}
+
+// releaseNode hands a node that is no longer reachable from any tree back to
+// nodePool. Every field is reset so no key, value, hash or child pointer leaks
+// into its next use. The byte slices themselves are left alone: proofs and Get
+// results may still share them.
+func releaseNode(node *TreapNode) {
+    *node = TreapNode{}
+    nodePool.Put(node)
 }
 
-func (cmt *CartesianMerkleTree) insert(node *TreapNode, key, priority []byte) *TreapNode {
+// cloneBytes copies b, keeping nil as nil (a nil value means key-only)
+func cloneBytes(b []byte) []byte {
+    if b == nil {
+        return nil
+    }
+    return append([]byte{}, b...)
+}
+
+func (cmt *CartesianMerkleTree) insert(node *TreapNode, newNode *TreapNode) (*TreapNode, bool) {
     if node == nil {
-        newNode := &TreapNode{
-            Key:        key,
-            Priority:   priority,
-        }
+        // The node outlives the call: don't alias the caller's slices
+        newNode.Key = cloneBytes(newNode.Key)
+        newNode.Value = cloneBytes(newNode.Value)
         // children = zero => hash(key, 0, 0)
-        newNode.MerkleHash = default3ArgHash(key, make([]byte, 32), make([]byte, 32))
-        return newNode
+        newNode.Size = 1
+        if cmt.TrackInsertionOrder {
+            cmt.seq++
+            newNode.Seq = cmt.seq
+        }
+        newNode.MerkleHash = cmt.computeMerkleHash(newNode)
+        return newNode, true
     }
 
     // BST property by key
-    if bytes.Compare(key, node.Key) < 0 {
-        node.Left = cmt.insert(node.Left, key, priority)
-        // rotate if left child has bigger priority
-        if bytes.Compare(node.Left.Priority, node.Priority) > 0 {
+    var inserted bool
+    key := newNode.Key
+    if cmt.compareKeys(key, node.Key) < 0 {
+        node.Left, inserted = cmt.insert(node.Left, newNode)
+        if inserted && cmt.Balancing == SizeBalanced {
+            node = cmt.maintain(node, false)
+        } else if inserted && cmt.outranks(node.Left, node) {
+            // rotate if left child has bigger priority
             node = cmt.rotateRight(node)
         }
-    } else if bytes.Compare(key, node.Key) > 0 {
-        node.Right = cmt.insert(node.Right, key, priority)
-        // rotate if right child has bigger priority
-        if bytes.Compare(node.Right.Priority, node.Priority) > 0 {
+    } else if cmt.compareKeys(key, node.Key) > 0 {
+        node.Right, inserted = cmt.insert(node.Right, newNode)
+        if inserted && cmt.Balancing == SizeBalanced {
+            node = cmt.maintain(node, true)
+        } else if inserted && cmt.outranks(node.Right, node) {
+            // rotate if right child has bigger priority
             node = cmt.rotateLeft(node)
         }
     } else {
         // key already exists => do nothing or update
-        return node
+        return node, false
+    }
+    if !inserted {
+        return node, false
     }
 
+    node.Size = subtreeSize(node)
     node.MerkleHash = cmt.computeMerkleHash(node)
-    return node
+    return node, true
 }
 
 // Remove a key from the Treap
 func (cmt *CartesianMerkleTree) Remove(key []byte) error {
+    if cmt == nil {
+        return ErrNilTree
+    }
     if len(key) == 0 {
         return errors.New("key cannot be empty")
     }
+    cmt.mu.Lock()
+    defer cmt.mu.Unlock()
+    defer cmt.recordRoot()
+    return cmt.removeKey(key)
+}
+
+func (cmt *CartesianMerkleTree) removeKey(key []byte) error {
+    if cmt.AppendOnly {
+        return ErrRemovalDisabled
+    }
+    key = cmt.treeKey(key)
     // If the node doesn't exist, we'll do nothing or return error
     if cmt.Root == nil {
         return errors.New("tree is empty")
@@ -107,6 +554,8 @@ func (cmt *CartesianMerkleTree) Remove(key []byte) error {
     if !removed {
         return fmt.Errorf("key %x not found", key)
     }
+    cmt.forgetKey(key)
+    cmt.logOp(opRemove, key, nil, 0)
     return nil
 }
 
@@ -115,7 +564,7 @@ func (cmt *CartesianMerkleTree) remove(node *TreapNode, key []byte) (*TreapNode,
         return nil, false
     }
     var removed bool
-    cmp := bytes.Compare(key, node.Key)
+    cmp := cmt.compareKeys(key, node.Key)
     if cmp < 0 {
         node.Left, removed = cmt.remove(node.Left, key)
     } else if cmp > 0 {
@@ -125,13 +574,22 @@ func (cmt *CartesianMerkleTree) remove(node *TreapNode, key []byte) (*TreapNode,
         removed = true
         // If no child or single child, just replace with non-nil child if any
         if node.Left == nil {
-            return node.Right, true
+            child := node.Right
+            releaseNode(node)
+            return child, true
         }
         if node.Right == nil {
-            return node.Left, true
+            child := node.Left
+            releaseNode(node)
+            return child, true
         }
-        // If two children, we do rotation based on priority
-        if bytes.Compare(node.Left.Priority, node.Right.Priority) < 0 {
+        // If two children, we do rotation based on priority: the child that
+        // outranks the other takes the node's place and the node sinks one
+        // level, until it has at most one child. The hashes the rotations give
+        // the sinking node are throwaway; every node left on its path is
+        // rehashed below on the way back up, so removing the root over and
+        // over (this branch each time) leaves no stale hash behind.
+        if cmt.promoteRight(node) {
             // rotateLeft
             node = cmt.rotateLeft(node)
             node.Left, _ = cmt.remove(node.Left, key)
@@ -143,13 +601,123 @@ func (cmt *CartesianMerkleTree) remove(node *TreapNode, key []byte) (*TreapNode,
     }
 
     if node != nil {
+        node.Size = subtreeSize(node)
         node.MerkleHash = cmt.computeMerkleHash(node)
     }
     return node, removed
 }
 
+// Update replaces the value stored under an existing key.
+// Priorities only depend on the key, so the shape stays the same and only the
+// hashes along the key's path are recomputed.
+func (cmt *CartesianMerkleTree) Update(key, value []byte) error {
+    if cmt == nil {
+        return ErrNilTree
+    }
+    if len(key) == 0 {
+        return errors.New("key cannot be empty")
+    }
+    cmt.mu.Lock()
+    defer cmt.mu.Unlock()
+    defer cmt.recordRoot()
+    key = cmt.treeKey(key)
+    if !cmt.update(cmt.Root, key, value) {
+        return fmt.Errorf("key %x not found", key)
+    }
+    cmt.logOp(opUpdate, key, value, 0)
+    return nil
+}
+
+func (cmt *CartesianMerkleTree) update(node *TreapNode, key, value []byte) bool {
+    if node == nil {
+        return false
+    }
+    var updated bool
+    cmp := cmt.compareKeys(key, node.Key)
+    if cmp < 0 {
+        updated = cmt.update(node.Left, key, value)
+    } else if cmp > 0 {
+        updated = cmt.update(node.Right, key, value)
+    } else {
+        node.Value = cloneBytes(value)
+        updated = true
+    }
+    if updated {
+        node.MerkleHash = cmt.computeMerkleHash(node)
+    }
+    return updated
+}
+
 // Generate a proof for a given key (analogous to your Solidity library)
 func (cmt *CartesianMerkleTree) GenerateProof(key []byte) (*Proof, error) {
+    return cmt.GenerateProofContext(context.Background(), key)
+}
+
+// GenerateProofContext is GenerateProof checking ctx at every level of the
+// descent, so a proof over a pathologically deep path is abandoned as soon as
+// the caller goes away: it then returns ctx.Err(). Together with MaxProofDepth
+// it bounds the work an untrusted request can cause.
+func (cmt *CartesianMerkleTree) GenerateProofContext(ctx context.Context, key []byte) (*Proof, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+    if cmt == nil {
+        return &Proof{Key: key, Siblings: [][]byte{}}, nil
+    }
+    cmt.mu.RLock()
+    defer cmt.mu.RUnlock()
+    return cmt.generateProofContext(ctx, key)
+}
+
+// GenerateAndVerify generates the proof of key and verifies it under a single
+// read lock, so a concurrent mutation can't slip in between and fail the
+// verification spuriously. root is the root both were taken against; valid
+// is VerifyProof's verdict, false for an absent key.
+func (cmt *CartesianMerkleTree) GenerateAndVerify(key []byte) (proof *Proof, valid bool, root []byte, err error) {
+    if cmt == nil {
+        return nil, false, nil, ErrNilTree
+    }
+    cmt.mu.RLock()
+    defer cmt.mu.RUnlock()
+    root = cmt.rootHash()
+    if proof, err = cmt.generateProofContext(context.Background(), key); err != nil {
+        return nil, false, root, err
+    }
+    valid, _ = cmt.verifyProof(key, proof, root)
+    return proof, valid, root, nil
+}
+
+// generateProofContext is GenerateProofContext with the read lock held
+func (cmt *CartesianMerkleTree) generateProofContext(ctx context.Context, key []byte) (*Proof, error) {
+    // cached by tree key, which is what the proof carries
+    treeKey := cmt.treeKey(key)
+    if cached := cmt.negCache.get(treeKey, cmt.rootHash()); cached != nil {
+        return cached, nil
+    }
+    if cmt.MaxProofDepth > 0 {
+        // measured before building anything, so a deep path costs no allocation
+        if depth, _ := cmt.pathDepth(key); depth > cmt.MaxProofDepth {
+            return nil, fmt.Errorf("%w: %d > %d", ErrProofTooDeep, depth, cmt.MaxProofDepth)
+        }
+    }
+    proof := &Proof{Key: treeKey, Siblings: [][]byte{}}
+    if cmt.Root != nil {
+        if err := cmt.collectProof(ctx, cmt.Root, key, proof, false); err != nil {
+            return nil, err
+        }
+    }
+    if cmt.SelfVerifyProofs && proof.Existence && !rootsEqual(cmt.rebuildFromProof(proof.Key, proof), cmt.rootHash()) {
+        return nil, fmt.Errorf("%w: key %x", ErrProofSelfCheck, key)
+    }
+    proof = ReorderSiblings(proof, TopDown, cmt.SiblingOrder)
+    if !proof.Existence && cmt.NegativeCacheSize > 0 {
+        cmt.negCache.put(treeKey, cmt.rootHash(), proof, cmt.NegativeCacheSize)
+    }
+    return proof, nil
+}
+
+func (cmt *CartesianMerkleTree) generateProof(key []byte) *Proof {
+    key = cmt.treeKey(key)
     proof := &Proof{
         Existence: false,
         Key:       key,
@@ -157,116 +725,338 @@ func (cmt *CartesianMerkleTree) GenerateProof(key []byte) (*Proof, error) {
     }
     if cmt.Root == nil {
         // empty tree => can't exist
-        return proof, nil
+        return proof
     }
     cmt.generateProofHelper(cmt.Root, key, proof)
-    return proof, nil
+    return proof
 }
 
-// A DFS to find the key and collect siblings along the path
+// Walks down from node to the key and collects siblings along the path.
+// It loops instead of recursing, so proof generation can't overflow the stack
+// however tall the tree gets.
 func (cmt *CartesianMerkleTree) generateProofHelper(node *TreapNode, key []byte, proof *Proof) {
-    if node == nil {
-        return
-    }
-    if bytes.Equal(node.Key, key) {
-        // Found the node => push childLeftHash, childRightHash
-        proof.Existence = true
-        leftHash := make([]byte, 32)
-        rightHash := make([]byte, 32)
-        if node.Left != nil {
-            leftHash = node.Left.MerkleHash
+    cmt.collectProof(context.Background(), node, key, proof, false)
+}
+
+// collectProof is generateProofHelper; with sharedZero, empty children are
+// recorded as the read-only zeroHash instead of a fresh 32-byte slice.
+// ctx is checked before each node, its error ends the walk.
+func (cmt *CartesianMerkleTree) collectProof(ctx context.Context, node *TreapNode, key []byte, proof *Proof, sharedZero bool) error {
+    childHash := func(child *TreapNode) []byte {
+        if child != nil {
+            return child.MerkleHash
         }
-        if node.Right != nil {
-            rightHash = node.Right.MerkleHash
+        if sharedZero {
+            return zeroHash
         }
-        proof.Siblings = append(proof.Siblings, leftHash, rightHash)
-        return
+        return make([]byte, 32)
     }
+    key = cmt.treeKey(key)
+    proof.Key = key
+    for node != nil {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        proof.NonExistenceKey = node.Key
+        if cmt.CommitSize {
+            proof.Sizes = append(proof.Sizes, uint64(node.Size))
+        }
+        if cmt.ProvePriorities {
+            proof.Path = append(proof.Path, cmt.pathNode(node))
+        }
+        if cmt.compareKeys(key, node.Key) == 0 {
+            // Found the node => push childLeftHash, childRightHash
+            proof.Existence = true
+            proof.NonExistenceKey = nil
+            proof.Key = node.Key
+            proof.Value = node.Value
+            proof.Siblings = append(proof.Siblings, childHash(node.Left), childHash(node.Right))
+            return nil
+        }
 
-    // If key < node.Key, go left
-    if bytes.Compare(key, node.Key) < 0 {
-        // We'll push (node.Key, rightChildHash) as siblings, for instance
-        // This matches the pattern from your Solidity "someKey, otherChildHash"
-        proof.Siblings = append(proof.Siblings, node.Key)
+        next, other := node.Right, node.Left
+        if cmt.compareKeys(key, node.Key) < 0 {
+            next, other = node.Left, node.Right
+        }
+        if next == nil {
+            // Dead end => like the contract, push the last node's
+            // childLeftHash, childRightHash; its key is NonExistenceKey
+            proof.Siblings = append(proof.Siblings, childHash(node.Left), childHash(node.Right))
+            return nil
+        }
+        // We'll push (node.Key, otherChildHash) as siblings, the pattern from
+        // the Solidity "someKey, otherChildHash", and go down towards key
+        proof.Siblings = append(proof.Siblings, cmt.entry(node.Key, node.Value), childHash(other))
+        node = next
+    }
+    return nil
+}
+
+// VerifyProof: a simplistic local re-hash approach.
+// It checks membership only: exclusion proofs are rejected (see
+// VerifyNonMembership), and so is every proof on an empty tree.
+// VerifyProofDetailed tells why a proof fails.
+func (cmt *CartesianMerkleTree) VerifyProof(key []byte, proof *Proof) bool {
+    ok, _ := cmt.VerifyProofDetailed(key, proof)
+    return ok
+}
+
+// VerifyProofDetailed is VerifyProof reporting why a proof fails: the error
+// wraps ErrNilTree or one of the categories ErrNotInclusion, ErrEmptyKey,
+// ErrKeyMismatch, ErrEmptyTree, ErrMalformedProof and ErrRootMismatch.
+// ok is true exactly when err is nil.
+func (cmt *CartesianMerkleTree) VerifyProofDetailed(key []byte, proof *Proof) (ok bool, err error) {
+    if cmt == nil {
+        return false, ErrNilTree
+    }
+    cmt.mu.RLock()
+    actualRoot := cmt.rootHash()
+    cmt.mu.RUnlock()
+    return cmt.verifyProof(key, proof, actualRoot)
+}
+
+// verifyProof is VerifyProofDetailed against actualRoot, the tree's root when
+// it was read. It doesn't touch the nodes, so it needs no lock.
+func (cmt *CartesianMerkleTree) verifyProof(key []byte, proof *Proof, actualRoot []byte) (ok bool, err error) {
+    if proof == nil || !proof.Existence {
+        // If the proof claims the key doesn't exist, then presumably it's false for membership
+        return false, ErrNotInclusion
+    }
+    if len(key) == 0 {
+        return false, ErrEmptyKey
+    }
+    if len(proof.Key) == 0 || cmt.compareKeys(cmt.treeKey(key), proof.Key) != 0 {
+        return false, fmt.Errorf("%w: proof key %x, want %x", ErrKeyMismatch, proof.Key, key)
+    }
+    // The proof commits to the stored key bytes, which may differ from key under KeyEqual
+    key = proof.Key
+    if len(actualRoot) == 0 {
+        return false, ErrEmptyTree
+    }
+    if proof.Kind() != Inclusion {
+        return false, fmt.Errorf("%w: %d siblings, %d sizes", ErrMalformedProof, len(proof.Siblings), len(proof.Sizes))
+    }
+    topDown := ReorderSiblings(proof, cmt.SiblingOrder, TopDown)
+    if err := checkSiblingWidths(topDown, len(actualRoot), cmt.maxEntryWidth()); err != nil {
+        return false, err
+    }
+    // We do a naive reconstruction approach similar to what you'd do in Solidity
+    // For brevity, let's just rely on a function that matches your library's pattern
+    computedRoot := cmt.rebuildFromProof(key, topDown)
+    if len(computedRoot) == 0 {
+        // e.g. a CommitSize tree given a proof without sizes
+        return false, fmt.Errorf("%w: siblings don't fold to a root", ErrMalformedProof)
+    }
+    if !rootsEqual(computedRoot, actualRoot) {
+        return false, fmt.Errorf("%w: computed %x, root %x", ErrRootMismatch, computedRoot, actualRoot)
+    }
+    return true, nil
+}
 
-        rightHash := make([]byte, 32)
-        if node.Right != nil {
-            rightHash = node.Right.MerkleHash
+// VerifyNonMembership checks an exclusion proof of key against the tree's
+// current state. On an empty (or nil) tree any well-formed exclusion proof of
+// key, which has no siblings, succeeds: its root is the canonical empty root
+// (see CanonicalRoot). Otherwise the proof must be exactly the one the tree
+// generates for key now. Sorted child hashing hides which side the missing
+// child is on, so exclusion can't be checked from the siblings alone.
+func (cmt *CartesianMerkleTree) VerifyNonMembership(key []byte, proof *Proof) bool {
+    if proof.Kind() != Exclusion || len(key) == 0 {
+        return false
+    }
+    if cmt == nil {
+        return len(proof.Siblings) == 0 && bytes.Equal(key, proof.Key)
+    }
+    key = cmt.treeKey(key)
+    if cmt.compareKeys(key, proof.Key) != 0 {
+        return false
+    }
+    cmt.mu.RLock()
+    defer cmt.mu.RUnlock()
+    if cmt.Root == nil {
+        return len(proof.Siblings) == 0
+    }
+    want := cmt.generateProof(key)
+    if want.Existence {
+        return false
+    }
+    got := ReorderSiblings(proof, cmt.SiblingOrder, TopDown)
+    if len(got.Siblings) != len(want.Siblings) || len(got.Sizes) != len(want.Sizes) ||
+        !bytes.Equal(got.NonExistenceKey, want.NonExistenceKey) {
+        return false
+    }
+    for i := range want.Siblings {
+        if !bytes.Equal(got.Siblings[i], want.Siblings[i]) {
+            return false
         }
-        proof.Siblings = append(proof.Siblings, rightHash)
+    }
+    for i := range want.Sizes {
+        if got.Sizes[i] != want.Sizes[i] {
+            return false
+        }
+    }
+    return true
+}
 
-        cmt.generateProofHelper(node.Left, key, proof)
-    } else {
-        // go right
-        proof.Siblings = append(proof.Siblings, node.Key)
+// VerifyProofAgainstRoot verifies an inclusion proof against a given root,
+// without needing the tree. A nil hasher means the default 3-arg hasher.
+// Siblings must be TopDown, see ReorderSiblings, and child hashes as wide as root.
+// Proofs carrying Sizes come from CommitSize trees and are folded accordingly.
+func VerifyProofAgainstRoot(key []byte, proof *Proof, root []byte, hasher func(a, b, c []byte) []byte) bool {
+    if len(root) == 0 || proof == nil || checkSiblingWidths(proof, len(root), 0) != nil {
+        return false
+    }
+    return rootsEqual(impliedRoot(key, proof, hasher), root)
+}
 
-        leftHash := make([]byte, 32)
-        if node.Left != nil {
-            leftHash = node.Left.MerkleHash
+// VerifyProofAgainstRoots is VerifyProofAgainstRoot for several candidate
+// roots (e.g. across a reorg, or one per peer): the proof's root is computed
+// once, and index is the first candidate it matches. found is false, with
+// index -1, if it matches none.
+func VerifyProofAgainstRoots(key []byte, proof *Proof, roots [][]byte, hasher func(a, b, c []byte) []byte) (index int, found bool) {
+    computedRoot := impliedRoot(key, proof, hasher)
+    if computedRoot == nil {
+        return -1, false
+    }
+    for i, root := range roots {
+        if len(root) > 0 && checkSiblingWidths(proof, len(root), 0) == nil && rootsEqual(computedRoot, root) {
+            return i, true
         }
-        proof.Siblings = append(proof.Siblings, leftHash)
+    }
+    return -1, false
+}
 
-        cmt.generateProofHelper(node.Right, key, proof)
+// impliedRoot folds an inclusion proof of key up to the root it implies,
+// nil if the proof can't be folded
+func impliedRoot(key []byte, proof *Proof, hasher func(a, b, c []byte) []byte) []byte {
+    if proof == nil || !proof.Existence {
+        return nil
+    }
+    if len(key) == 0 || len(proof.Key) == 0 {
+        return nil
+    }
+    if hasher == nil {
+        hasher = default3ArgHash
     }
+    if len(proof.Sizes) > 0 {
+        return foldSizedSiblings(nodeEntry(key, proof.Value), proof.Siblings, proof.Sizes, hasher)
+    }
+    return foldSiblings(nodeEntry(key, proof.Value), proof.Siblings, hasher)
 }
 
-// VerifyProof: a simplistic local re-hash approach
-func (cmt *CartesianMerkleTree) VerifyProof(key []byte, proof *Proof) bool {
-    if !proof.Existence {
-        // If the proof claims the key doesn't exist, then presumably it's false for membership
+// VerifyLeafHashAgainstRoot is VerifyProofAgainstRoot starting from the proven
+// node's committed entry (sha256 of the length-prefixed key and value, see
+// nodeEntry) instead of the raw key and value, so a verifier can check
+// inclusion without ever seeing the key. For key-only nodes the entry is the
+// key itself, so nothing is hidden. proof.Key and proof.Value are ignored.
+func VerifyLeafHashAgainstRoot(leafHash []byte, proof *Proof, root []byte, hasher func(a, b, c []byte) []byte) bool {
+    if proof == nil || !proof.Existence || len(leafHash) == 0 || len(root) == 0 {
         return false
     }
-    if len(key) == 0 || len(proof.Key) == 0 {
+    if checkSiblingWidths(proof, len(root), 0) != nil {
         return false
     }
-    // We do a naive reconstruction approach similar to what you'd do in Solidity
-    // For brevity, let's just rely on a function that matches your library's pattern
-    computedRoot := cmt.rebuildFromProof(key, proof.Siblings)
-    actualRoot := cmt.GetRoot()
-    return bytes.Equal(computedRoot, actualRoot)
+    if hasher == nil {
+        hasher = default3ArgHash
+    }
+    var computedRoot []byte
+    if len(proof.Sizes) > 0 {
+        computedRoot = foldSizedSiblings(leafHash, proof.Siblings, proof.Sizes, hasher)
+    } else {
+        computedRoot = foldSiblings(leafHash, proof.Siblings, hasher)
+    }
+    return rootsEqual(computedRoot, root)
+}
+
+// rootsEqual compares a computed root with the expected one in constant time
+// (crypto/subtle), so the comparison doesn't leak how many leading bytes matched.
+// Only the lengths, which are public, may cut it short.
+func rootsEqual(computed, expected []byte) bool {
+    return subtle.ConstantTimeCompare(computed, expected) == 1
+}
+
+// Replays generateProofHelper for an inclusion proof. Nothing is guessed from
+// the sibling bytes: Existence marks the final pair as the proven node's
+// children (a single-node tree is just that pair, whatever the hashes look like)
+// and every earlier pair is (ancestorKey, otherChildHash).
+func (cmt *CartesianMerkleTree) rebuildFromProof(key []byte, proof *Proof) []byte {
+    if !proof.Existence {
+        return nil
+    }
+    leaf := cmt.entry(key, proof.Value)
+    if cmt.CommitSize {
+        return foldSizedSiblings(leaf, proof.Siblings, proof.Sizes, cmt.hash3)
+    }
+    return foldSiblings(leaf, proof.Siblings, cmt.hash3)
 }
 
-// This attempts to replicate the pattern in generateProofHelper
-func (cmt *CartesianMerkleTree) rebuildFromProof(leaf []byte, siblings [][]byte) []byte {
-    // Start from leaf
-    current := leaf
-    // If zero siblings => must be single-node tree => compare with hash(leaf, 0, 0)
-    if len(siblings) == 2 && bytes.Equal(siblings[0], make([]byte, 32)) && bytes.Equal(siblings[1], make([]byte, 32)) {
-        return default3ArgHash(leaf, siblings[0], siblings[1])
+// foldSiblings recomputes the root from a leaf entry and its proof siblings
+func foldSiblings(leaf []byte, siblings [][]byte, hash3 func(a, b, c []byte) []byte) []byte {
+    // Siblings are recorded root-to-leaf, so fold them back leaf-to-root:
+    // the last pair holds the proven node's children => hash(leaf, left, right),
+    // every earlier pair is (ancestorKey, otherChildHash).
+    // Child order doesn't matter here since the 3-arg hasher sorts its children.
+    n := len(siblings)
+    if n < 2 || n%2 != 0 {
+        return nil
+    }
+    current := hash3(leaf, siblings[n-2], siblings[n-1])
+    for idx := n - 4; idx >= 0; idx -= 2 {
+        current = hash3(siblings[idx], current, siblings[idx+1])
     }
+    return current
+}
 
-    idx := 0
-    for idx < len(siblings) {
-        // We expect them in pairs => (nodeKey or childHash, theOtherChildHash)
-        if idx+1 >= len(siblings) {
-            break
-        }
-        s1 := siblings[idx]
-        s2 := siblings[idx+1]
-        idx += 2
-
-        // Heuristics to see if s1 is the nodeKey or childHash
-        // If s1 != 32 zero bytes and s1 != leaf => we treat it as nodeKey
-        // Then decide if leaf < nodeKey => leaf is left, s2 is right
-        // or leaf > nodeKey => s2 is left, leaf is right
-        // If it doesn't look like a nodeKey, maybe it's the leftChildHash, rightChildHash
-        // This is "guess-y" but follows the logic from generateProofHelper
-        if len(s1) == 32 && !bytes.Equal(s1, make([]byte, 32)) && !bytes.Equal(s1, current) {
-            // s1 is likely nodeKey
-            if bytes.Compare(current, s1) < 0 {
-                current = default3ArgHash(s1, current, s2)
-            } else {
-                current = default3ArgHash(s1, s2, current)
-            }
-        } else {
-            // s1, s2 are leftHash, rightHash => nodeKey is current
-            current = default3ArgHash(current, s1, s2)
-        }
+// foldSizedSiblings is foldSiblings for CommitSize trees, sizes[i] belongs to the i-th pair
+func foldSizedSiblings(leaf []byte, siblings [][]byte, sizes []uint64, hash3 func(a, b, c []byte) []byte) []byte {
+    n := len(siblings)
+    if n < 2 || n%2 != 0 || len(sizes) != n/2 {
+        return nil
+    }
+    current := hash3(sizedEntry(leaf, sizes[n/2-1]), siblings[n-2], siblings[n-1])
+    for idx := n - 4; idx >= 0; idx -= 2 {
+        current = hash3(sizedEntry(siblings[idx], sizes[idx/2]), current, siblings[idx+1])
     }
     return current
 }
 
-// computeMerkleHash => hash(node.key, leftChildHash, rightChildHash)
+// TreeSize returns the number of keys committed by the root,
+// only meaningful for proofs of CommitSize trees that verified
+func (p *Proof) TreeSize() (uint64, bool) {
+    if len(p.Sizes) == 0 {
+        return 0, false
+    }
+    return p.Sizes[0], true
+}
+
+// nodeEntry is what a node commits to in the first hash argument:
+// the bare key for key-only nodes (on-chain compatible), otherwise
+// sha256(len(key) || key || len(value) || value) with 4-byte big-endian lengths.
+// The lengths pin the key/value boundary, so an empty value can't stand in for a
+// real one by shifting bytes between key and value.
+func nodeEntry(key, value []byte) []byte {
+    if value == nil {
+        return key
+    }
+    var n [4]byte
+    h := sha256.New()
+    binary.BigEndian.PutUint32(n[:], uint32(len(key)))
+    h.Write(n[:])
+    h.Write(key)
+    binary.BigEndian.PutUint32(n[:], uint32(len(value)))
+    h.Write(n[:])
+    h.Write(value)
+    return h.Sum(nil)
+}
+
+// entry is nodeEntry with the value first passed through ValueHashFunc
+func (cmt *CartesianMerkleTree) entry(key, value []byte) []byte {
+    if value != nil && cmt.ValueHashFunc != nil {
+        value = cmt.ValueHashFunc(value)
+    }
+    return nodeEntry(key, value)
+}
+
+// computeMerkleHash => hash(nodeEntry, leftChildHash, rightChildHash)
 func (cmt *CartesianMerkleTree) computeMerkleHash(node *TreapNode) []byte {
     var leftH, rightH []byte
     if node.Left != nil {
@@ -279,16 +1069,121 @@ func (cmt *CartesianMerkleTree) computeMerkleHash(node *TreapNode) []byte {
     } else {
         rightH = make([]byte, 32)
     }
-    return default3ArgHash(node.Key, leftH, rightH)
+    if cmt.CommitSize {
+        return cmt.hash3(sizedEntry(cmt.entry(node.Key, node.Value), uint64(node.Size)), leftH, rightH)
+    }
+    return cmt.hash3(cmt.entry(node.Key, node.Value), leftH, rightH)
+}
+
+// hash3 is the tree's 3-arg hasher: HashFactory or sha256, domain separated when DomainSeparator is set.
+// It is built on the first call and cached, so both fields must be set before the tree hashes anything.
+func (cmt *CartesianMerkleTree) hash3(a, b, c []byte) []byte {
+    if h := cmt.hasher.Load(); h != nil {
+        return (*h)(a, b, c)
+    }
+    h := default3ArgHash
+    if cmt.HashFactory != nil {
+        h = FactoryHasher(cmt.HashFactory, cmt.DomainSeparator)
+    } else if cmt.DomainSeparator != nil {
+        h = DomainHasher(cmt.DomainSeparator)
+    }
+    cmt.hasher.Store(&h)
+    return h(a, b, c)
+}
+
+// compareKeys orders keys like bytes.Compare, through KeyEqual/KeyLess when set
+func (cmt *CartesianMerkleTree) compareKeys(a, b []byte) int {
+    if cmt == nil || (cmt.KeyEqual == nil && cmt.KeyLess == nil) {
+        return bytes.Compare(a, b)
+    }
+    equal := cmt.KeyEqual
+    if equal == nil {
+        equal = bytes.Equal
+    }
+    if equal(a, b) {
+        return 0
+    }
+    if cmt.KeyLess != nil {
+        if cmt.KeyLess(a, b) {
+            return -1
+        }
+        return 1
+    }
+    if bytes.Compare(a, b) < 0 {
+        return -1
+    }
+    return 1
+}
+
+// priority returns the heap priority of a key
+func (cmt *CartesianMerkleTree) priority(key []byte) ([]byte, error) {
+    var priority []byte
+    if cmt.PriorityFunc != nil {
+        priority = cmt.PriorityFunc(key)
+    } else {
+        sum := sha256.Sum256(key) // or a poseidon-based approach
+        priority = sum[:]
+    }
+    want := cmt.PriorityLength
+    if want == 0 && cmt.Root != nil {
+        // every node has the same length, the root stands for all of them
+        want = len(cmt.Root.Priority)
+    }
+    if len(priority) == 0 || (want > 0 && len(priority) != want) {
+        return nil, fmt.Errorf("%w: %d bytes for key %x, want %d", ErrInvalidPriority, len(priority), key, want)
+    }
+    return priority, nil
+}
+
+// outranks reports whether a belongs above b in the heap:
+// higher Weight first, then higher Priority.
+// Equal priorities are broken by key, the smaller key goes on top, so the shape
+// stays unique (and reproducible by a verifier) even if the priority function collides.
+// Every heap decision (insert and remove rotations) goes through here.
+func (cmt *CartesianMerkleTree) outranks(a, b *TreapNode) bool {
+    return outranks(a, b)
+}
+
+// outranks is the heap order itself, for verifiers that have no tree
+func outranks(a, b *TreapNode) bool {
+    if a.Weight != b.Weight {
+        return a.Weight > b.Weight
+    }
+    if cmp := bytes.Compare(a.Priority, b.Priority); cmp != 0 {
+        return cmp > 0
+    }
+    return bytes.Compare(a.Key, b.Key) < 0
+}
+
+// full reports whether inserting key would exceed MaxSize (key is a tree key)
+func (cmt *CartesianMerkleTree) full(key []byte) bool {
+    return cmt.MaxSize > 0 && cmt.Root != nil && cmt.Root.Size >= cmt.MaxSize && cmt.find(key) == nil
+}
+
+// subtreeSize recounts a node from its children's cached sizes
+func subtreeSize(node *TreapNode) int {
+    size := 1
+    if node.Left != nil {
+        size += node.Left.Size
+    }
+    if node.Right != nil {
+        size += node.Right.Size
+    }
+    return size
 }
 
 // standard treap rotations
 func (cmt *CartesianMerkleTree) rotateRight(y *TreapNode) *TreapNode {
     x := y.Left
+    if x == nil {
+        return y
+    }
     T2 := x.Right
     x.Right = y
     y.Left = T2
 
+    y.Size = subtreeSize(y)
+    x.Size = subtreeSize(x)
     y.MerkleHash = cmt.computeMerkleHash(y)
     x.MerkleHash = cmt.computeMerkleHash(x)
     return x
@@ -296,10 +1191,15 @@ func (cmt *CartesianMerkleTree) rotateRight(y *TreapNode) *TreapNode {
 
 func (cmt *CartesianMerkleTree) rotateLeft(x *TreapNode) *TreapNode {
     y := x.Right
+    if y == nil {
+        return x
+    }
     T2 := y.Left
     y.Left = x
     x.Right = T2
 
+    x.Size = subtreeSize(x)
+    y.Size = subtreeSize(y)
     x.MerkleHash = cmt.computeMerkleHash(x)
     y.MerkleHash = cmt.computeMerkleHash(y)
     return y
@@ -307,8 +1207,32 @@ func (cmt *CartesianMerkleTree) rotateLeft(x *TreapNode) *TreapNode {
 
 // Return the root hash
 func (cmt *CartesianMerkleTree) GetRoot() []byte {
+    if cmt == nil {
+        return nil
+    }
+    cmt.mu.RLock()
+    defer cmt.mu.RUnlock()
+    return cmt.rootHash()
+}
+
+func (cmt *CartesianMerkleTree) rootHash() []byte {
     if cmt.Root == nil {
         return nil
     }
     return cmt.Root.MerkleHash
 }
+
+// CanonicalRoot returns the root as a fixed 32-byte array, zero-padded on the
+// left (all zeros for an empty tree, like bytes32(0) on-chain). Use this form,
+// not a trimmed big-endian integer, when hashing the root into another
+// commitment. Roots wider than 32 bytes (a custom hasher's, e.g. sha512) are
+// not supported: only their last 32 bytes are kept, so use GetRoot for those.
+func (cmt *CartesianMerkleTree) CanonicalRoot() [32]byte {
+    var out [32]byte
+    root := cmt.GetRoot()
+    if len(root) > len(out) {
+        root = root[len(root)-len(out):]
+    }
+    copy(out[len(out)-len(root):], root)
+    return out
+}
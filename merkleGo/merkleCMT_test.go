@@ -0,0 +1,978 @@
+package merkleGo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// removing the root every time, i.e. in descending priority order, takes the
+// two-children rotation path until the tree is a single path
+func TestRemoveRootUntilEmpty(t *testing.T) {
+	configs := map[string]func() *CartesianMerkleTree{
+		"plain": NewCartesianMerkleTree,
+		"sized": func() *CartesianMerkleTree {
+			cmt := NewCartesianMerkleTree()
+			cmt.CommitSize = true
+			return cmt
+		},
+		"balanced": func() *CartesianMerkleTree {
+			cmt := NewCartesianMerkleTree()
+			cmt.Balancing = SizeBalanced
+			return cmt
+		},
+	}
+	for name, newTree := range configs {
+		t.Run(name, func(t *testing.T) {
+			keys := strKeys(200)
+			cmt := fillTree(t, newTree(), keys)
+			twoChildren := 0
+			for n := len(keys); n > 0; n-- {
+				root := cmt.Root
+				if root.Left != nil && root.Right != nil {
+					twoChildren++
+				}
+				if err := cmt.Remove(root.Key); err != nil {
+					t.Fatalf("Remove(%s): %v", root.Key, err)
+				}
+				mustValidate(t, cmt)
+				if cmt.Size() != n-1 || cmt.Contains(root.Key) {
+					t.Fatalf("after removing %s: size %d, want %d", root.Key, cmt.Size(), n-1)
+				}
+				if cmt.Balancing != SizeBalanced {
+					if ok, err := cmt.VerifySelfRoot(); !ok || err != nil {
+						t.Fatalf("after removing %s: hashes differ from a rebuild (%v)", root.Key, err)
+					}
+				}
+			}
+			if twoChildren < len(keys)/2 {
+				t.Fatalf("only %d removals had two children", twoChildren)
+			}
+			if cmt.GetRoot() != nil || cmt.CanonicalRoot() != [32]byte{} {
+				t.Fatalf("empty tree has root %x", cmt.GetRoot())
+			}
+		})
+	}
+}
+
+func TestRemoveWeightedRoots(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(100) {
+		if _, err := cmt.AddWeighted(key, []byte{byte(i)}, uint64(i%3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for cmt.Root != nil {
+		key := cmt.Root.Key
+		if err := cmt.Remove(key); err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, cmt)
+		if ok, err := cmt.VerifySelfRoot(); !ok || err != nil {
+			t.Fatalf("after removing %s: hashes differ from a rebuild (%v)", key, err)
+		}
+	}
+}
+
+func TestCanonicalRoot(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	if root := cmt.CanonicalRoot(); root != [32]byte{} {
+		t.Fatalf("empty tree: %x", root)
+	}
+	fillTree(t, cmt, strKeys(10))
+	root := cmt.CanonicalRoot()
+	if hex.EncodeToString(root[:]) != hex.EncodeToString(cmt.GetRoot()) {
+		t.Fatalf("canonical root %x, root %x", root, cmt.GetRoot())
+	}
+
+	// a root with leading zero bytes keeps them, unlike its big.Int form
+	small := fillTree(t, NewCartesianMerkleTreeWithHashFactory(func() hash.Hash {
+		return smallHash{sha256.New()}
+	}), strKeys(10))
+	root = small.CanonicalRoot()
+	if root[0] != 0 || hex.EncodeToString(root[:]) != small.RootHex() {
+		t.Fatalf("small root: canonical %x, RootHex %s", root, small.RootHex())
+	}
+	asInt := new(big.Int).SetBytes(root[:])
+	if len(asInt.Bytes()) >= 32 || !bytes.Equal(asInt.FillBytes(make([]byte, 32)), root[:]) {
+		t.Fatalf("small root %x isn't the padded form of %s", root, asInt)
+	}
+
+	// a narrower hasher's root is left-padded too
+	narrow := fillTree(t, NewCartesianMerkleTreeWithHashFactory(sha1.New), strKeys(10))
+	root = narrow.CanonicalRoot()
+	if !bytes.Equal(root[:12], make([]byte, 12)) || !bytes.Equal(root[12:], narrow.GetRoot()) {
+		t.Fatalf("sha1 root %x padded to %x", narrow.GetRoot(), root)
+	}
+}
+
+func TestAddWeightedReportsDuplicates(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	inserted, err := cmt.AddWeighted([]byte("hot"), []byte("v1"), 5)
+	if !inserted || err != nil {
+		t.Fatalf("first insert: %v, %v", inserted, err)
+	}
+	root := cmt.GetRoot()
+	inserted, err = cmt.AddWeighted([]byte("hot"), []byte("v2"), 9)
+	if inserted || err != nil {
+		t.Fatalf("duplicate insert: %v, %v", inserted, err)
+	}
+	if value, _ := cmt.Get([]byte("hot")); string(value) != "v1" || cmt.Root.Weight != 5 {
+		t.Fatalf("duplicate changed the entry to %q, weight %d", value, cmt.Root.Weight)
+	}
+	if string(cmt.GetRoot()) != string(root) {
+		t.Fatal("duplicate changed the root")
+	}
+
+	b := NewTreeBuilder(NewCartesianMerkleTree())
+	if inserted, _ := b.AddWeighted([]byte("hot"), nil, 1); !inserted {
+		t.Fatal("builder didn't stage a new key")
+	}
+	if inserted, _ := b.AddWeighted([]byte("hot"), nil, 2); inserted {
+		t.Fatal("builder staged a key twice")
+	}
+}
+
+func TestHash3IsBuiltOnce(t *testing.T) {
+	a, b, c := []byte("entry"), make([]byte, 32), bytes.Repeat([]byte{1}, 32)
+	keccak := NewCartesianMerkleTreeWithHashFactory(sha3.NewLegacyKeccak256)
+	keccak.DomainSeparator = []byte("app")
+	trees := map[string]struct {
+		cmt  *CartesianMerkleTree
+		want func(a, b, c []byte) []byte
+	}{
+		"default": {NewCartesianMerkleTree(), default3ArgHash},
+		"domain":  {NewCartesianMerkleTreeWithDomain([]byte("app")), DomainHasher([]byte("app"))},
+		"factory": {keccak, FactoryHasher(sha3.NewLegacyKeccak256, []byte("app"))},
+	}
+	for name, tc := range trees {
+		if !bytes.Equal(tc.cmt.hash3(a, b, c), tc.want(a, b, c)) {
+			t.Fatalf("%s: hash3 differs from its stateless hasher", name)
+		}
+		cached := tc.cmt.hasher.Load()
+		tc.cmt.hash3(a, b, c)
+		if cached == nil || tc.cmt.hasher.Load() != cached {
+			t.Fatalf("%s: hasher rebuilt on a later call", name)
+		}
+	}
+}
+
+func TestAddWeightedLiftsHeavyKeys(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	keys := strKeys(500)
+	heavy := map[string]bool{}
+	for i, key := range keys {
+		var weight uint64
+		if i%100 == 7 {
+			weight = uint64(1 + i/100)
+			heavy[string(key)] = true
+		}
+		if _, err := cmt.AddWeighted(key, nil, weight); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustValidate(t, cmt)
+	if !heavy[string(cmt.Root.Key)] || cmt.Root.Weight != 5 {
+		t.Fatalf("root %s has weight %d, want the heaviest key", cmt.Root.Key, cmt.Root.Weight)
+	}
+	var heavyDepth, lightDepth int
+	for _, key := range keys {
+		depth, _ := cmt.pathDepth(key)
+		if heavy[string(key)] {
+			if depth > len(heavy) {
+				t.Errorf("heavy key %s at depth %d", key, depth)
+			}
+			heavyDepth += depth
+		} else {
+			lightDepth += depth
+		}
+	}
+	heavyMean := float64(heavyDepth) / float64(len(heavy))
+	lightMean := float64(lightDepth) / float64(len(keys)-len(heavy))
+	if heavyMean >= lightMean {
+		t.Fatalf("heavy keys average depth %.1f, light keys %.1f", heavyMean, lightMean)
+	}
+}
+
+func TestEqualPrioritiesBreakTiesByKey(t *testing.T) {
+	constant := func([]byte) []byte { return []byte{7} }
+	keys := strKeys(30)
+	var roots [][]byte
+	for _, order := range [][][]byte{keys, reversed(keys)} {
+		cmt := NewCartesianMerkleTree()
+		cmt.PriorityFunc = constant
+		fillTree(t, cmt, order)
+		mustValidate(t, cmt)
+		if err := cmt.Remove(keys[11]); err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, cmt)
+		roots = append(roots, cmt.GetRoot())
+	}
+	if !bytes.Equal(roots[0], roots[1]) {
+		t.Fatal("insertion order changed the root of colliding priorities")
+	}
+}
+
+// reversed returns a reversed copy of keys
+func reversed(keys [][]byte) [][]byte {
+	out := make([][]byte, len(keys))
+	for i, key := range keys {
+		out[len(keys)-1-i] = key
+	}
+	return out
+}
+
+// the constant-time root comparison must accept and reject exactly what
+// bytes.Equal would
+func TestVerifyProofConstantTimeCompare(t *testing.T) {
+	cmt := buildTree(t, strKeys(30))
+	root := cmt.GetRoot()
+	for _, key := range strKeys(30) {
+		proof, _ := cmt.GenerateProof(key)
+		if !cmt.VerifyProof(key, proof) || !VerifyProofAgainstRoot(key, proof, root, nil) {
+			t.Fatalf("%s: valid proof rejected", key)
+		}
+		for _, at := range []int{0, len(root) - 1} {
+			other := append([]byte{}, root...)
+			other[at] ^= 1
+			if VerifyProofAgainstRoot(key, proof, other, nil) {
+				t.Fatalf("%s: accepted against a root differing in byte %d", key, at)
+			}
+		}
+		last := append([]byte{}, proof.Siblings[len(proof.Siblings)-1]...)
+		last[31] ^= 1
+		proof.Siblings[len(proof.Siblings)-1] = last
+		if ok, err := cmt.VerifyProofDetailed(key, proof); ok || !errors.Is(err, ErrRootMismatch) {
+			t.Fatalf("%s: tampered proof gives %v, %v", key, ok, err)
+		}
+	}
+
+	cases := []struct {
+		a, b []byte
+		want bool
+	}{
+		{[]byte{1, 2}, []byte{1, 2}, true},
+		{[]byte{1, 2}, []byte{1, 3}, false},
+		{[]byte{1, 2}, []byte{1, 2, 0}, false},
+		{nil, []byte{}, true},
+	}
+	for _, c := range cases {
+		if got := rootsEqual(c.a, c.b); got != c.want || got != bytes.Equal(c.a, c.b) {
+			t.Errorf("rootsEqual(%x, %x) = %v", c.a, c.b, got)
+		}
+	}
+}
+
+func TestAddReportsInserted(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for _, key := range strKeys(20) {
+		if inserted, err := cmt.Add(key); !inserted || err != nil {
+			t.Fatalf("new key %s: %v, %v", key, inserted, err)
+		}
+	}
+	root := cmt.GetRoot()
+	for _, key := range strKeys(20) {
+		if inserted, err := cmt.Add(append([]byte{}, key...)); inserted || err != nil {
+			t.Fatalf("duplicate %s: %v, %v", key, inserted, err)
+		}
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) || cmt.Size() != 20 {
+		t.Fatal("duplicates changed the tree")
+	}
+	if inserted, err := cmt.Add(nil); inserted || err == nil {
+		t.Fatalf("empty key: %v, %v", inserted, err)
+	}
+	if inserted, err := cmt.AddKV([]byte("key-3"), []byte("v")); inserted || err != nil {
+		t.Fatalf("AddKV duplicate: %v, %v", inserted, err)
+	}
+}
+
+func TestCommitSize(t *testing.T) {
+	keys := strKeys(25)
+	plain := buildTree(t, keys)
+	sized := NewCartesianMerkleTree()
+	sized.CommitSize = true
+	fillTree(t, sized, keys)
+	if bytes.Equal(plain.GetRoot(), sized.GetRoot()) {
+		t.Fatal("committing sizes left the root unchanged")
+	}
+
+	proof, _ := sized.GenerateProof(keys[9])
+	if n, ok := proof.TreeSize(); !ok || n != uint64(len(keys)) {
+		t.Fatalf("proof attests %d keys (%v), want %d", n, ok, len(keys))
+	}
+	if !sized.VerifyProof(keys[9], proof) || !VerifyProofAgainstRoot(keys[9], proof, sized.GetRoot(), nil) {
+		t.Fatal("sized proof doesn't verify")
+	}
+	forged := *proof
+	forged.Sizes = append([]uint64{proof.Sizes[0] + 1}, proof.Sizes[1:]...)
+	if sized.VerifyProof(keys[9], &forged) {
+		t.Fatal("proof claiming one more key verifies")
+	}
+	if plainProof, _ := plain.GenerateProof(keys[9]); len(plainProof.Sizes) != 0 {
+		t.Fatal("plain tree proof carries sizes")
+	}
+}
+
+func TestEmptyAndNilTrees(t *testing.T) {
+	key := []byte("key")
+	for name, cmt := range map[string]*CartesianMerkleTree{"empty": NewCartesianMerkleTree(), "nil": nil} {
+		if cmt.GetRoot() != nil || cmt.Contains(key) || cmt.Size() != 0 {
+			t.Fatalf("%s: root %x, contains %v, size %d", name, cmt.GetRoot(), cmt.Contains(key), cmt.Size())
+		}
+		proof, err := cmt.GenerateProof(key)
+		if err != nil || proof == nil || proof.Existence {
+			t.Fatalf("%s: GenerateProof gives %+v, %v", name, proof, err)
+		}
+		if cmt.VerifyProof(key, proof) {
+			t.Fatalf("%s: an exclusion proof verifies as inclusion", name)
+		}
+		if !cmt.VerifyNonMembership(key, proof) {
+			t.Fatalf("%s: exclusion proof rejected", name)
+		}
+		if _, ok := cmt.Select(0); ok {
+			t.Fatalf("%s: Select(0) found a key", name)
+		}
+		if err := cmt.Remove(key); err == nil {
+			t.Fatalf("%s: Remove of a missing key succeeded", name)
+		}
+	}
+
+	// nothing is included in an empty tree, not even with another tree's proof,
+	// and every key is provably absent
+	empty := NewCartesianMerkleTree()
+	other := buildTree(t, [][]byte{key, []byte("other")})
+	included, _ := other.GenerateProof(key)
+	if empty.VerifyProof(key, included) {
+		t.Fatal("inclusion proof verifies on an empty tree")
+	}
+	for _, k := range append(strKeys(5), key) {
+		proof, _ := empty.GenerateProof(k)
+		if !empty.VerifyNonMembership(k, proof) {
+			t.Fatalf("%s: exclusion proof rejected by an empty tree", k)
+		}
+	}
+
+	var cmt *CartesianMerkleTree
+	if _, err := cmt.Add(key); !errors.Is(err, ErrNilTree) {
+		t.Fatalf("Add on a nil tree: %v", err)
+	}
+	if err := cmt.Remove(key); !errors.Is(err, ErrNilTree) {
+		t.Fatalf("Remove on a nil tree: %v", err)
+	}
+	if err := cmt.Update(key, nil); !errors.Is(err, ErrNilTree) {
+		t.Fatalf("Update on a nil tree: %v", err)
+	}
+}
+
+// tallTree returns a tree of n keys whose priorities grow with the key, a
+// single left-leaning path of height n; keys[0] is the deepest node
+func tallTree(t testing.TB, n int) (*CartesianMerkleTree, [][]byte) {
+	t.Helper()
+	cmt := NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte { return key }
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = uintKey(int64(i))
+	}
+	return fillTree(t, cmt, keys), keys
+}
+
+// recursiveProof is the recursive proof walk GenerateProof replaced, kept as
+// the baseline of BenchmarkProofTallTree. Plain trees only.
+func recursiveProof(cmt *CartesianMerkleTree, node *TreapNode, key []byte, proof *Proof) {
+	childHash := func(child *TreapNode) []byte {
+		if child != nil {
+			return child.MerkleHash
+		}
+		return make([]byte, 32)
+	}
+	cmp := bytes.Compare(key, node.Key)
+	next, other := node.Right, node.Left
+	if cmp < 0 {
+		next, other = node.Left, node.Right
+	}
+	if cmp == 0 || next == nil {
+		proof.Existence = cmp == 0
+		if cmp != 0 {
+			proof.NonExistenceKey = node.Key
+		}
+		proof.Siblings = append(proof.Siblings, childHash(node.Left), childHash(node.Right))
+		return
+	}
+	proof.Siblings = append(proof.Siblings, node.Key, childHash(other))
+	recursiveProof(cmt, next, key, proof)
+}
+
+func TestProofTallTree(t *testing.T) {
+	cmt, keys := tallTree(t, 50000)
+	if cmt.Height() != len(keys) {
+		t.Fatalf("height %d, want %d", cmt.Height(), len(keys))
+	}
+	proof, err := cmt.GenerateProof(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proof.Siblings) != 2*len(keys) || !cmt.VerifyProof(keys[0], proof) {
+		t.Fatalf("proof of the deepest key: %d siblings, valid %v", len(proof.Siblings), cmt.VerifyProof(keys[0], proof))
+	}
+
+	small, smallKeys := tallTree(t, 100)
+	for _, key := range append(smallKeys, uintKey(1000)) {
+		want, _ := small.GenerateProof(key)
+		got := &Proof{Key: key, Siblings: [][]byte{}}
+		recursiveProof(small, small.Root, key, got)
+		if !proofsEqual(got, want) {
+			t.Fatalf("%x: the recursive baseline differs from GenerateProof", key)
+		}
+	}
+}
+
+func BenchmarkProofTallTree(b *testing.B) {
+	cmt, keys := tallTree(b, 10000)
+	b.Run("iterative", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmt.GenerateProof(keys[0])
+		}
+	})
+	b.Run("recursive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			recursiveProof(cmt, cmt.Root, keys[0], &Proof{Key: keys[0], Siblings: [][]byte{}})
+		}
+	})
+}
+
+func TestDomainSeparation(t *testing.T) {
+	keys := strKeys(20)
+	plain := buildTree(t, keys)
+	a := fillTree(t, NewCartesianMerkleTreeWithDomain([]byte("app-a")), keys)
+	b := fillTree(t, NewCartesianMerkleTreeWithDomain([]byte("app-b")), keys)
+	if bytes.Equal(a.GetRoot(), b.GetRoot()) || bytes.Equal(a.GetRoot(), plain.GetRoot()) {
+		t.Fatal("domains don't separate the roots")
+	}
+
+	proof, _ := a.GenerateProof(keys[3])
+	if !a.VerifyProof(keys[3], proof) || !VerifyProofAgainstRoot(keys[3], proof, a.GetRoot(), DomainHasher([]byte("app-a"))) {
+		t.Fatal("proof doesn't verify under its own domain")
+	}
+	for name, hasher := range map[string]func(a, b, c []byte) []byte{
+		"default": nil,
+		"other":   DomainHasher([]byte("app-b")),
+	} {
+		if VerifyProofAgainstRoot(keys[3], proof, a.GetRoot(), hasher) {
+			t.Errorf("proof verifies under the %s domain", name)
+		}
+	}
+	if b.VerifyProof(keys[3], proof) {
+		t.Fatal("proof verifies on a tree of another domain")
+	}
+}
+
+// smallHash is sha256 with all but its last byte zeroed, so real child hashes
+// look almost like the zero hash of an empty child
+type smallHash struct{ hash.Hash }
+
+func (h smallHash) Sum(b []byte) []byte {
+	sum := h.Hash.Sum(nil)
+	for i := range sum[:len(sum)-1] {
+		sum[i] = 0
+	}
+	return append(b, sum...)
+}
+
+func TestVerifySmallTrees(t *testing.T) {
+	trees := map[string]*CartesianMerkleTree{
+		"one node":  buildTree(t, strKeys(1)),
+		"two nodes": buildTree(t, strKeys(2)),
+		"small hashes": fillTree(t, NewCartesianMerkleTreeWithHashFactory(func() hash.Hash {
+			return smallHash{sha256.New()}
+		}), strKeys(40)),
+	}
+	for name, cmt := range trees {
+		for _, key := range cmt.Keys() {
+			proof, _ := cmt.GenerateProof(key)
+			if !cmt.VerifyProof(key, proof) {
+				t.Fatalf("%s: proof of %s doesn't verify", name, key)
+			}
+		}
+		if proof, _ := cmt.GenerateProof([]byte("absent")); cmt.VerifyProof([]byte("absent"), proof) {
+			t.Fatalf("%s: exclusion proof verifies as inclusion", name)
+		}
+	}
+	proof, _ := trees["one node"].GenerateProof([]byte("key-0"))
+	if len(proof.Siblings) != 2 {
+		t.Fatalf("single-node proof has %d siblings", len(proof.Siblings))
+	}
+}
+
+func TestAddCopiesInputs(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	key, value := []byte("key-a"), []byte("value")
+	if _, err := cmt.AddKV(key, value); err != nil {
+		t.Fatal(err)
+	}
+	other := []byte("key-b")
+	if _, err := cmt.Add(other); err != nil {
+		t.Fatal(err)
+	}
+	want := NewCartesianMerkleTree()
+	want.AddKV([]byte("key-a"), []byte("value"))
+	want.Add([]byte("key-b"))
+
+	key[4], value[0], other[4] = 'z', 'V', 'y'
+	if !cmt.Contains([]byte("key-a")) || !cmt.Contains([]byte("key-b")) || cmt.Contains(key) {
+		t.Fatal("mutating the caller's key changed the stored key")
+	}
+	if got, _ := cmt.Get([]byte("key-a")); string(got) != "value" {
+		t.Fatalf("stored value changed to %q", got)
+	}
+	if !bytes.Equal(cmt.GetRoot(), want.GetRoot()) {
+		t.Fatal("mutating the caller's slices changed the root")
+	}
+	mustValidate(t, cmt)
+}
+
+func TestValueHashFunc(t *testing.T) {
+	valueHash := func(value []byte) []byte {
+		sum := sha256.Sum256(value)
+		return sum[:]
+	}
+	build := func(f func([]byte) []byte) *CartesianMerkleTree {
+		cmt := NewCartesianMerkleTree()
+		cmt.ValueHashFunc = f
+		for i, key := range strKeys(20) {
+			if _, err := cmt.AddKV(key, []byte{byte(i), 'v'}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmt
+	}
+	raw, hashed := build(nil), build(valueHash)
+	if bytes.Equal(raw.GetRoot(), hashed.GetRoot()) {
+		t.Fatal("the value hasher left the root unchanged")
+	}
+
+	key := []byte("key-5")
+	proof, _ := hashed.GenerateProof(key)
+	if !hashed.VerifyProof(key, proof) {
+		t.Fatal("proof doesn't verify on its tree")
+	}
+	if value, _ := hashed.Get(key); !bytes.Equal(proof.Value, value) {
+		t.Fatalf("proof carries %x, tree holds %x", proof.Value, value)
+	}
+	committed := *proof
+	committed.Value = valueHash(proof.Value)
+	if !VerifyProofAgainstRoot(key, &committed, hashed.GetRoot(), nil) {
+		t.Fatal("proof with the hashed value doesn't verify statelessly")
+	}
+	if VerifyProofAgainstRoot(key, proof, hashed.GetRoot(), nil) {
+		t.Fatal("the raw value verifies against the value-hashed root")
+	}
+	forged := *proof
+	forged.Value = []byte{6, 'v'}
+	if hashed.VerifyProof(key, &forged) {
+		t.Fatal("proof verifies with another value")
+	}
+}
+
+// recycled nodes must not leak into results taken before their removal, and
+// churn from several goroutines must stay race-free (run with -race)
+func TestNodePoolChurn(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(200) {
+		if _, err := cmt.AddKV(key, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept := []byte("key-7")
+	proof, _ := cmt.GenerateProof(kept)
+	value, _ := cmt.Get(kept)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for round := 0; round < 50; round++ {
+				for i := 10 + w; i < 200; i += 4 {
+					key := []byte(fmt.Sprintf("key-%d", i))
+					if err := cmt.Remove(key); err != nil {
+						t.Error(err)
+						return
+					}
+					cmt.GenerateProof(kept)
+					if _, err := cmt.AddKV(key, []byte{byte(i)}); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	mustValidate(t, cmt)
+	if cmt.Size() != 200 || !cmt.VerifyProof(kept, proof) || !bytes.Equal(value, []byte{7}) {
+		t.Fatalf("after churn: size %d, old proof valid %v, old value %x", cmt.Size(), cmt.VerifyProof(kept, proof), value)
+	}
+}
+
+func BenchmarkChurn(b *testing.B) {
+	cmt := buildTree(b, strKeys(1000))
+	key := []byte("key-500")
+	b.Run("remove+add", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cmt.Remove(key)
+			cmt.Add(key)
+		}
+	})
+	b.Run("duplicate add", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cmt.Add(key)
+		}
+	})
+}
+
+func TestMaxProofDepth(t *testing.T) {
+	cmt, keys := tallTree(t, 300)
+	cmt.MaxProofDepth = 100
+	// keys[i] sits at depth 300-i on the chain
+	for _, c := range []struct {
+		index int
+		ok    bool
+	}{{299, true}, {200, true}, {199, false}, {0, false}} {
+		proof, err := cmt.GenerateProof(keys[c.index])
+		if c.ok != (err == nil) {
+			t.Fatalf("depth %d: %v", 300-c.index, err)
+		}
+		if c.ok && !cmt.VerifyProof(keys[c.index], proof) {
+			t.Fatalf("depth %d: proof doesn't verify", 300-c.index)
+		}
+		if !c.ok && !errors.Is(err, ErrProofTooDeep) {
+			t.Fatalf("depth %d: %v, want ErrProofTooDeep", 300-c.index, err)
+		}
+	}
+	// an absent key below every key walks the whole chain too
+	if _, err := cmt.GenerateProof([]byte{0}); !errors.Is(err, ErrProofTooDeep) {
+		t.Fatalf("absent key past the bound: %v", err)
+	}
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.KeyEqual = bytes.EqualFold
+	cmt.KeyLess = func(a, b []byte) bool { return bytes.Compare(bytes.ToLower(a), bytes.ToLower(b)) < 0 }
+	fillTree(t, cmt, [][]byte{[]byte("ABC"), []byte("xyz"), []byte("Mno"), []byte("def")})
+	if inserted, err := cmt.Add([]byte("abc")); inserted || err != nil {
+		t.Fatalf("abc collides with ABC: %v, %v", inserted, err)
+	}
+	if cmt.Size() != 4 || !cmt.Contains([]byte("aBc")) || !cmt.Contains([]byte("MNO")) {
+		t.Fatal("lookups don't fold case")
+	}
+	mustValidate(t, cmt)
+
+	proof, err := cmt.GenerateProof([]byte("abc"))
+	if err != nil || !proof.Existence || string(proof.Key) != "ABC" {
+		t.Fatalf("proof of abc: %+v, %v", proof, err)
+	}
+	if !cmt.VerifyProof([]byte("abc"), proof) || !cmt.VerifyProof([]byte("ABC"), proof) {
+		t.Fatal("proof doesn't verify under the custom comparator")
+	}
+	if cmt.VerifyProof([]byte("abd"), proof) {
+		t.Fatal("proof verifies for another key")
+	}
+	// the stored bytes are what's committed, so a stateless verifier needs them
+	if !VerifyProofAgainstRoot(proof.Key, proof, cmt.GetRoot(), nil) {
+		t.Fatal("proof doesn't verify statelessly with the stored key")
+	}
+
+	if err := cmt.Remove([]byte("abc")); err != nil || cmt.Contains([]byte("ABC")) {
+		t.Fatalf("Remove(abc): %v", err)
+	}
+}
+
+func TestHashFactories(t *testing.T) {
+	factories := map[string]func() hash.Hash{
+		"sha256":    sha256.New,
+		"sha1":      sha1.New,
+		"sha512":    sha512.New,
+		"keccak256": sha3.NewLegacyKeccak256,
+	}
+	roots := map[string]bool{}
+	for name, f := range factories {
+		cmt := fillTree(t, NewCartesianMerkleTreeWithHashFactory(f), strKeys(40))
+		mustValidate(t, cmt)
+		if ok, err := cmt.VerifySelfRoot(); !ok || err != nil {
+			t.Fatalf("%s: rebuild differs (%v)", name, err)
+		}
+		if len(cmt.GetRoot()) != f().Size() {
+			t.Fatalf("%s: root of %d bytes", name, len(cmt.GetRoot()))
+		}
+		roots[string(cmt.GetRoot())] = true
+		for _, key := range strKeys(40) {
+			proof, _ := cmt.GenerateProof(key)
+			if !cmt.VerifyProof(key, proof) || !VerifyProofAgainstRoot(key, proof, cmt.GetRoot(), FactoryHasher(f, nil)) {
+				t.Fatalf("%s: proof of %s doesn't verify", name, key)
+			}
+		}
+	}
+	if len(roots) != len(factories) {
+		t.Fatal("factories share a root")
+	}
+	sha := fillTree(t, NewCartesianMerkleTreeWithHashFactory(sha256.New), strKeys(40))
+	if !bytes.Equal(sha.GetRoot(), buildTree(t, strKeys(40)).GetRoot()) {
+		t.Fatal("the sha256 factory differs from the default hasher")
+	}
+}
+
+func TestSelfVerifyProofs(t *testing.T) {
+	cmt := buildTree(t, strKeys(50))
+	cmt.SelfVerifyProofs = true
+	for _, key := range strKeys(50) {
+		if _, err := cmt.GenerateProof(key); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+
+	// corrupt a cached child hash: the root's proof carries it as is
+	cmt.Root.Left.MerkleHash = bytes.Repeat([]byte{9}, 32)
+	if _, err := cmt.GenerateProof(cmt.Root.Key); !errors.Is(err, ErrProofSelfCheck) {
+		t.Fatalf("proof through the corrupted node: %v", err)
+	}
+	if _, err := cmt.GenerateProof([]byte("absent")); err != nil {
+		t.Fatalf("exclusion proofs aren't self-checked: %v", err)
+	}
+	cmt.SelfVerifyProofs = false
+	proof, err := cmt.GenerateProof(cmt.Root.Key)
+	if err != nil || cmt.VerifyProof(cmt.Root.Key, proof) {
+		t.Fatalf("without the check: %v, and the proof verifies", err)
+	}
+}
+
+func TestValueCommitments(t *testing.T) {
+	single := func(key string, value []byte) []byte {
+		cmt := NewCartesianMerkleTree()
+		if value == nil {
+			cmt.Add([]byte(key))
+		} else {
+			cmt.AddKV([]byte(key), value)
+		}
+		return cmt.GetRoot()
+	}
+	roots := map[string][]byte{
+		"absent":   single("ab", nil),
+		"empty":    single("ab", []byte{}),
+		"one byte": single("ab", []byte{0}),
+		"shifted":  single("a", []byte("b")), // same bytes, other boundary
+	}
+	seen := map[string]string{}
+	for name, root := range roots {
+		if other, ok := seen[string(root)]; ok {
+			t.Errorf("%s and %s share a root", name, other)
+		}
+		seen[string(root)] = name
+	}
+	if !bytes.Equal(nodeEntry([]byte("ab"), nil), []byte("ab")) {
+		t.Fatal("key-only nodes don't commit the bare key")
+	}
+}
+
+// A PriorityFunc whose output length varies is rejected before the tree moves
+func TestVariableLengthPriority(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte { return key }
+	fillTree(t, cmt, [][]byte{[]byte("aa"), []byte("bb")})
+	root, size := cmt.GetRoot(), cmt.Size()
+
+	for _, key := range [][]byte{[]byte("c"), []byte("ddd")} {
+		if _, err := cmt.Add(key); !errors.Is(err, ErrInvalidPriority) {
+			t.Fatalf("Add(%s): %v", key, err)
+		}
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) || cmt.Size() != size || cmt.Contains([]byte("c")) {
+		t.Fatal("a rejected insert mutated the tree")
+	}
+	mustValidate(t, cmt)
+
+	cmt = NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte { return key }
+	cmt.PriorityLength = 3
+	if _, err := cmt.Add([]byte("aa")); !errors.Is(err, ErrInvalidPriority) || cmt.Root != nil {
+		t.Fatalf("first key of the wrong length: %v", err)
+	}
+	cmt.PriorityFunc = func([]byte) []byte { return nil }
+	if _, err := cmt.Add([]byte("abc")); !errors.Is(err, ErrInvalidPriority) {
+		t.Fatalf("empty priority: %v", err)
+	}
+}
+
+// The leaf-hash verifier agrees with the key-based one on every entry
+func TestVerifyLeafHashAgainstRoot(t *testing.T) {
+	cmt := buildTree(t, strKeys(10))
+	for i := 0; i < 10; i++ {
+		if _, err := cmt.AddKV([]byte(fmt.Sprintf("kv-%d", i)), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := cmt.GetRoot()
+	for _, key := range cmt.Keys() {
+		proof, err := cmt.GenerateInclusionProof(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf := cmt.LeafHash(key, proof.Value)
+		byKey := VerifyProofAgainstRoot(key, proof, root, nil)
+		if byLeaf := VerifyLeafHashAgainstRoot(leaf, proof, root, nil); !byKey || byLeaf != byKey {
+			t.Fatalf("%s: key-based %v, leaf-based %v", key, byKey, byLeaf)
+		}
+
+		if VerifyLeafHashAgainstRoot(cmt.LeafHash(key, []byte("other value")), proof, root, nil) {
+			t.Fatalf("%s: another entry verifies", key)
+		}
+	}
+	if VerifyLeafHashAgainstRoot(nil, &Proof{Existence: true}, root, nil) {
+		t.Fatal("empty leaf hash verifies")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	keys := strKeys(6)
+	cmt := fillTree(t, NewCartesianMerkleTreeWithMaxSize(5), keys[:5])
+	root := cmt.GetRoot()
+	if _, err := cmt.Add(keys[5]); !errors.Is(err, ErrTreeFull) {
+		t.Fatalf("Add to a full tree: %v", err)
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) || cmt.Size() != 5 {
+		t.Fatal("a rejected insert changed the tree")
+	}
+	if inserted, err := cmt.Add(keys[0]); inserted || err != nil {
+		t.Fatalf("re-adding a present key to a full tree: %v, %v", inserted, err)
+	}
+
+	if err := cmt.Remove(keys[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmt.Add(keys[5]); err != nil {
+		t.Fatalf("Add after a removal: %v", err)
+	}
+	if _, err := cmt.Add(keys[2]); !errors.Is(err, ErrTreeFull) {
+		t.Fatalf("Add to a full tree again: %v", err)
+	}
+	mustValidate(t, cmt)
+}
+
+func TestVerifyProofDetailed(t *testing.T) {
+	cmt := buildTree(t, strKeys(20))
+	key := []byte("key-3")
+	proof, _ := cmt.GenerateProof(key)
+	if ok, err := cmt.VerifyProofDetailed(key, proof); !ok || err != nil {
+		t.Fatalf("sound proof: %v", err)
+	}
+
+	odd := *proof
+	odd.Siblings = proof.Siblings[:len(proof.Siblings)-1]
+	wrongRoot := *proof
+	wrongRoot.Siblings = append([][]byte{}, proof.Siblings...)
+	last := len(wrongRoot.Siblings) - 1
+	wrongRoot.Siblings[last] = flipByte(wrongRoot.Siblings[last])
+	absent, _ := cmt.GenerateProof([]byte("absent"))
+	cases := []struct {
+		name  string
+		cmt   *CartesianMerkleTree
+		key   []byte
+		proof *Proof
+		want  error
+	}{
+		{"nil tree", nil, key, proof, ErrNilTree},
+		{"nil proof", cmt, key, nil, ErrNotInclusion},
+		{"exclusion proof", cmt, []byte("absent"), absent, ErrNotInclusion},
+		{"empty key", cmt, nil, proof, ErrEmptyKey},
+		{"other key", cmt, []byte("key-4"), proof, ErrKeyMismatch},
+		{"empty tree", NewCartesianMerkleTree(), key, proof, ErrEmptyTree},
+		{"odd siblings", cmt, key, &odd, ErrMalformedProof},
+		{"tampered sibling", cmt, key, &wrongRoot, ErrRootMismatch},
+	}
+	for _, c := range cases {
+		ok, err := c.cmt.VerifyProofDetailed(c.key, c.proof)
+		if ok || !errors.Is(err, c.want) {
+			t.Errorf("%s: %v, %v, want %v", c.name, ok, err, c.want)
+		}
+		if c.cmt.VerifyProof(c.key, c.proof) {
+			t.Errorf("%s: VerifyProof accepts it", c.name)
+		}
+	}
+}
+
+// countdownCtx is cancelled once Err has been asked n times
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestGenerateProofContext(t *testing.T) {
+	cmt, keys := tallTree(t, 2000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if proof, err := cmt.GenerateProofContext(ctx, keys[0]); proof != nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled context: %v, %v", proof, err)
+	}
+
+	// cancelled ten levels down a 2000-deep path, the descent stops there
+	countdown := &countdownCtx{Context: context.Background(), n: 10}
+	if _, err := cmt.GenerateProofContext(countdown, keys[0]); !errors.Is(err, context.Canceled) {
+		t.Fatalf("context cancelled mid-descent: %v", err)
+	}
+	if countdown.n < -1 {
+		t.Fatalf("descent went on %d levels after the cancellation", -countdown.n-1)
+	}
+
+	if proof, err := cmt.GenerateProofContext(context.Background(), keys[0]); err != nil || !cmt.VerifyProof(keys[0], proof) {
+		t.Fatalf("live context: %v", err)
+	}
+}
+
+func TestVerifyProofAgainstRoots(t *testing.T) {
+	keys := strKeys(20)
+	cmt := buildTree(t, keys[:10])
+	var roots [][]byte
+	for _, key := range keys[10:14] {
+		roots = append(roots, cmt.GetRoot())
+		fillTree(t, cmt, [][]byte{key})
+	}
+	// a proof taken at the third root matches it and no other
+	old := buildTree(t, keys[:12])
+	proof, _ := old.GenerateProof(keys[3])
+	candidates := append([][]byte{nil}, roots...)
+	if index, found := VerifyProofAgainstRoots(keys[3], proof, candidates, nil); !found || index != 3 {
+		t.Fatalf("index %d, found %v, want 3", index, found)
+	}
+	if index, found := VerifyProofAgainstRoots(keys[3], proof, [][]byte{roots[3], roots[0], roots[1]}, nil); found || index != -1 {
+		t.Fatalf("matched index %d of roots it wasn't taken at", index)
+	}
+	if _, found := VerifyProofAgainstRoots(keys[4], proof, roots, nil); found {
+		t.Fatal("matched for another key")
+	}
+	if _, found := VerifyProofAgainstRoots(keys[3], proof, nil, nil); found {
+		t.Fatal("matched without candidates")
+	}
+}
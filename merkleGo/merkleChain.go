@@ -0,0 +1,39 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+)
+
+// HashRootChain commits to an ordered sequence of roots (a checkpoint chain):
+// c = hasher(c, root) for each root in turn, starting from 32 zero bytes, so
+// the result changes if any root is altered, dropped, added or moved.
+// A nil hasher means sha256(a || b). No roots give nil. A nil root (the empty
+// tree's) is a valid checkpoint and hashes as zero bytes.
+func HashRootChain(roots [][]byte, hasher func(a, b []byte) []byte) []byte {
+	if len(roots) == 0 {
+		return nil
+	}
+	if hasher == nil {
+		hasher = default2ArgHash
+	}
+	chain := make([]byte, 32)
+	for _, root := range roots {
+		chain = hasher(chain, root)
+	}
+	return chain
+}
+
+// VerifyRootChain reports whether roots, in this order, hash to chainHash
+func VerifyRootChain(chainHash []byte, roots [][]byte, hasher func(a, b []byte) []byte) bool {
+	if len(chainHash) == 0 {
+		return false
+	}
+	return rootsEqual(HashRootChain(roots, hasher), chainHash)
+}
+
+func default2ArgHash(a, b []byte) []byte {
+	h := sha256.New()
+	h.Write(a)
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,34 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHashRootChain(t *testing.T) {
+	var roots [][]byte
+	cmt := NewCartesianMerkleTree()
+	for _, key := range strKeys(4) {
+		fillTree(t, cmt, [][]byte{key})
+		roots = append(roots, cmt.GetRoot())
+	}
+	chain := HashRootChain(roots, nil)
+	if !VerifyRootChain(chain, roots, nil) {
+		t.Fatal("chain doesn't verify")
+	}
+
+	swapped := [][]byte{roots[1], roots[0], roots[2], roots[3]}
+	if bytes.Equal(HashRootChain(swapped, nil), chain) || VerifyRootChain(chain, swapped, nil) {
+		t.Fatal("reordering the roots kept the chain hash")
+	}
+	if VerifyRootChain(chain, roots[:3], nil) || VerifyRootChain(chain, append(roots, roots[0]), nil) {
+		t.Fatal("dropping or adding a root kept the chain hash")
+	}
+	if VerifyRootChain(nil, roots, nil) || HashRootChain(nil, nil) != nil {
+		t.Fatal("empty chain")
+	}
+	// the empty tree's nil root is a checkpoint of its own
+	if bytes.Equal(HashRootChain(append([][]byte{nil}, roots...), nil), chain) {
+		t.Fatal("a leading empty root was ignored")
+	}
+}
@@ -0,0 +1,58 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Operation codes folded into the apply checksum
+const (
+	opAdd byte = iota + 1
+	opRemove
+	opUpdate
+	opReplace
+)
+
+// ApplyChecksum returns a 32-byte rolling digest of every operation that
+// changed the tree since it was created (all zeros before the first one).
+// A primary and its replicas applying the same operations in the same order
+// end up with the same checksum, so comparing it after each batch catches a
+// diverging replica early. Operations that change nothing (duplicate Add,
+// Remove of an absent key) are not folded in. The root remains the ground truth.
+func (cmt *CartesianMerkleTree) ApplyChecksum() []byte {
+	out := make([]byte, sha256.Size)
+	if cmt == nil {
+		return out
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	copy(out, cmt.checksum)
+	return out
+}
+
+// logOp folds one applied operation into the checksum:
+// checksum = sha256(checksum || op || len(key) || key || hasValue || len(value) || value || weight)
+func (cmt *CartesianMerkleTree) logOp(op byte, key, value []byte, weight uint64) {
+	var buf [8]byte
+	h := sha256.New()
+	if cmt.checksum == nil {
+		h.Write(make([]byte, sha256.Size))
+	} else {
+		h.Write(cmt.checksum)
+	}
+	h.Write([]byte{op})
+	binary.BigEndian.PutUint64(buf[:], uint64(len(key)))
+	h.Write(buf[:])
+	h.Write(key)
+	if value == nil {
+		h.Write([]byte{0})
+	} else {
+		h.Write([]byte{1})
+		binary.BigEndian.PutUint64(buf[:], uint64(len(value)))
+		h.Write(buf[:])
+		h.Write(value)
+	}
+	binary.BigEndian.PutUint64(buf[:], weight)
+	h.Write(buf[:])
+	cmt.checksum = h.Sum(nil)
+}
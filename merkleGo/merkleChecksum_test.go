@@ -0,0 +1,56 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestApplyChecksum(t *testing.T) {
+	apply := func(ops ...func(cmt *CartesianMerkleTree)) *CartesianMerkleTree {
+		cmt := NewCartesianMerkleTree()
+		for _, op := range ops {
+			op(cmt)
+		}
+		return cmt
+	}
+	add := func(key string) func(*CartesianMerkleTree) {
+		return func(cmt *CartesianMerkleTree) { cmt.Add([]byte(key)) }
+	}
+	addKV := func(key, value string) func(*CartesianMerkleTree) {
+		return func(cmt *CartesianMerkleTree) { cmt.AddKV([]byte(key), []byte(value)) }
+	}
+	update := func(key, value string) func(*CartesianMerkleTree) {
+		return func(cmt *CartesianMerkleTree) { cmt.Update([]byte(key), []byte(value)) }
+	}
+	remove := func(key string) func(*CartesianMerkleTree) {
+		return func(cmt *CartesianMerkleTree) { cmt.Remove([]byte(key)) }
+	}
+
+	if !bytes.Equal(NewCartesianMerkleTree().ApplyChecksum(), make([]byte, 32)) {
+		t.Fatal("new tree has a non-zero checksum")
+	}
+	ops := []func(*CartesianMerkleTree){add("a"), addKV("b", "1"), update("b", "2"), add("c"), remove("a")}
+	primary, replica := apply(ops...), apply(ops...)
+	if !bytes.Equal(primary.ApplyChecksum(), replica.ApplyChecksum()) {
+		t.Fatal("identical sequences give different checksums")
+	}
+	noops := apply(append(ops, add("c"), remove("absent"))...)
+	if !bytes.Equal(noops.ApplyChecksum(), primary.ApplyChecksum()) {
+		t.Fatal("operations that changed nothing moved the checksum")
+	}
+
+	divergent := map[string][]func(*CartesianMerkleTree){
+		"reordered": {addKV("b", "1"), add("a"), update("b", "2"), add("c"), remove("a")},
+		"value":     {add("a"), addKV("b", "1"), update("b", "3"), add("c"), remove("a")},
+		"extra":     append(append([]func(*CartesianMerkleTree){}, ops...), add("d")),
+	}
+	for name, seq := range divergent {
+		if bytes.Equal(apply(seq...).ApplyChecksum(), primary.ApplyChecksum()) {
+			t.Errorf("%s: divergent sequence gives the same checksum", name)
+		}
+	}
+	// the reordered replica ends in the same state, only the checksum tells
+	if !bytes.Equal(apply(divergent["reordered"]...).GetRoot(), primary.GetRoot()) {
+		t.Fatal("reordering changed the root")
+	}
+}
@@ -0,0 +1,75 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+)
+
+// CompositeKey is a key made of segments, e.g. (namespace, id), encoded so
+// that byte order is segment order: keys sort by their first segment, then by
+// the next, and so on, whatever bytes the segments hold. Each segment is
+// written with its 0x00 bytes escaped as 0x00 0xff and ends with 0x00 0x01,
+// which sorts below any byte that can continue a segment; so no delimiter can
+// collide and a shorter segment sorts before its extensions.
+type CompositeKey [][]byte
+
+// Encode returns the tree key of k
+func (k CompositeKey) Encode() []byte {
+	var out []byte
+	for _, segment := range k {
+		for _, b := range segment {
+			if b == 0x00 {
+				out = append(out, 0x00, 0xff)
+			} else {
+				out = append(out, b)
+			}
+		}
+		out = append(out, 0x00, 0x01)
+	}
+	return out
+}
+
+// Prefix returns the bytes every key extending k starts with: its encoding.
+// KeysWithPrefix(CompositeKey{namespace}.Prefix()) lists that namespace.
+func (k CompositeKey) Prefix() []byte {
+	return k.Encode()
+}
+
+// DecodeCompositeKey splits an encoded key back into its segments
+func DecodeCompositeKey(key []byte) (CompositeKey, error) {
+	var k CompositeKey
+	segment := []byte{}
+	for i := 0; i < len(key); i++ {
+		if key[i] != 0x00 {
+			segment = append(segment, key[i])
+			continue
+		}
+		if i+1 == len(key) {
+			return nil, errors.New("composite key ends inside an escape")
+		}
+		i++
+		switch key[i] {
+		case 0xff:
+			segment = append(segment, 0x00)
+		case 0x01:
+			k = append(k, segment)
+			segment = []byte{}
+		default:
+			return nil, errors.New("invalid escape in composite key")
+		}
+	}
+	if len(segment) > 0 {
+		return nil, errors.New("composite key has an unterminated segment")
+	}
+	return k, nil
+}
+
+// Compare orders composite keys segment by segment, as their encodings sort
+func (k CompositeKey) Compare(other CompositeKey) int {
+	for i := 0; i < len(k) && i < len(other); i++ {
+		if c := bytes.Compare(k[i], other[i]); c != 0 {
+			return c
+		}
+	}
+	return len(k) - len(other)
+}
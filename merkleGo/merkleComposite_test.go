@@ -0,0 +1,62 @@
+package merkleGo
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompositeKeys(t *testing.T) {
+	// namespaces that are prefixes of one another, segments holding the
+	// escape bytes themselves
+	namespaces := [][]byte{{}, []byte("a"), []byte("a\x00"), []byte("a\x00\x01"), []byte("ab"), []byte("b")}
+	ids := [][]byte{{}, {0x00}, {0x00, 0xff}, {0x01}, []byte("id"), {0xff}}
+	var keys []CompositeKey
+	cmt := NewCartesianMerkleTree()
+	for _, ns := range namespaces {
+		for _, id := range ids {
+			key := CompositeKey{ns, id}
+			keys = append(keys, key)
+			fillTree(t, cmt, [][]byte{key.Encode()})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Compare(keys[j]) < 0 })
+
+	// the tree's byte order is segment order
+	treeKeys := cmt.Keys()
+	if len(treeKeys) != len(keys) {
+		t.Fatalf("%d tree keys for %d composite keys", len(treeKeys), len(keys))
+	}
+	for i, encoded := range treeKeys {
+		decoded, err := DecodeCompositeKey(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Compare(keys[i]) != 0 || len(decoded) != 2 {
+			t.Fatalf("key %d is %q, want %q", i, decoded, keys[i])
+		}
+	}
+
+	for _, ns := range namespaces {
+		var got []CompositeKey
+		for _, encoded := range cmt.KeysWithPrefix(CompositeKey{ns}.Prefix()) {
+			decoded, _ := DecodeCompositeKey(encoded)
+			got = append(got, decoded)
+		}
+		var want []CompositeKey
+		for _, key := range keys {
+			if reflect.DeepEqual(key[0], ns) {
+				want = append(want, key)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("namespace %q: %q, want %q", ns, got, want)
+		}
+	}
+
+	for _, bad := range [][]byte{{0x00}, []byte("unterminated"), {0x00, 0x02}} {
+		if _, err := DecodeCompositeKey(bad); err == nil {
+			t.Errorf("%x decoded", bad)
+		}
+	}
+}
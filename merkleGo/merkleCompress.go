@@ -0,0 +1,111 @@
+package merkleGo
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// CompressedProof is a TopDown Proof whose node-key siblings are stored as
+// deltas against Key: the prefix a node key shares with Key is dropped and only
+// its length kept. In structured key spaces (addresses, prefixed ids) the keys
+// along a path mostly share long prefixes with the proven key, so this shrinks
+// the proof. Child hashes, and entries of nodes holding values (which are
+// hashes), share nothing worth eliding and are kept whole.
+type CompressedProof struct {
+	Existence       bool
+	Key             []byte
+	Value           []byte
+	Siblings        [][]byte   // child hashes as is, node keys without their shared prefix
+	Shared          []int      // length of the dropped prefix of each node-key sibling, in order
+	Sizes           []uint64   // carried over untouched, see Proof.Sizes
+	NonExistenceKey []byte     // carried over untouched, see Proof.NonExistenceKey
+	Path            []PathNode // carried over untouched, see Proof.Path
+}
+
+// CompressProof elides the prefix each node key of p shares with p.Key.
+// p's siblings must be TopDown, see ReorderSiblings.
+func CompressProof(p *Proof) *CompressedProof {
+	c := &CompressedProof{
+		Existence:       p.Existence,
+		Key:             p.Key,
+		Value:           p.Value,
+		Siblings:        make([][]byte, len(p.Siblings)),
+		Sizes:           p.Sizes,
+		NonExistenceKey: p.NonExistenceKey,
+		Path:            p.Path,
+	}
+	copy(c.Siblings, p.Siblings)
+	for i := range p.Siblings {
+		if !isNodeKeySibling(i, len(p.Siblings)) {
+			continue
+		}
+		shared := commonPrefixLength(p.Siblings[i], p.Key)
+		c.Shared = append(c.Shared, shared)
+		c.Siblings[i] = p.Siblings[i][shared:]
+	}
+	return c
+}
+
+// Expand restores the full node keys and returns the original proof.
+// It fails if Shared doesn't match the siblings or claims more than Key holds.
+func (c *CompressedProof) Expand() (*Proof, error) {
+	p := &Proof{
+		Existence:       c.Existence,
+		Key:             c.Key,
+		Value:           c.Value,
+		Siblings:        make([][]byte, len(c.Siblings)),
+		Sizes:           c.Sizes,
+		NonExistenceKey: c.NonExistenceKey,
+		Path:            c.Path,
+	}
+	next := 0
+	for i, s := range c.Siblings {
+		if !isNodeKeySibling(i, len(c.Siblings)) {
+			p.Siblings[i] = s
+			continue
+		}
+		if next >= len(c.Shared) {
+			return nil, fmt.Errorf("%w: no shared prefix length for sibling %d", ErrMalformedProof, i)
+		}
+		shared := c.Shared[next]
+		next++
+		if shared < 0 || shared > len(c.Key) {
+			return nil, fmt.Errorf("%w: sibling %d shares %d bytes of a %d-byte key", ErrMalformedProof, i, shared, len(c.Key))
+		}
+		full := make([]byte, 0, shared+len(s))
+		p.Siblings[i] = append(append(full, c.Key[:shared]...), s...)
+	}
+	if next != len(c.Shared) {
+		return nil, fmt.Errorf("%w: %d shared prefix lengths for %d node keys", ErrMalformedProof, len(c.Shared), next)
+	}
+	return p, nil
+}
+
+// Size returns the number of sibling bytes carried by the proof, counting each
+// shared prefix length as its varint encoding
+func (c *CompressedProof) Size() int {
+	size := 0
+	for _, s := range c.Siblings {
+		size += len(s)
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for _, shared := range c.Shared {
+		size += binary.PutUvarint(buf[:], uint64(shared))
+	}
+	return size
+}
+
+// isNodeKeySibling reports whether sibling i of n (TopDown) is a node's entry
+// rather than a child hash: the first of each pair but the last, which holds
+// the last node's children in both inclusion and exclusion proofs
+func isNodeKeySibling(i, n int) bool {
+	return i%2 == 0 && i < n-2
+}
+
+func commonPrefixLength(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
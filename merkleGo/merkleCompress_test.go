@@ -0,0 +1,58 @@
+package merkleGo
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCompressProof(t *testing.T) {
+	// 20-byte addresses sharing a 16-byte prefix, as one deployer's contracts might
+	keys := make([][]byte, 500)
+	for i := range keys {
+		key := make([]byte, 20)
+		copy(key, "0xdeployer-prefix")
+		binary.BigEndian.PutUint32(key[16:], uint32(i*7919))
+		keys[i] = key
+	}
+	cmt := buildTree(t, keys)
+
+	var full, compressed int
+	for _, key := range append(keys[:100], make([]byte, 20)) {
+		proof, _ := cmt.GenerateProof(key)
+		c := CompressProof(proof)
+		for _, s := range proof.Siblings {
+			full += len(s)
+		}
+		compressed += c.Size()
+
+		expanded, err := c.Expand()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proofsEqual(expanded, proof) {
+			t.Fatalf("%x: expanded proof differs", key)
+		}
+		if proof.Existence && !cmt.VerifyProof(key, expanded) {
+			t.Fatalf("%x: expanded proof doesn't verify", key)
+		}
+		if !proof.Existence && !cmt.VerifyNonMembership(key, expanded) {
+			t.Fatalf("%x: expanded exclusion proof doesn't verify", key)
+		}
+	}
+	t.Logf("sibling bytes: %d full, %d compressed", full, compressed)
+	// each node key drops 16 of its 20 bytes, the 32-byte hashes stay
+	if compressed >= full*4/5 {
+		t.Fatalf("compressed %d of %d bytes", compressed, full)
+	}
+
+	proof, _ := cmt.GenerateProof(keys[0])
+	c := CompressProof(proof)
+	c.Shared[0] = len(c.Key) + 1
+	if _, err := c.Expand(); err == nil {
+		t.Fatal("shared prefix longer than the key accepted")
+	}
+	c.Shared = c.Shared[1:]
+	if _, err := c.Expand(); err == nil {
+		t.Fatal("missing shared prefix length accepted")
+	}
+}
@@ -0,0 +1,90 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"reflect"
+	"sync"
+)
+
+// TreeConfig describes the options a tree was configured with, see Config
+type TreeConfig struct {
+	Hasher          string // node hasher: "sha256", or the name of HashFactory
+	ChildOrder      string // how the hasher orders the two child hashes, "sorted"
+	DomainSeparator []byte
+	Priority        string // name of PriorityFunc, "sha256" when unset
+	ValueHash       string // name of ValueHashFunc, "" when unset
+	CommitSize      bool
+	SiblingOrder    ProofSiblingOrder
+	KeyEndianness   Endianness
+	MaxProofDepth   int
+	Balancing       BalanceStrategy
+}
+
+// hashFuncNames maps the code pointer of every registered hash function
+// (and hash factory) to its name
+var hashFuncNames = struct {
+	sync.RWMutex
+	names map[uintptr]string
+}{names: map[uintptr]string{
+	reflect.ValueOf(sha256.New).Pointer(): "sha256",
+	reflect.ValueOf(sha512.New).Pointer(): "sha512",
+}}
+
+// RegisterHashFunc names fn so Config can report it when it is used as a
+// PriorityFunc or ValueHashFunc. Functions are told apart by their code, so
+// closures created by the same function literal share one name.
+func RegisterHashFunc(name string, fn func([]byte) []byte) {
+	registerFunc(name, fn)
+}
+
+// RegisterHashFactory names f so Config can report it as the HashFactory.
+// sha256.New and sha512.New are registered already.
+func RegisterHashFactory(name string, f func() hash.Hash) {
+	registerFunc(name, f)
+}
+
+func registerFunc(name string, fn interface{}) {
+	hashFuncNames.Lock()
+	defer hashFuncNames.Unlock()
+	hashFuncNames.names[reflect.ValueOf(fn).Pointer()] = name
+}
+
+// hashFuncName returns the registered name of fn (a func value), "" for nil
+// and "custom" if unregistered
+func hashFuncName(fn interface{}) string {
+	if fn == nil || reflect.ValueOf(fn).IsNil() {
+		return ""
+	}
+	hashFuncNames.RLock()
+	defer hashFuncNames.RUnlock()
+	if name, ok := hashFuncNames.names[reflect.ValueOf(fn).Pointer()]; ok {
+		return name
+	}
+	return "custom"
+}
+
+// Config returns the active options of the tree (the defaults for a nil tree)
+func (cmt *CartesianMerkleTree) Config() TreeConfig {
+	cfg := TreeConfig{Hasher: "sha256", ChildOrder: "sorted", Priority: "sha256"}
+	if cmt == nil {
+		return cfg
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	cfg.DomainSeparator = cmt.DomainSeparator
+	if cmt.HashFactory != nil {
+		cfg.Hasher = hashFuncName(cmt.HashFactory)
+	}
+	if cmt.PriorityFunc != nil {
+		cfg.Priority = hashFuncName(cmt.PriorityFunc)
+	}
+	cfg.ValueHash = hashFuncName(cmt.ValueHashFunc)
+	cfg.CommitSize = cmt.CommitSize
+	cfg.SiblingOrder = cmt.SiblingOrder
+	cfg.KeyEndianness = cmt.KeyEndianness
+	cfg.MaxProofDepth = cmt.MaxProofDepth
+	cfg.Balancing = cmt.Balancing
+	return cfg
+}
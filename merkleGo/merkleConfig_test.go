@@ -0,0 +1,54 @@
+package merkleGo
+
+import (
+	"crypto/sha512"
+	"reflect"
+	"testing"
+)
+
+func reverseHash(value []byte) []byte {
+	out := append([]byte{}, value...)
+	reverseBytes(out)
+	return out
+}
+
+func TestConfig(t *testing.T) {
+	RegisterHashFunc("reverse", reverseHash)
+	defaults := TreeConfig{Hasher: "sha256", ChildOrder: "sorted", Priority: "sha256"}
+
+	custom := NewCartesianMerkleTree()
+	custom.PriorityFunc = func(key []byte) []byte { return key }
+	custom.ValueHashFunc = reverseHash
+	custom.CommitSize = true
+	custom.SiblingOrder = BottomUp
+	custom.KeyEndianness = LittleEndian
+	custom.MaxProofDepth = 64
+	custom.Balancing = SizeBalanced
+
+	cases := map[string]struct {
+		cmt  *CartesianMerkleTree
+		want TreeConfig
+	}{
+		"nil":     {nil, defaults},
+		"default": {NewCartesianMerkleTree(), defaults},
+		"domain": {NewCartesianMerkleTreeWithDomain([]byte("app")), TreeConfig{
+			Hasher: "sha256", ChildOrder: "sorted", Priority: "sha256", DomainSeparator: []byte("app"),
+		}},
+		"factory": {NewCartesianMerkleTreeWithHashFactory(sha512.New), TreeConfig{
+			Hasher: "sha512", ChildOrder: "sorted", Priority: "sha256",
+		}},
+		"solidity": {NewCartesianMerkleTreeSolidityV1(), TreeConfig{
+			Hasher: "keccak256", ChildOrder: "sorted", Priority: "solidity-v1",
+		}},
+		"fields": {custom, TreeConfig{
+			Hasher: "sha256", ChildOrder: "sorted", Priority: "custom", ValueHash: "reverse",
+			CommitSize: true, SiblingOrder: BottomUp, KeyEndianness: LittleEndian,
+			MaxProofDepth: 64, Balancing: SizeBalanced,
+		}},
+	}
+	for name, c := range cases {
+		if got := c.cmt.Config(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: %+v, want %+v", name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package merkleGo
+
+import (
+	"math"
+)
+
+// defaultDegenerateThreshold is the BalanceFactor above which the tree counts
+// as degenerate when DegenerateThreshold is 0: random treaps stay around 2-3
+const defaultDegenerateThreshold = 4
+
+// degenerateAlarm is a pending DegenerateCallback call. Inserts fire it after
+// releasing the lock, so the callback may use the tree (e.g. to rebuild it).
+type degenerateAlarm struct {
+	callback     func(height, size int)
+	height, size int
+}
+
+func (a *degenerateAlarm) fire() {
+	if a.callback != nil {
+		a.callback(a.height, a.size)
+	}
+}
+
+// checkDegenerate arms the DegenerateCallback if the insert of key made the
+// tree degenerate. Only key's depth is measured, O(log n) on a healthy tree:
+// when it alone exceeds DegenerateThreshold·log2(size+1), the height does too
+// and is then computed in full. To keep a degenerate tree from reporting on
+// every insert, the callback fires again only once the tree is taller than
+// at the previous call.
+func (cmt *CartesianMerkleTree) checkDegenerate(key []byte) degenerateAlarm {
+	if cmt.DegenerateCallback == nil || cmt.Root == nil {
+		return degenerateAlarm{}
+	}
+	threshold := cmt.DegenerateThreshold
+	if threshold <= 0 {
+		threshold = defaultDegenerateThreshold
+	}
+	size := cmt.Root.Size
+	depth, _ := cmt.pathDepth(key)
+	if float64(depth) <= threshold*math.Log2(float64(size+1)) || depth <= cmt.degenerateHeight {
+		return degenerateAlarm{}
+	}
+	cmt.degenerateHeight = height(cmt.Root)
+	return degenerateAlarm{callback: cmt.DegenerateCallback, height: cmt.degenerateHeight, size: size}
+}
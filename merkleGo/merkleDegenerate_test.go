@@ -0,0 +1,42 @@
+package merkleGo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDegenerateCallback(t *testing.T) {
+	type call struct{ height, size int }
+	var calls []call
+	cmt := NewCartesianMerkleTree()
+	// descending keys ranked by themselves: each lands below the last, a chain
+	cmt.PriorityFunc = func(key []byte) []byte { return key }
+	cmt.DegenerateCallback = func(height, size int) {
+		// the lock is released, the tree can be used
+		if cmt.Size() != size {
+			t.Errorf("callback size %d, tree holds %d", size, cmt.Size())
+		}
+		calls = append(calls, call{height, size})
+	}
+	for i := 19; i >= 0; i-- {
+		fillTree(t, cmt, [][]byte{uintKey(int64(i))})
+	}
+	// a chain of n is degenerate once n > 4·log2(n+1), from n = 17 on, and
+	// grows taller with every insert after that
+	want := []call{{17, 17}, {18, 18}, {19, 19}, {20, 20}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls %v, want %v", calls, want)
+	}
+
+	// a duplicate doesn't make the tree taller: no new call
+	fillTree(t, cmt, [][]byte{uintKey(0)})
+	if len(calls) != len(want) {
+		t.Fatalf("duplicate insert fired the callback: %v", calls)
+	}
+
+	healthy := NewCartesianMerkleTree()
+	healthy.DegenerateCallback = func(height, size int) {
+		t.Errorf("healthy tree reported degenerate: height %d, size %d", height, size)
+	}
+	fillTree(t, healthy, strKeys(1000))
+}
@@ -0,0 +1,46 @@
+package merkleGo
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// NamedHasher is a candidate 3-arg hasher for DetectHasher
+type NamedHasher struct {
+	Name   string
+	Hasher func(a, b, c []byte) []byte
+}
+
+// StandardHashers returns the usual candidates for DetectHasher: "sha256" (the
+// default hasher), "keccak256" (FactoryHasher(sha3.NewLegacyKeccak256, nil),
+// as NewCartesianMerkleTreeSolidityV1 trees hash) and "blake2b-256", none domain separated
+func StandardHashers() []NamedHasher {
+	return []NamedHasher{
+		{Name: "sha256", Hasher: default3ArgHash},
+		{Name: "keccak256", Hasher: FactoryHasher(sha3.NewLegacyKeccak256, nil)},
+		{Name: "blake2b-256", Hasher: FactoryHasher(newBlake2b256, nil)},
+	}
+}
+
+func newBlake2b256() hash.Hash {
+	h, _ := blake2b.New256(nil) // only fails for keys over 64 bytes
+	return h
+}
+
+// DetectHasher diagnoses a proof that won't verify against root: it tries each
+// candidate (see StandardHashers) and returns the name of the first one under
+// which the proof verifies, as VerifyProofAgainstRoot does for proof.Key.
+// found is false if none does, or the proof is not an inclusion proof.
+func DetectHasher(proof *Proof, root []byte, candidates []NamedHasher) (name string, found bool) {
+	if proof == nil {
+		return "", false
+	}
+	for _, c := range candidates {
+		if c.Hasher != nil && VerifyProofAgainstRoot(proof.Key, proof, root, c.Hasher) {
+			return c.Name, true
+		}
+	}
+	return "", false
+}
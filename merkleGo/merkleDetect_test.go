@@ -0,0 +1,37 @@
+package merkleGo
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestDetectHasher(t *testing.T) {
+	keys := strKeys(30)
+	trees := map[string]*CartesianMerkleTree{
+		"sha256":      buildTree(t, keys),
+		"keccak256":   fillTree(t, NewCartesianMerkleTreeWithHashFactory(sha3.NewLegacyKeccak256), keys),
+		"blake2b-256": fillTree(t, NewCartesianMerkleTreeWithHashFactory(newBlake2b256), keys),
+	}
+	for want, cmt := range trees {
+		proof, _ := cmt.GenerateProof(keys[7])
+		if name, found := DetectHasher(proof, cmt.GetRoot(), StandardHashers()); !found || name != want {
+			t.Errorf("%s proof detected as %q, %v", want, name, found)
+		}
+	}
+
+	// candidates the proof wasn't built with don't match
+	keccak := trees["keccak256"]
+	proof, _ := keccak.GenerateProof(keys[7])
+	others := []NamedHasher{StandardHashers()[0], StandardHashers()[2]}
+	if name, found := DetectHasher(proof, keccak.GetRoot(), others); found {
+		t.Fatalf("keccak proof detected as %q", name)
+	}
+	absent, _ := keccak.GenerateProof([]byte("absent"))
+	if _, found := DetectHasher(absent, keccak.GetRoot(), StandardHashers()); found {
+		t.Fatal("exclusion proof detected")
+	}
+	if _, found := DetectHasher(nil, keccak.GetRoot(), StandardHashers()); found {
+		t.Fatal("nil proof detected")
+	}
+}
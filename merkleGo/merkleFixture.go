@@ -0,0 +1,22 @@
+package merkleGo
+
+// ProofItem is one captured (key, proof) pair of a fixture, see VerifyFixture
+type ProofItem struct {
+	Key   []byte
+	Proof *Proof
+}
+
+// VerifyFixture checks every item of a fixture, typically captured from an
+// on-chain system, against root in one call: ok is true when all of them
+// verify, and failed lists the indexes of those that don't, in order, for test
+// assertions. Each item is checked like VerifyProofAgainstRoot, so proofs must
+// be inclusion proofs with TopDown siblings, and a nil hasher means the default
+// 3-arg hasher. An empty fixture passes.
+func VerifyFixture(root []byte, items []ProofItem, hasher func(a, b, c []byte) []byte) (ok bool, failed []int) {
+	for i, item := range items {
+		if !VerifyProofAgainstRoot(item.Key, item.Proof, root, hasher) {
+			failed = append(failed, i)
+		}
+	}
+	return len(failed) == 0, failed
+}
@@ -0,0 +1,49 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestVerifyFixture(t *testing.T) {
+	raw, err := os.ReadFile("testdata/proofs_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Root   string
+		Proofs map[string]*Proof
+	}
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	root, err := hex.DecodeString(fixture.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []ProofItem
+	for key, proof := range fixture.Proofs {
+		if proof.Existence {
+			items = append(items, ProofItem{Key: []byte(key), Proof: proof})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return string(items[i].Key) < string(items[j].Key) })
+	if len(items) < 3 {
+		t.Fatalf("fixture has %d inclusion proofs", len(items))
+	}
+
+	if ok, failed := VerifyFixture(root, items, nil); !ok || failed != nil {
+		t.Fatalf("fixture fails at %v", failed)
+	}
+	items[2].Key = []byte("mutated")
+	if ok, failed := VerifyFixture(root, items, nil); ok || !reflect.DeepEqual(failed, []int{2}) {
+		t.Fatalf("mutated item: %v, failed %v", ok, failed)
+	}
+	if ok, failed := VerifyFixture(root, nil, nil); !ok || failed != nil {
+		t.Fatal("empty fixture fails")
+	}
+}
@@ -0,0 +1,152 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Forest is an ordered set of shard trees committed to by a single root:
+// a binary Merkle tree over the shard roots, leaf = sha256(0x00 || shardRoot),
+// inner node = sha256(0x01 || left || right). On a level with an odd count the
+// last node moves up unchanged instead of being paired with itself.
+// Shard order is part of the commitment.
+type Forest struct {
+	Shards []*CartesianMerkleTree
+}
+
+// ForestProof proves Key in shard ShardIndex and that shard's root in the forest
+type ForestProof struct {
+	ShardIndex int
+	ShardCount int
+	ShardRoot  []byte
+	Shard      *Proof   // inclusion proof of the key against ShardRoot, TopDown
+	Path       [][]byte // sibling hashes from the shard's leaf up to the forest root
+}
+
+// NewForest returns a forest over shards, in that order
+func NewForest(shards ...*CartesianMerkleTree) *Forest {
+	return &Forest{Shards: shards}
+}
+
+// ShardRoots returns the root of every shard, nil for empty shards
+func (f *Forest) ShardRoots() [][]byte {
+	roots := make([][]byte, len(f.Shards))
+	for i, shard := range f.Shards {
+		roots[i] = shard.GetRoot()
+	}
+	return roots
+}
+
+// ForestRoot returns the commitment over all shard roots, nil without shards.
+// Writes to a shard change it; the forest does not lock its shards together.
+func (f *Forest) ForestRoot() []byte {
+	if f == nil || len(f.Shards) == 0 {
+		return nil
+	}
+	level := forestLeaves(f.ShardRoots())
+	for len(level) > 1 {
+		level = forestLevelUp(level)
+	}
+	return level[0]
+}
+
+// GenerateForestProof proves key in shard shardIndex and that shard in the forest.
+// The shard proof and the shard root are read under the shard's lock, so they
+// always match; the proof goes stale, like any proof, once some shard changes.
+func (f *Forest) GenerateForestProof(shardIndex int, key []byte) (*ForestProof, error) {
+	if f == nil || shardIndex < 0 || shardIndex >= len(f.Shards) {
+		return nil, fmt.Errorf("shard index %d out of range", shardIndex)
+	}
+	shard := f.Shards[shardIndex]
+	if shard == nil {
+		return nil, ErrNilTree
+	}
+
+	shard.mu.RLock()
+	proof := shard.generateProof(key)
+	shardRoot := shard.rootHash()
+	shard.mu.RUnlock()
+	if !proof.Existence {
+		return nil, fmt.Errorf("%w: %x in shard %d", ErrKeyNotFound, key, shardIndex)
+	}
+
+	roots := f.ShardRoots()
+	roots[shardIndex] = shardRoot
+	fp := &ForestProof{
+		ShardIndex: shardIndex,
+		ShardCount: len(roots),
+		ShardRoot:  shardRoot,
+		Shard:      proof,
+	}
+	level := forestLeaves(roots)
+	for idx := shardIndex; len(level) > 1; idx /= 2 {
+		if sib := idx ^ 1; sib < len(level) {
+			fp.Path = append(fp.Path, level[sib])
+		}
+		level = forestLevelUp(level)
+	}
+	return fp, nil
+}
+
+// VerifyForestProof checks both levels: the key against the shard root (with
+// hasher, nil means the default 3-arg hasher) and the shard root against forestRoot.
+func VerifyForestProof(forestRoot, key []byte, proof *ForestProof, hasher func(a, b, c []byte) []byte) bool {
+	if proof == nil || proof.ShardIndex < 0 || proof.ShardIndex >= proof.ShardCount {
+		return false
+	}
+	if !VerifyProofAgainstRoot(key, proof.Shard, proof.ShardRoot, hasher) {
+		return false
+	}
+	h := forestLeaf(proof.ShardRoot)
+	path := proof.Path
+	for idx, n := proof.ShardIndex, proof.ShardCount; n > 1; idx, n = idx/2, (n+1)/2 {
+		if idx^1 >= n {
+			continue // promoted unchanged
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if idx%2 == 0 {
+			h = forestNode(h, path[0])
+		} else {
+			h = forestNode(path[0], h)
+		}
+		path = path[1:]
+	}
+	return len(path) == 0 && rootsEqual(h, forestRoot)
+}
+
+func forestLeaves(roots [][]byte) [][]byte {
+	leaves := make([][]byte, len(roots))
+	for i, root := range roots {
+		leaves[i] = forestLeaf(root)
+	}
+	return leaves
+}
+
+func forestLevelUp(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 < len(level) {
+			next = append(next, forestNode(level[i], level[i+1]))
+		} else {
+			next = append(next, level[i])
+		}
+	}
+	return next
+}
+
+func forestLeaf(shardRoot []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(shardRoot)
+	return h.Sum(nil)
+}
+
+func forestNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
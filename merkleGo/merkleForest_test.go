@@ -0,0 +1,75 @@
+package merkleGo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestForestProof(t *testing.T) {
+	// five shards, so one level promotes its odd node unchanged
+	forest := NewForest()
+	for i := 0; i < 5; i++ {
+		keys := make([][]byte, 8)
+		for j := range keys {
+			keys[j] = []byte(fmt.Sprintf("shard-%d-%d", i, j))
+		}
+		forest.Shards = append(forest.Shards, buildTree(t, keys))
+	}
+	root := forest.ForestRoot()
+
+	for i := range forest.Shards {
+		key := []byte(fmt.Sprintf("shard-%d-3", i))
+		proof, err := forest.GenerateForestProof(i, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyForestProof(root, key, proof, nil) {
+			t.Fatalf("shard %d: proof doesn't verify", i)
+		}
+		if VerifyForestProof(root, []byte("shard-0-99"), proof, nil) {
+			t.Fatalf("shard %d: proof verifies another key", i)
+		}
+
+		// the shard level: a sibling hash of the key's proof
+		shardProof := *proof.Shard
+		shardProof.Siblings = append([][]byte{}, shardProof.Siblings...)
+		shardProof.Siblings[len(shardProof.Siblings)-1] = flipByte(shardProof.Siblings[len(shardProof.Siblings)-1])
+		tampered := *proof
+		tampered.Shard = &shardProof
+		if VerifyForestProof(root, key, &tampered, nil) {
+			t.Fatalf("shard %d: tampered shard proof verifies", i)
+		}
+
+		// the forest level: the shard root and the path above it
+		tampered = *proof
+		tampered.ShardRoot = flipByte(proof.ShardRoot)
+		if VerifyForestProof(root, key, &tampered, nil) {
+			t.Fatalf("shard %d: tampered shard root verifies", i)
+		}
+		tampered = *proof
+		tampered.Path = append([][]byte{}, proof.Path...)
+		tampered.Path[0] = flipByte(proof.Path[0])
+		if VerifyForestProof(root, key, &tampered, nil) {
+			t.Fatalf("shard %d: tampered forest path verifies", i)
+		}
+		tampered = *proof
+		tampered.ShardIndex = (i + 1) % len(forest.Shards)
+		if VerifyForestProof(root, key, &tampered, nil) {
+			t.Fatalf("shard %d: proof verifies at another index", i)
+		}
+	}
+
+	if _, err := forest.GenerateForestProof(0, []byte("shard-1-3")); err == nil {
+		t.Fatal("proof for a key of another shard")
+	}
+	if _, err := forest.GenerateForestProof(5, []byte("shard-0-3")); err == nil {
+		t.Fatal("proof for a shard out of range")
+	}
+}
+
+// flipByte returns a copy of b with its first byte changed
+func flipByte(b []byte) []byte {
+	c := append([]byte{}, b...)
+	c[0] ^= 0xff
+	return c
+}
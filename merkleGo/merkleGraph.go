@@ -0,0 +1,125 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// NodeEdges is one node of AdjacencyList: its key, its children's keys (nil
+// when missing) and its hash. Value is committed as in PathNode, so the hashes
+// can be recomputed from the list alone (see RootFromAdjacencyList).
+type NodeEdges struct {
+	Key        []byte
+	LeftKey    []byte
+	RightKey   []byte
+	MerkleHash []byte
+	Value      []byte // as committed: after ValueHashFunc, nil for key-only nodes
+}
+
+// AdjacencyList returns every node with the keys of its children, in ascending
+// key order, for graph tools to analyze or rebuild the structure. The root is
+// the one node no other lists as a child. The slices must not be modified.
+func (cmt *CartesianMerkleTree) AdjacencyList() []NodeEdges {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	var out []NodeEdges
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		edges := NodeEdges{Key: node.Key, MerkleHash: node.MerkleHash, Value: cmt.pathNode(node).Value}
+		if node.Left != nil {
+			edges.LeftKey = node.Left.Key
+		}
+		if node.Right != nil {
+			edges.RightKey = node.Right.Key
+		}
+		out = append(out, edges)
+		return true
+	})
+	return out
+}
+
+// RootFromAdjacencyList rebuilds the tree AdjacencyList described, recomputing
+// every hash with cmt's hashing configuration, and returns the root. It fails
+// if the edges don't form a single tree or a listed MerkleHash is not the
+// recomputed one. Search-tree and heap order are not checked (see Validate).
+func (cmt *CartesianMerkleTree) RootFromAdjacencyList(edges []NodeEdges) ([]byte, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	if len(edges) == 0 {
+		return nil, nil
+	}
+	byKey := make(map[string]*NodeEdges, len(edges))
+	isChild := make(map[string]bool, len(edges))
+	for i := range edges {
+		e := &edges[i]
+		if _, dup := byKey[string(e.Key)]; dup {
+			return nil, fmt.Errorf("key %x listed twice", e.Key)
+		}
+		byKey[string(e.Key)] = e
+		for _, child := range [][]byte{e.LeftKey, e.RightKey} {
+			if child == nil {
+				continue
+			}
+			if isChild[string(child)] {
+				return nil, fmt.Errorf("key %x has two parents", child)
+			}
+			isChild[string(child)] = true
+		}
+	}
+	var root *NodeEdges
+	for i := range edges {
+		if !isChild[string(edges[i].Key)] {
+			if root != nil {
+				return nil, errors.New("edges form more than one tree")
+			}
+			root = &edges[i]
+		}
+	}
+	if root == nil {
+		return nil, errors.New("edges form a cycle")
+	}
+
+	visited := 0
+	var hashOf func(key []byte) ([]byte, int, error)
+	hashOf = func(key []byte) ([]byte, int, error) {
+		if key == nil {
+			return make([]byte, 32), 0, nil
+		}
+		e, ok := byKey[string(key)]
+		if !ok {
+			return nil, 0, fmt.Errorf("child key %x is not listed", key)
+		}
+		visited++
+		leftH, leftSize, err := hashOf(e.LeftKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		rightH, rightSize, err := hashOf(e.RightKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		size := leftSize + rightSize + 1
+		entry := nodeEntry(e.Key, e.Value)
+		if cmt.CommitSize {
+			entry = sizedEntry(entry, uint64(size))
+		}
+		h := cmt.hash3(entry, leftH, rightH)
+		if !bytes.Equal(h, e.MerkleHash) {
+			return nil, 0, fmt.Errorf("key %x lists a hash that does not match its subtree", e.Key)
+		}
+		return h, size, nil
+	}
+	rootHash, _, err := hashOf(root.Key)
+	if err != nil {
+		return nil, err
+	}
+	if visited != len(edges) {
+		// the unreachable nodes form a cycle of their own
+		return nil, errors.New("edges form a cycle")
+	}
+	return rootHash, nil
+}
@@ -0,0 +1,51 @@
+package merkleGo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestAdjacencyList(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(50))
+		for i := 0; i < 10; i++ {
+			if _, err := cmt.AddKV([]byte(fmt.Sprintf("kv-%d", i)), []byte("value")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		edges := cmt.AdjacencyList()
+		if len(edges) != cmt.Size() {
+			t.Fatalf("CommitSize %v: %d entries for %d keys", commitSize, len(edges), cmt.Size())
+		}
+		root, err := cmt.RootFromAdjacencyList(edges)
+		if err != nil || !bytes.Equal(root, cmt.GetRoot()) {
+			t.Fatalf("CommitSize %v: rebuilt root %x, %v, want %x", commitSize, root, err, cmt.GetRoot())
+		}
+	}
+
+	cmt := buildTree(t, strKeys(20))
+	// copy before breaking, the list shares the tree's slices
+	broken := func(edit func(edges []NodeEdges) []NodeEdges) []NodeEdges {
+		return edit(append([]NodeEdges{}, cmt.AdjacencyList()...))
+	}
+	cases := map[string][]NodeEdges{
+		"missing node": broken(func(e []NodeEdges) []NodeEdges { return e[1:] }),
+		"listed twice": broken(func(e []NodeEdges) []NodeEdges { return append(e, e[0]) }),
+		"wrong hash": broken(func(e []NodeEdges) []NodeEdges {
+			e[3].MerkleHash = flipByte(e[3].MerkleHash)
+			return e
+		}),
+		"wrong value": broken(func(e []NodeEdges) []NodeEdges {
+			e[3].Value = []byte("value")
+			return e
+		}),
+	}
+	for name, edges := range cases {
+		if _, err := cmt.RootFromAdjacencyList(edges); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package merkleGo
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// uintKey is n as a bytes32 key, the contract's uint256 keys
+func uintKey(n int64) []byte {
+	return KeyFromBigInt(big.NewInt(n))
+}
+
+// strKeys returns the keys "key-0" .. "key-<n-1>"
+func strKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+	return keys
+}
+
+// buildTree adds keys to a fresh tree, failing the test on any error
+func buildTree(t testing.TB, keys [][]byte) *CartesianMerkleTree {
+	t.Helper()
+	return fillTree(t, NewCartesianMerkleTree(), keys)
+}
+
+// fillTree adds keys to cmt, failing the test on any error
+func fillTree(t testing.TB, cmt *CartesianMerkleTree, keys [][]byte) *CartesianMerkleTree {
+	t.Helper()
+	for _, key := range keys {
+		if _, err := cmt.Add(key); err != nil {
+			t.Fatalf("Add(%x): %v", key, err)
+		}
+	}
+	return cmt
+}
+
+// mustValidate fails the test if cmt breaks a structural invariant
+func mustValidate(t testing.TB, cmt *CartesianMerkleTree) {
+	t.Helper()
+	if err := cmt.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VerifyProofHex is VerifyProofAgainstRoot for inputs held as hex strings, an
+// optional 0x prefix allowed on each. It covers inclusion proofs of key-only
+// nodes with TopDown siblings; a malformed string is an error, a proof that
+// doesn't match the root is just false. Non-existence proofs can't be checked
+// statelessly and are an error. A nil hasher means the default 3-arg hasher.
+func VerifyProofHex(keyHex, rootHex string, siblingsHex []string, existence bool, hasher func(a, b, c []byte) []byte) (bool, error) {
+	if !existence {
+		return false, errors.New("non-existence proofs can only be verified by the tree")
+	}
+	key, err := decodeHex(keyHex)
+	if err != nil {
+		return false, fmt.Errorf("key: %w", err)
+	}
+	root, err := decodeHex(rootHex)
+	if err != nil {
+		return false, fmt.Errorf("root: %w", err)
+	}
+	proof := &Proof{Existence: true, Key: key, Siblings: make([][]byte, len(siblingsHex))}
+	for i, s := range siblingsHex {
+		if proof.Siblings[i], err = decodeHex(s); err != nil {
+			return false, fmt.Errorf("sibling %d: %w", i, err)
+		}
+	}
+	return VerifyProofAgainstRoot(key, proof, root, hasher), nil
+}
+
+// decodeHex decodes s with or without a 0x/0X prefix
+func decodeHex(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,51 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyProofHex(t *testing.T) {
+	cmt := buildTree(t, strKeys(20))
+	key := []byte("key-7")
+	proof, err := cmt.GenerateProof(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyHex, rootHex := hex.EncodeToString(key), "0x"+hex.EncodeToString(cmt.GetRoot())
+	siblings := make([]string, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		siblings[i] = hex.EncodeToString(sibling)
+	}
+
+	if ok, err := VerifyProofHex(keyHex, rootHex, siblings, true, nil); err != nil || !ok {
+		t.Fatalf("valid hex: %v %v", ok, err)
+	}
+
+	tampered := []byte(rootHex)
+	if tampered[2] == 'a' {
+		tampered[2] = 'b'
+	} else {
+		tampered[2] = 'a'
+	}
+	if ok, err := VerifyProofHex(keyHex, string(tampered), siblings, true, nil); err != nil || ok {
+		t.Fatalf("tampered root: %v %v", ok, err)
+	}
+
+	badSiblings := append([]string{}, siblings...)
+	badSiblings[0] = "zz"
+	for name, args := range map[string][2]string{
+		"key":  {"0xg1", rootHex},
+		"root": {keyHex, "abc"},
+	} {
+		if _, err := VerifyProofHex(args[0], args[1], siblings, true, nil); err == nil {
+			t.Errorf("malformed %s accepted", name)
+		}
+	}
+	if _, err := VerifyProofHex(keyHex, rootHex, badSiblings, true, nil); err == nil {
+		t.Error("malformed sibling accepted")
+	}
+	if _, err := VerifyProofHex(keyHex, rootHex, siblings, false, nil); err == nil {
+		t.Error("non-existence proof accepted")
+	}
+}
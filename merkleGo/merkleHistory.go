@@ -0,0 +1,156 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// rootRecord is the root the tree had at a given version
+type rootRecord struct {
+	version int
+	root    []byte
+}
+
+// Version returns how many times the root changed since the tree was created:
+// 0 for a new tree, then +1 on every mutation that changes the root.
+// Mutations leaving the root unchanged (duplicate Add, RecomputeHashes on a
+// consistent tree) don't count.
+func (cmt *CartesianMerkleTree) Version() int {
+	if cmt == nil {
+		return 0
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return cmt.version
+}
+
+// recordRoot bumps the version if the root changed since the last record.
+// Mutators defer it right after taking the lock.
+func (cmt *CartesianMerkleTree) recordRoot() {
+	root := cmt.rootHash()
+	var last []byte
+	if n := len(cmt.history); n > 0 {
+		last = cmt.history[n-1].root
+	}
+	if bytes.Equal(root, last) {
+		return
+	}
+	cmt.version++
+	cmt.history = append(cmt.history, rootRecord{version: cmt.version, root: root})
+	cmt.negCache.clear()
+	cmt.onRootChange()
+	if cmt.HistoryLimit > 0 {
+		cmt.pruneHistory(cmt.HistoryLimit)
+	}
+}
+
+// ErrHistoryPruned is returned when a proof may be for a root version that
+// PruneHistory (or HistoryLimit) already dropped
+var ErrHistoryPruned = errors.New("root history pruned")
+
+// PruneHistory keeps the recorded roots and the snapshots of the last
+// keepVersions versions only (the current one included, so at least 1) and
+// frees the rest. Verifying against a dropped root then fails with
+// ErrHistoryPruned instead of an unknown-root error. The apply checksum is a
+// fixed-size rolling digest and needs no pruning.
+func (cmt *CartesianMerkleTree) PruneHistory(keepVersions int) {
+	if cmt == nil {
+		return
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	cmt.pruneHistory(keepVersions)
+}
+
+func (cmt *CartesianMerkleTree) pruneHistory(keepVersions int) {
+	if keepVersions < 1 {
+		keepVersions = 1
+	}
+	oldest := cmt.version - keepVersions + 1
+	if oldest <= cmt.prunedBefore {
+		return
+	}
+	cmt.prunedBefore = oldest
+
+	drop := 0
+	for drop < len(cmt.history) && cmt.history[drop].version < oldest {
+		drop++
+	}
+	if drop > 0 {
+		// copy so the dropped records' backing array can be collected
+		cmt.history = append([]rootRecord(nil), cmt.history[drop:]...)
+	}
+	drop = 0
+	for drop < len(cmt.snapshots) && cmt.snapshots[drop].Version < oldest {
+		drop++
+	}
+	if drop > 0 {
+		cmt.snapshots = append([]*TreeSnapshot(nil), cmt.snapshots[drop:]...)
+	}
+}
+
+// rootVersionOf returns the latest version whose root is root
+func (cmt *CartesianMerkleTree) rootVersionOf(root []byte) (int, bool) {
+	for i := len(cmt.history) - 1; i >= 0; i-- {
+		if rootsEqual(root, cmt.history[i].root) {
+			return cmt.history[i].version, true
+		}
+	}
+	return 0, false
+}
+
+// VerifyProofFresh verifies an inclusion proof against every root the tree has
+// had and accepts it only if the root it proves was still current at minVersion
+// or later (see Version). A root seen again later counts as its latest version.
+// It errors when the proof matches no known root or is older than minVersion.
+func (cmt *CartesianMerkleTree) VerifyProofFresh(key []byte, proof *Proof, minVersion int) (bool, error) {
+	if cmt == nil {
+		return false, ErrNilTree
+	}
+	if proof == nil || !proof.Existence || len(key) == 0 || len(proof.Key) == 0 {
+		return false, errors.New("not an inclusion proof")
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	computedRoot := cmt.rebuildFromProof(key, ReorderSiblings(proof, cmt.SiblingOrder, TopDown))
+	version, ok := cmt.rootVersionOf(computedRoot)
+	if !ok && cmt.prunedBefore > 0 {
+		return false, fmt.Errorf("%w: proof matches no retained root, versions before %d are gone", ErrHistoryPruned, cmt.prunedBefore)
+	}
+	if !ok {
+		return false, errors.New("proof matches no known root")
+	}
+	if version < minVersion {
+		return false, fmt.Errorf("proof is for root version %d, older than %d", version, minVersion)
+	}
+	return true, nil
+}
+
+// Reserve hints that about n keys are about to be inserted: the nodes they need
+// are allocated at once, in one block, and the root history grows room for as
+// many versions, instead of both growing one insert at a time. It never changes
+// what the tree holds or commits to, and unused reservations are just memory.
+// The block is only freed once all of its nodes are, so don't over-reserve on
+// trees that shrink a lot afterwards.
+func (cmt *CartesianMerkleTree) Reserve(n int) {
+	if cmt == nil || n <= 0 {
+		return
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+
+	if len(cmt.reserved) < n {
+		cmt.reserved = make([]TreapNode, n)
+	}
+	if cmt.HistoryLimit > 0 && n > cmt.HistoryLimit {
+		// pruning caps the history anyway
+		n = cmt.HistoryLimit
+	}
+	if cap(cmt.history)-len(cmt.history) < n {
+		history := make([]rootRecord, len(cmt.history), len(cmt.history)+n)
+		copy(history, cmt.history)
+		cmt.history = history
+	}
+}
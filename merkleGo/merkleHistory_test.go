@@ -0,0 +1,116 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestVerifyProofFresh(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	if cmt.Version() != 0 {
+		t.Fatalf("new tree at version %d", cmt.Version())
+	}
+	fillTree(t, cmt, strKeys(10))
+	key := []byte("key-3")
+	old, _ := cmt.GenerateProof(key)
+	oldVersion := cmt.Version()
+	if oldVersion != 10 {
+		t.Fatalf("version %d after 10 inserts", oldVersion)
+	}
+	cmt.Add([]byte("key-3")) // duplicate, the root doesn't change
+	if cmt.Version() != oldVersion {
+		t.Fatal("a duplicate Add bumped the version")
+	}
+
+	fillTree(t, cmt, [][]byte{[]byte("new")})
+	current, _ := cmt.GenerateProof(key)
+	if ok, err := cmt.VerifyProofFresh(key, current, cmt.Version()); !ok || err != nil {
+		t.Fatalf("current proof: %v, %v", ok, err)
+	}
+	if ok, err := cmt.VerifyProofFresh(key, old, oldVersion); !ok || err != nil {
+		t.Fatalf("old proof at its own version: %v, %v", ok, err)
+	}
+	if ok, err := cmt.VerifyProofFresh(key, old, cmt.Version()); ok || err == nil {
+		t.Fatal("old proof accepted as current")
+	}
+
+	// removing "new" brings the old root back: it's current again
+	if err := cmt.Remove([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cmt.VerifyProofFresh(key, old, cmt.Version()); !ok || err != nil {
+		t.Fatalf("proof of a root seen again: %v, %v", ok, err)
+	}
+
+	forged := *current
+	forged.Key = []byte("key-4")
+	if ok, _ := cmt.VerifyProofFresh([]byte("key-4"), &forged, 0); ok {
+		t.Fatal("forged proof accepted")
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.SetSnapshotPolicy(2, 0, 0)
+	fillTree(t, cmt, strKeys(10))
+	old, _ := cmt.GenerateProof([]byte("key-1"))
+	fillTree(t, cmt, strKeys(20)[10:])
+	if len(cmt.history) != 20 || len(cmt.snapshots) != 10 {
+		t.Fatalf("%d roots and %d snapshots before pruning", len(cmt.history), len(cmt.snapshots))
+	}
+
+	cmt.PruneHistory(5)
+	if len(cmt.history) != 5 || len(cmt.snapshots) != 3 {
+		t.Fatalf("%d roots and %d snapshots kept, want 5 and 3", len(cmt.history), len(cmt.snapshots))
+	}
+	if _, err := cmt.VerifyProofFresh([]byte("key-1"), old, 0); !errors.Is(err, ErrHistoryPruned) {
+		t.Fatalf("proof of a pruned root: %v", err)
+	}
+	current, _ := cmt.GenerateProof([]byte("key-1"))
+	if ok, err := cmt.VerifyProofFresh([]byte("key-1"), current, cmt.Version()); !ok || err != nil {
+		t.Fatalf("current proof after pruning: %v, %v", ok, err)
+	}
+
+	cmt.PruneHistory(0)
+	if len(cmt.history) != 1 || len(cmt.snapshots) != 1 {
+		t.Fatalf("%d roots and %d snapshots kept, want the current one", len(cmt.history), len(cmt.snapshots))
+	}
+
+	// HistoryLimit prunes as the tree changes
+	cmt = NewCartesianMerkleTree()
+	cmt.HistoryLimit = 3
+	fillTree(t, cmt, strKeys(10))
+	if len(cmt.history) != 3 {
+		t.Fatalf("HistoryLimit 3 kept %d roots", len(cmt.history))
+	}
+}
+
+func TestReserve(t *testing.T) {
+	keys := strKeys(500)
+	cmt := NewCartesianMerkleTree()
+	cmt.Reserve(400) // fewer than needed: the rest is allocated as usual
+	fillTree(t, cmt, keys)
+	mustValidate(t, cmt)
+	if !bytes.Equal(cmt.GetRoot(), buildTree(t, keys).GetRoot()) || cmt.Version() != len(keys) {
+		t.Fatal("Reserve changed what the tree commits to")
+	}
+}
+
+// Adding 10k keys, with and without a Reserve beforehand
+func BenchmarkBulkInsert(b *testing.B) {
+	keys := strKeys(10000)
+	for _, reserve := range []bool{false, true} {
+		b.Run(fmt.Sprintf("reserve=%v", reserve), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cmt := NewCartesianMerkleTree()
+				if reserve {
+					cmt.Reserve(len(keys))
+				}
+				fillTree(b, cmt, keys)
+			}
+		})
+	}
+}
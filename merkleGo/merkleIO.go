@@ -0,0 +1,178 @@
+package merkleGo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ImportFrom reads newline-delimited keys from r and inserts them one by one,
+// so the input never has to fit in memory. Empty lines are skipped.
+// count is the number of keys that were new to the tree.
+func (cmt *CartesianMerkleTree) ImportFrom(r io.Reader) (count int, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		key := bytes.TrimSuffix(line, []byte{'\n'})
+		if len(key) > 0 {
+			inserted, err := cmt.Add(key)
+			if err != nil {
+				return count, err
+			}
+			if inserted {
+				count++
+			}
+		}
+		if readErr == io.EOF {
+			return count, nil
+		}
+		if readErr != nil {
+			return count, readErr
+		}
+	}
+}
+
+// ExportTo writes every key to w in ascending order, one per line,
+// in the format read back by ImportFrom. Keys hashed by MaxKeyLength are
+// written as originally inserted. Keys containing a newline can't be
+// represented and make it fail.
+func (cmt *CartesianMerkleTree) ExportTo(w io.Writer) error {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	var err error
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		key := cmt.originalKey(node.Key)
+		if bytes.IndexByte(key, '\n') >= 0 {
+			err = fmt.Errorf("key %x contains a newline", key)
+			return false
+		}
+		if _, err = bw.Write(key); err != nil {
+			return false
+		}
+		err = bw.WriteByte('\n')
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// noValue is the value length marking a key-only record, see DumpRecords
+const noValue = math.MaxUint32
+
+// maxRecordField bounds the key and value lengths LoadRecords accepts, so a
+// corrupt length can't make it allocate gigabytes
+const maxRecordField = 64 << 20
+
+// LoadRecords reads the binary records written by DumpRecords from r and
+// inserts them one by one, so the input never has to fit in memory. Each record
+// is a u32 big-endian key length, the key, a u32 big-endian value length, the
+// value and the node's weight as a u64 big-endian; a value length of 0xffffffff
+// marks a key-only node. Records are inserted with AddWeighted, which for a
+// zero weight is Add or AddKV. A stream cut inside a record fails with an error
+// wrapping io.ErrUnexpectedEOF, after inserting the records before it.
+// count is the number of keys that were new to the tree.
+func (cmt *CartesianMerkleTree) LoadRecords(r io.Reader) (count int, err error) {
+	reader := bufio.NewReader(r)
+	for record := 0; ; record++ {
+		key, err := readRecordField(reader, false)
+		if err == io.EOF {
+			return count, nil
+		}
+		if err == nil {
+			var value []byte
+			var weight [8]byte
+			if value, err = readRecordField(reader, true); err == nil {
+				_, err = io.ReadFull(reader, weight[:])
+			}
+			if err == nil {
+				var inserted bool
+				inserted, err = cmt.AddWeighted(key, value, binary.BigEndian.Uint64(weight[:]))
+				if inserted {
+					count++
+				}
+			}
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return count, fmt.Errorf("record %d: %w", record, err)
+		}
+	}
+}
+
+// readRecordField reads one length-prefixed field. io.EOF means the stream
+// ended cleanly before the field, io.ErrUnexpectedEOF that it ended inside it.
+// A value may be key-only (nil, see noValue), a key may not be empty.
+func readRecordField(r io.Reader, isValue bool) ([]byte, error) {
+	var n [4]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	switch {
+	case isValue && size == noValue:
+		return nil, nil
+	case !isValue && size == 0:
+		return nil, errors.New("empty key")
+	case size > maxRecordField:
+		return nil, fmt.Errorf("field of %d bytes exceeds the %d byte limit", size, maxRecordField)
+	}
+	field := make([]byte, size)
+	if _, err := io.ReadFull(r, field); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return field, nil
+}
+
+// DumpRecords writes every key with its value and weight to w in ascending key
+// order, in the binary format read back by LoadRecords. Unlike ExportTo it
+// handles any key and keeps values and weights, so loading the dump into an
+// empty tree of the same configuration rebuilds the same root. Keys hashed by
+// MaxKeyLength are written as originally inserted.
+func (cmt *CartesianMerkleTree) DumpRecords(w io.Writer) error {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	var err error
+	var n [4]byte
+	var weight [8]byte
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		key := cmt.originalKey(node.Key)
+		binary.BigEndian.PutUint32(n[:], uint32(len(key)))
+		bw.Write(n[:])
+		bw.Write(key)
+		if node.Value == nil {
+			binary.BigEndian.PutUint32(n[:], noValue)
+		} else {
+			binary.BigEndian.PutUint32(n[:], uint32(len(node.Value)))
+		}
+		bw.Write(n[:])
+		bw.Write(node.Value)
+		binary.BigEndian.PutUint64(weight[:], node.Weight)
+		_, err = bw.Write(weight[:])
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,80 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDumpLoadRecordsKeepsWeightsAndValues(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(40) {
+		var value []byte
+		switch i % 3 {
+		case 1:
+			value = []byte{}
+		case 2:
+			value = []byte{byte(i)}
+		}
+		if _, err := cmt.AddWeighted(key, value, uint64(i%5)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := cmt.DumpRecords(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewCartesianMerkleTree()
+	count, err := loaded.LoadRecords(bytes.NewReader(buf.Bytes()))
+	if err != nil || count != 40 {
+		t.Fatalf("loaded %d records: %v", count, err)
+	}
+	mustValidate(t, loaded)
+	if !bytes.Equal(loaded.GetRoot(), cmt.GetRoot()) {
+		t.Fatal("dump and load changed the root")
+	}
+	if value, _ := loaded.Get([]byte("key-0")); value != nil {
+		t.Fatal("key-only node came back with a value")
+	}
+	if value, _ := loaded.Get([]byte("key-1")); value == nil || len(value) != 0 {
+		t.Fatal("empty value didn't come back")
+	}
+
+	// a stream cut inside the last weight
+	count, err = NewCartesianMerkleTree().LoadRecords(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+	if !errors.Is(err, io.ErrUnexpectedEOF) || count != 39 {
+		t.Fatalf("truncated dump: %d records, %v", count, err)
+	}
+}
+
+func TestImportFrom(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	count, err := cmt.ImportFrom(strings.NewReader("a\nb\n\nc\nb\nd"))
+	if err != nil || count != 4 {
+		t.Fatalf("imported %d keys: %v", count, err)
+	}
+	want := buildTree(t, [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	if !bytes.Equal(cmt.GetRoot(), want.GetRoot()) {
+		t.Fatal("import differs from adding the keys")
+	}
+
+	src := buildTree(t, strKeys(500))
+	var buf bytes.Buffer
+	if err := src.ExportTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewCartesianMerkleTree()
+	if count, err := dst.ImportFrom(&buf); err != nil || count != 500 {
+		t.Fatalf("round trip imported %d keys: %v", count, err)
+	}
+	if !bytes.Equal(dst.GetRoot(), src.GetRoot()) {
+		t.Fatal("export then import changed the root")
+	}
+
+	if err := buildTree(t, [][]byte{[]byte("two\nlines")}).ExportTo(io.Discard); err == nil {
+		t.Fatal("exported a key containing a newline")
+	}
+}
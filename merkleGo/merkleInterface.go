@@ -0,0 +1,115 @@
+package merkleGo
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-merkletree-sql/v2"
+)
+
+// MerkleTree is the API both tree kinds share through their adapters
+// (CartesianMerkleTree.AsMerkleTree, SimpleMerkleTree.AsMerkleTree), so callers
+// can be written once against either backend. Roots are 32 bytes big-endian.
+type MerkleTree interface {
+	// AddKV inserts a key/value pair, failing if the key is already present
+	AddKV(key, value []byte) error
+	// Root returns the current root, nil for an empty CMT
+	Root() []byte
+	// Prove returns the inclusion proof of key, ErrKeyNotFound if absent
+	Prove(key []byte) (TreeProof, error)
+	// Verify checks that proof proves key => value under root
+	Verify(root, key, value []byte, proof TreeProof) bool
+}
+
+// TreeProof is a proof returned by MerkleTree.Prove: a *Proof for the CMT,
+// a *merkletree.Proof for the SMT. Only the implementation that produced it
+// can verify it.
+type TreeProof interface{}
+
+// AsMerkleTree returns the tree behind the MerkleTree interface
+func (cmt *CartesianMerkleTree) AsMerkleTree() MerkleTree {
+	return cmtAdapter{cmt}
+}
+
+type cmtAdapter struct {
+	cmt *CartesianMerkleTree
+}
+
+func (a cmtAdapter) AddKV(key, value []byte) error {
+	inserted, err := a.cmt.AddKV(key, value)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		return fmt.Errorf("key %x already present", key)
+	}
+	return nil
+}
+
+func (a cmtAdapter) Root() []byte {
+	return a.cmt.GetRoot()
+}
+
+func (a cmtAdapter) Prove(key []byte) (TreeProof, error) {
+	return a.cmt.GenerateInclusionProof(key)
+}
+
+func (a cmtAdapter) Verify(root, key, value []byte, proof TreeProof) bool {
+	p, ok := proof.(*Proof)
+	if !ok || p == nil || a.cmt == nil {
+		return false
+	}
+	// value stands in for the proof's own, hashed the way the tree commits it
+	if value == nil {
+		value = []byte{}
+	}
+	if a.cmt.ValueHashFunc != nil {
+		value = a.cmt.ValueHashFunc(value)
+	}
+	q := *ReorderSiblings(p, a.cmt.SiblingOrder, TopDown)
+	q.Value = value
+	return VerifyProofAgainstRoot(key, &q, root, a.cmt.hash3)
+}
+
+// AsMerkleTree returns the tree behind the MerkleTree interface.
+// Keys and values are read as big-endian integers (BigIntFromKey) and must
+// fit in the iden3 field.
+func (smt *SimpleMerkleTree) AsMerkleTree() MerkleTree {
+	return smtAdapter{smt}
+}
+
+type smtAdapter struct {
+	smt *SimpleMerkleTree
+}
+
+func (a smtAdapter) AddKV(key, value []byte) error {
+	return a.smt.Add(context.Background(), BigIntFromKey(key), BigIntFromKey(value))
+}
+
+func (a smtAdapter) Root() []byte {
+	return a.smt.MerkleTree.Root().BigInt().FillBytes(make([]byte, 32))
+}
+
+func (a smtAdapter) Prove(key []byte) (TreeProof, error) {
+	proof, err := a.smt.GenerateProof(context.Background(), BigIntFromKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if !proof.Existence {
+		return nil, fmt.Errorf("%w: %x", ErrKeyNotFound, key)
+	}
+	return proof, nil
+}
+
+func (a smtAdapter) Verify(root, key, value []byte, proof TreeProof) bool {
+	p, ok := proof.(*merkletree.Proof)
+	if !ok || p == nil {
+		return false
+	}
+	rootHash, err := merkletree.NewHashFromBigInt(new(big.Int).SetBytes(root))
+	if err != nil {
+		return false
+	}
+	return a.smt.VerifyProof(rootHash, p, BigIntFromKey(key), BigIntFromKey(value))
+}
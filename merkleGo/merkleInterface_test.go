@@ -0,0 +1,56 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestMerkleTreeBackends(t *testing.T) {
+	smt, err := NewSimpleMerkleTree(40, func(data []byte) []byte {
+		hash := sha256.Sum256(data)
+		return hash[:]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends := map[string]MerkleTree{
+		"cmt": NewCartesianMerkleTree().AsMerkleTree(),
+		"smt": smt.AsMerkleTree(),
+	}
+	for name, tree := range backends {
+		t.Run(name, func(t *testing.T) {
+			for i := int64(1); i <= 20; i++ {
+				if err := tree.AddKV(uintKey(i), uintKey(100+i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tree.AddKV(uintKey(3), uintKey(7)); err == nil {
+				t.Fatal("duplicate key accepted")
+			}
+			root := tree.Root()
+			if len(root) != 32 {
+				t.Fatalf("root of %d bytes", len(root))
+			}
+			proof, err := tree.Prove(uintKey(5))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tree.Verify(root, uintKey(5), uintKey(105), proof) {
+				t.Fatal("proof doesn't verify")
+			}
+			if tree.Verify(root, uintKey(5), uintKey(106), proof) {
+				t.Fatal("proof verifies another value")
+			}
+			if tree.Verify(root, uintKey(6), uintKey(105), proof) {
+				t.Fatal("proof verifies another key")
+			}
+			if _, err := tree.Prove(uintKey(99)); !errors.Is(err, ErrKeyNotFound) {
+				t.Fatalf("absent key: %v", err)
+			}
+			if tree.Verify(root, uintKey(5), uintKey(105), "not a proof") {
+				t.Fatal("foreign proof type verifies")
+			}
+		})
+	}
+}
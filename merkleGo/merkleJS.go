@@ -0,0 +1,68 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/hex"
+)
+
+// MerkleTreeJSNode is one proof element in the merkletreejs layout
+type MerkleTreeJSNode struct {
+	Position string `json:"position"` // "left" or "right"
+	Data     string `json:"data"`     // hex, no 0x prefix
+}
+
+// ToMerkleTreeJS converts an inclusion proof (TopDown, default hasher, with or
+// without Sizes) into merkletreejs's {position, data} array, leaf to root.
+//
+// merkletreejs's own verify can't check it: it hashes two inputs per step,
+// while a CMT node hashes three, sha256(entry || lower child || higher child).
+// The array therefore starts with the proven node's left child hash, followed
+// by two elements per node: its entry (position "left", it always comes first)
+// and the other child hash, whose position says on which side of the running
+// hash it goes. The JS side folds it as:
+//
+//	let h = Buffer.from(proof[0].data, 'hex')
+//	for (let i = 1; i < proof.length; i += 2) {
+//	  const entry = Buffer.from(proof[i].data, 'hex')
+//	  const sib = Buffer.from(proof[i + 1].data, 'hex')
+//	  h = sha256(Buffer.concat(proof[i + 1].position === 'left' ? [entry, sib, h] : [entry, h, sib]))
+//	}
+//	// h equals the root
+//
+// For CommitSize proofs the entries already include the size suffix.
+// Non-inclusion and malformed proofs yield nil.
+func (p *Proof) ToMerkleTreeJS() []MerkleTreeJSNode {
+	n := len(p.Siblings)
+	if !p.Existence || n < 2 || n%2 != 0 {
+		return nil
+	}
+	sized := len(p.Sizes) > 0
+	if sized && len(p.Sizes) != n/2 {
+		return nil
+	}
+	entryAt := func(entry []byte, level int) []byte {
+		if sized {
+			return sizedEntry(entry, p.Sizes[level])
+		}
+		return entry
+	}
+
+	h := p.Siblings[n-2]
+	out := []MerkleTreeJSNode{{Position: "left", Data: hex.EncodeToString(h)}}
+	step := func(entry, sib []byte) {
+		position := "right"
+		if bytes.Compare(sib, h) < 0 {
+			position = "left"
+		}
+		out = append(out,
+			MerkleTreeJSNode{Position: "left", Data: hex.EncodeToString(entry)},
+			MerkleTreeJSNode{Position: position, Data: hex.EncodeToString(sib)},
+		)
+		h = default3ArgHash(entry, h, sib)
+	}
+	step(entryAt(nodeEntry(p.Key, p.Value), n/2-1), p.Siblings[n-1])
+	for idx := n - 4; idx >= 0; idx -= 2 {
+		step(entryAt(p.Siblings[idx], idx/2), p.Siblings[idx+1])
+	}
+	return out
+}
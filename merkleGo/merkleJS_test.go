@@ -0,0 +1,59 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestToMerkleTreeJS(t *testing.T) {
+	// 2 at the root, 1 and 3 as its leaves
+	cmt := NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte {
+		if bytes.Equal(key, uintKey(2)) {
+			return []byte{2}
+		}
+		return []byte{1}
+	}
+	fillTree(t, cmt, [][]byte{uintKey(1), uintKey(2), uintKey(3)})
+	zero := make([]byte, 32)
+	leaf1 := default3ArgHash(uintKey(1), zero, zero)
+	leaf3 := default3ArgHash(uintKey(3), zero, zero)
+	side := "right"
+	if bytes.Compare(leaf3, leaf1) < 0 {
+		side = "left"
+	}
+	want := []MerkleTreeJSNode{
+		{"left", hex.EncodeToString(zero)},       // 1's left child
+		{"left", hex.EncodeToString(uintKey(1))}, // 1's entry
+		{"right", hex.EncodeToString(zero)},      // 1's right child, not below the zero hash
+		{"left", hex.EncodeToString(uintKey(2))}, // the root's entry
+		{side, hex.EncodeToString(leaf3)},        // the root's other child
+	}
+	proof, _ := cmt.GenerateProof(uintKey(1))
+	got := proof.ToMerkleTreeJS()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("layout %v, want %v", got, want)
+	}
+
+	// fold it the way the documented JS snippet does
+	h, _ := hex.DecodeString(got[0].Data)
+	for i := 1; i < len(got); i += 2 {
+		entry, _ := hex.DecodeString(got[i].Data)
+		sib, _ := hex.DecodeString(got[i+1].Data)
+		if got[i+1].Position == "left" {
+			h = default3ArgHash(entry, sib, h)
+		} else {
+			h = default3ArgHash(entry, h, sib)
+		}
+	}
+	if !bytes.Equal(h, cmt.GetRoot()) {
+		t.Fatalf("folds to %x, root %x", h, cmt.GetRoot())
+	}
+
+	absent, _ := cmt.GenerateProof(uintKey(4))
+	if absent.ToMerkleTreeJS() != nil {
+		t.Fatal("exclusion proof converted")
+	}
+}
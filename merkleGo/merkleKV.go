@@ -0,0 +1,64 @@
+package merkleGo
+
+import (
+	"sync"
+)
+
+// KVStore adapts a CartesianMerkleTree to a plain Get/Set/Delete store whose
+// Commit is the tree's root, so code written against a key/value store gets a
+// verifiable commitment (and proofs, through Tree) for free. It is safe for
+// concurrent use; Set and Delete are atomic as long as the tree is only
+// written through the store.
+type KVStore struct {
+	mu   sync.Mutex // serializes Set and Delete, which take several tree calls
+	tree *CartesianMerkleTree
+}
+
+// NewKVStore wraps tree, or a new default tree when tree is nil
+func NewKVStore(tree *CartesianMerkleTree) *KVStore {
+	if tree == nil {
+		tree = NewCartesianMerkleTree()
+	}
+	return &KVStore{tree: tree}
+}
+
+// Tree returns the underlying tree, e.g. to generate proofs against Commit
+func (s *KVStore) Tree() *CartesianMerkleTree {
+	return s.tree
+}
+
+// Get returns the value stored under key
+func (s *KVStore) Get(key []byte) ([]byte, bool) {
+	return s.tree.Get(key)
+}
+
+// Set stores value under key, inserting it or replacing its value. A nil value
+// is stored as an empty one, as AddKV does.
+func (s *KVStore) Set(key, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inserted, err := s.tree.AddKV(key, value)
+	if err != nil || inserted {
+		return err
+	}
+	return s.tree.Update(key, value)
+}
+
+// Delete removes key; deleting an absent key is not an error
+func (s *KVStore) Delete(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.tree.Contains(key) {
+		return nil
+	}
+	return s.tree.Remove(key)
+}
+
+// Commit returns the commitment to the store's contents: the tree's root,
+// nil while the store is empty
+func (s *KVStore) Commit() []byte {
+	return s.tree.GetRoot()
+}
@@ -0,0 +1,57 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKVStore(t *testing.T) {
+	s := NewKVStore(nil)
+	if s.Commit() != nil {
+		t.Fatal("empty store has a commitment")
+	}
+	key := []byte("account")
+	if err := s.Set(key, []byte("100")); err != nil {
+		t.Fatal(err)
+	}
+	first := s.Commit()
+	if first == nil || !bytes.Equal(first, s.Tree().GetRoot()) {
+		t.Fatal("Commit doesn't track the root")
+	}
+	if value, ok := s.Get(key); !ok || string(value) != "100" {
+		t.Fatalf("Get = %q, %v", value, ok)
+	}
+
+	if err := s.Set(key, []byte("90")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := s.Get(key); string(value) != "90" || bytes.Equal(s.Commit(), first) || !bytes.Equal(s.Commit(), s.Tree().GetRoot()) {
+		t.Fatalf("overwrite: value %q, commit %x", value, s.Commit())
+	}
+	if s.Tree().Size() != 1 {
+		t.Fatalf("overwrite left %d keys", s.Tree().Size())
+	}
+	proof, err := s.Tree().GenerateInclusionProof(key)
+	if err != nil || !VerifyProofAgainstRoot(key, proof, s.Commit(), nil) || string(proof.Value) != "90" {
+		t.Fatalf("proof against Commit: %v", err)
+	}
+
+	if err := s.Set(key, []byte("100")); err != nil || !bytes.Equal(s.Commit(), first) {
+		t.Fatalf("restoring the value doesn't restore the commitment: %v", err)
+	}
+	if err := s.Set([]byte("empty"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := s.Get([]byte("empty")); !ok || value == nil || len(value) != 0 {
+		t.Fatalf("nil value stored as %v, %v", value, ok)
+	}
+	if err := s.Delete([]byte("empty")); err != nil || !bytes.Equal(s.Commit(), first) {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete([]byte("absent")); err != nil {
+		t.Fatalf("deleting an absent key: %v", err)
+	}
+	if err := s.Delete(key); err != nil || s.Commit() != nil {
+		t.Fatalf("emptied store: %v, commit %x", err, s.Commit())
+	}
+}
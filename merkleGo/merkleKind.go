@@ -0,0 +1,61 @@
+package merkleGo
+
+import (
+	"bytes"
+)
+
+// ProofKind classifies a proof by its shape, see Proof.Kind
+type ProofKind int
+
+const (
+	// Malformed proofs can't verify whatever the root
+	Malformed ProofKind = iota
+	Inclusion
+	Exclusion
+)
+
+func (k ProofKind) String() string {
+	switch k {
+	case Inclusion:
+		return "inclusion"
+	case Exclusion:
+		return "exclusion"
+	default:
+		return "malformed"
+	}
+}
+
+// Kind tells inclusion from exclusion proofs from the Existence flag and the
+// proof's structure, without hashing anything: a cheap pre-check, not a
+// verification. Siblings must come in pairs (with one size per pair when Sizes
+// is set), an inclusion proof must name its key and end with the proven node's
+// children, and an exclusion proof must carry a NonExistenceKey other than Key
+// and no value. An exclusion proof without siblings (empty tree) needs no
+// NonExistenceKey.
+func (p *Proof) Kind() ProofKind {
+	if p == nil || len(p.Key) == 0 || len(p.Siblings)%2 != 0 {
+		return Malformed
+	}
+	if len(p.Sizes) > 0 && len(p.Sizes) != len(p.Siblings)/2 {
+		return Malformed
+	}
+	if p.Existence {
+		if len(p.Siblings) == 0 || p.NonExistenceKey != nil {
+			return Malformed
+		}
+		return Inclusion
+	}
+	if p.Value != nil {
+		return Malformed
+	}
+	if len(p.Siblings) == 0 {
+		if p.NonExistenceKey != nil {
+			return Malformed
+		}
+		return Exclusion
+	}
+	if len(p.NonExistenceKey) == 0 || bytes.Equal(p.NonExistenceKey, p.Key) {
+		return Malformed
+	}
+	return Exclusion
+}
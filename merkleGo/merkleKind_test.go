@@ -0,0 +1,132 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestProofKind(t *testing.T) {
+	cmt := buildTree(t, strKeys(20))
+	in, _ := cmt.GenerateProof([]byte("key-3"))
+	out, _ := cmt.GenerateProof([]byte("absent"))
+	empty, _ := NewCartesianMerkleTree().GenerateProof([]byte("absent"))
+
+	cases := []struct {
+		name  string
+		proof *Proof
+		want  ProofKind
+	}{
+		{"inclusion", in, Inclusion},
+		{"exclusion", out, Exclusion},
+		{"empty tree", empty, Exclusion},
+		{"nil", nil, Malformed},
+		{"odd siblings", &Proof{Existence: true, Key: []byte("k"), Siblings: [][]byte{{1}}}, Malformed},
+		{"inclusion with non-existence key", &Proof{Existence: true, Key: []byte("k"), Siblings: in.Siblings, NonExistenceKey: []byte("x")}, Malformed},
+		{"exclusion without non-existence key", &Proof{Key: []byte("k"), Siblings: out.Siblings}, Malformed},
+		{"exclusion with value", &Proof{Key: []byte("k"), Value: []byte{}, Siblings: out.Siblings, NonExistenceKey: []byte("x")}, Malformed},
+	}
+	for _, c := range cases {
+		if got := c.proof.Kind(); got != c.want {
+			t.Errorf("%s: Kind() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// An exclusion proof is laid out like the contract's _proof: (key, other child)
+// per ancestor, then the last node's (left, right) children, so it folds to the
+// root from NonExistenceKey exactly like an inclusion proof of that key would.
+func TestExclusionProofMatchesContractLayout(t *testing.T) {
+	cmt := NewCartesianMerkleTreeSolidityV1()
+	for i := int64(1); i <= 64; i++ {
+		fillTree(t, cmt, [][]byte{uintKey(i * 10)})
+	}
+	keccak := FactoryHasher(sha3.NewLegacyKeccak256, nil)
+	for _, absent := range []int64{5, 15, 333, 641, 9999} {
+		key := uintKey(absent)
+		proof, err := cmt.GenerateProof(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Kind() != Exclusion {
+			t.Fatalf("%d: kind %v", absent, proof.Kind())
+		}
+		last, _ := cmt.ProofLength(key)
+		if len(proof.Siblings) != last {
+			t.Fatalf("%d: %d siblings, ProofLength says %d", absent, len(proof.Siblings), last)
+		}
+
+		// the last pair is the dead end's children, one of them the empty slot
+		n := len(proof.Siblings)
+		left, right := proof.Siblings[n-2], proof.Siblings[n-1]
+		wantEmpty := left
+		if bytes.Compare(key, proof.NonExistenceKey) > 0 {
+			wantEmpty = right
+		}
+		if !bytes.Equal(wantEmpty, make([]byte, 32)) {
+			t.Fatalf("%d: the child on the key's side of %x is not empty", absent, proof.NonExistenceKey)
+		}
+		terminal, err := cmt.GenerateProof(proof.NonExistenceKey)
+		if err != nil || !terminal.Existence {
+			t.Fatalf("%d: no inclusion proof of the non-existence key: %v", absent, err)
+		}
+		if len(terminal.Siblings) != n {
+			t.Fatalf("%d: exclusion and terminal inclusion proofs differ in length", absent)
+		}
+		for i := range terminal.Siblings {
+			if !bytes.Equal(terminal.Siblings[i], proof.Siblings[i]) {
+				t.Fatalf("%d: sibling %d differs from the terminal node's proof", absent, i)
+			}
+		}
+		if !VerifyProofAgainstRoot(proof.NonExistenceKey, terminal, cmt.GetRoot(), keccak) {
+			t.Fatalf("%d: siblings don't fold to the root from the non-existence key", absent)
+		}
+		if !cmt.VerifyNonMembership(key, proof) {
+			t.Fatalf("%d: VerifyNonMembership rejected the proof", absent)
+		}
+	}
+}
+
+func TestExclusionProofShapeAndEncodings(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.ProvePriorities = true
+	fillTree(t, cmt, strKeys(100))
+	proof, err := cmt.GenerateProof([]byte("key-50a"))
+	if err != nil || proof.Existence {
+		t.Fatalf("want an exclusion proof, got %+v, %v", proof, err)
+	}
+	if err := cmt.VerifyProofShape(proof); err != nil {
+		t.Fatalf("VerifyProofShape: %v", err)
+	}
+	if got := MinimizeProof(proof).Expand(); !proofsEqual(got, proof) {
+		t.Fatal("MinimizeProof round trip changed the exclusion proof")
+	}
+	compressed := CompressProof(proof)
+	if got, err := compressed.Expand(); err != nil || !proofsEqual(got, proof) {
+		t.Fatalf("CompressProof round trip changed the exclusion proof: %v", err)
+	}
+	bottomUp := ReorderSiblings(proof, TopDown, BottomUp)
+	if !proofsEqual(ReorderSiblings(bottomUp, BottomUp, TopDown), proof) {
+		t.Fatal("ReorderSiblings round trip changed the exclusion proof")
+	}
+}
+
+// proofsEqual compares the fields that commit to the root
+func proofsEqual(a, b *Proof) bool {
+	if a.Existence != b.Existence || !bytes.Equal(a.Key, b.Key) || !bytes.Equal(a.Value, b.Value) ||
+		!bytes.Equal(a.NonExistenceKey, b.NonExistenceKey) || len(a.Siblings) != len(b.Siblings) || len(a.Sizes) != len(b.Sizes) {
+		return false
+	}
+	for i := range a.Siblings {
+		if !bytes.Equal(a.Siblings[i], b.Siblings[i]) {
+			return false
+		}
+	}
+	for i := range a.Sizes {
+		if a.Sizes[i] != b.Sizes[i] {
+			return false
+		}
+	}
+	return true
+}
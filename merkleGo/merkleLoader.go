@@ -0,0 +1,53 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrValueMismatch is returned by LoadValue when the value the ValueLoader
+// returns doesn't hash to the one committed in the tree
+var ErrValueMismatch = errors.New("loaded value does not match the committed hash")
+
+// LoadValue reads the value of key through ValueLoader: committed is the hash
+// stored in the node, raw what the loader returned for the node's key, checked
+// to hash (sha256, or ValueHashFunc when set) to committed, ErrValueMismatch
+// otherwise. The loader is called with the tree unlocked. A key-only node has
+// nothing to load and yields nil, nil. Without a ValueLoader raw is the stored
+// value itself.
+func (cmt *CartesianMerkleTree) LoadValue(key []byte) (raw, committed []byte, err error) {
+	if cmt == nil {
+		return nil, nil, ErrNilTree
+	}
+	cmt.mu.RLock()
+	node := cmt.find(key)
+	var nodeKey []byte
+	if node != nil {
+		nodeKey, committed = node.Key, node.Value
+	}
+	cmt.mu.RUnlock()
+	if node == nil {
+		return nil, nil, fmt.Errorf("%w: %x", ErrKeyNotFound, key)
+	}
+	if cmt.ValueLoader == nil || committed == nil {
+		return committed, committed, nil
+	}
+
+	raw, err = cmt.ValueLoader(nodeKey)
+	if err != nil {
+		return nil, committed, fmt.Errorf("loading value of key %x: %w", nodeKey, err)
+	}
+	var sum []byte
+	if cmt.ValueHashFunc != nil {
+		sum = cmt.ValueHashFunc(raw)
+	} else {
+		h := sha256.Sum256(raw)
+		sum = h[:]
+	}
+	if !bytes.Equal(sum, committed) {
+		return nil, committed, fmt.Errorf("%w: key %x", ErrValueMismatch, nodeKey)
+	}
+	return raw, committed, nil
+}
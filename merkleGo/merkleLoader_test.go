@@ -0,0 +1,61 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValueLoader(t *testing.T) {
+	store := make(map[string][]byte)
+	errUnavailable := errors.New("store unavailable")
+	cmt := NewCartesianMerkleTree()
+	cmt.ValueLoader = func(key []byte) ([]byte, error) {
+		if string(key) == "broken" {
+			return nil, errUnavailable
+		}
+		return store[string(key)], nil
+	}
+	for i := 0; i < 10; i++ {
+		key, value := fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i))
+		store[key] = value
+		sum := sha256.Sum256(value)
+		if _, err := cmt.AddKV([]byte(key), sum[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum := sha256.Sum256([]byte("anything"))
+	if _, err := cmt.AddKV([]byte("broken"), sum[:]); err != nil {
+		t.Fatal(err)
+	}
+	fillTree(t, cmt, [][]byte{[]byte("key-only")})
+
+	if value, ok := cmt.Get([]byte("key-3")); !ok || string(value) != "value-3" {
+		t.Fatalf("Get = %q, %v", value, ok)
+	}
+	raw, committed, err := cmt.LoadValue([]byte("key-3"))
+	if want := sha256.Sum256([]byte("value-3")); err != nil || string(raw) != "value-3" || !bytes.Equal(committed, want[:]) {
+		t.Fatalf("LoadValue = %q, %x, %v", raw, committed, err)
+	}
+
+	// the store hands back something else than what the tree committed to
+	store["key-3"] = []byte("corrupted")
+	if _, _, err := cmt.LoadValue([]byte("key-3")); !errors.Is(err, ErrValueMismatch) {
+		t.Fatalf("corrupted value: %v", err)
+	}
+	if value, ok := cmt.Get([]byte("key-3")); ok || value != nil {
+		t.Fatalf("Get of a corrupted value = %q, %v", value, ok)
+	}
+
+	if _, _, err := cmt.LoadValue([]byte("broken")); !errors.Is(err, errUnavailable) {
+		t.Fatalf("loader error: %v", err)
+	}
+	if raw, _, err := cmt.LoadValue([]byte("key-only")); raw != nil || err != nil {
+		t.Fatalf("key-only node: %q, %v", raw, err)
+	}
+	if _, _, err := cmt.LoadValue([]byte("absent")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("absent key: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+)
+
+// TreeKey returns the key the tree stores for key: sha256(key) when key is
+// longer than MaxKeyLength, key itself otherwise. Every method taking a key
+// maps it this way, but what the tree holds, commits to and proves is the tree
+// key: Keys, proofs (Proof.Key) and the stateless verifiers all use it, so a
+// verifier of a long key's proof needs TreeKey(key), not key.
+// Use OriginalKey to get the long key back.
+func (cmt *CartesianMerkleTree) TreeKey(key []byte) []byte {
+	return cmt.treeKey(key)
+}
+
+// OriginalKey returns the key that was inserted as treeKey: the long key for a
+// hashed one, treeKey itself otherwise. false if treeKey is not in the tree.
+// The returned slice must not be modified.
+func (cmt *CartesianMerkleTree) OriginalKey(treeKey []byte) ([]byte, bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	node := cmt.find(treeKey)
+	if node == nil {
+		return nil, false
+	}
+	return cmt.originalKey(node.Key), true
+}
+
+func (cmt *CartesianMerkleTree) treeKey(key []byte) []byte {
+	if cmt == nil || cmt.MaxKeyLength <= 0 {
+		return key
+	}
+	// hashed keys are 32 bytes, they must never be hashed again
+	limit := cmt.MaxKeyLength
+	if limit < sha256.Size {
+		limit = sha256.Size
+	}
+	if len(key) <= limit {
+		return key
+	}
+	sum := sha256.Sum256(key)
+	return sum[:]
+}
+
+func (cmt *CartesianMerkleTree) originalKey(treeKey []byte) []byte {
+	if original, ok := cmt.longKeys[string(treeKey)]; ok {
+		return original
+	}
+	return treeKey
+}
+
+// rememberKey records original when it was inserted as a different treeKey
+func (cmt *CartesianMerkleTree) rememberKey(original, treeKey []byte) {
+	if len(original) == len(treeKey) {
+		// treeKey shortens any key it changes
+		return
+	}
+	if cmt.longKeys == nil {
+		cmt.longKeys = make(map[string][]byte)
+	}
+	cmt.longKeys[string(treeKey)] = cloneBytes(original)
+}
+
+func (cmt *CartesianMerkleTree) forgetKey(treeKey []byte) {
+	delete(cmt.longKeys, string(treeKey))
+}
@@ -0,0 +1,62 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestLongKeys(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.MaxKeyLength = 64
+	short := []byte("short")
+	long := make([][]byte, 20)
+	for i := range long {
+		long[i] = bytes.Repeat([]byte{byte('a' + i)}, 4096)
+	}
+	fillTree(t, cmt, append(long, short))
+	mustValidate(t, cmt)
+
+	// no node holds more than a hash of a long key
+	var widest int
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		if len(node.Key) > widest {
+			widest = len(node.Key)
+		}
+		return true
+	})
+	if widest > cmt.MaxKeyLength {
+		t.Fatalf("a node key is %d bytes", widest)
+	}
+
+	for _, key := range long {
+		treeKey := cmt.TreeKey(key)
+		if sum := sha256.Sum256(key); !bytes.Equal(treeKey, sum[:]) {
+			t.Fatalf("TreeKey = %x, want the key's sha256", treeKey)
+		}
+		if !cmt.Contains(key) || !cmt.Contains(treeKey) {
+			t.Fatal("long key not found")
+		}
+		if original, ok := cmt.OriginalKey(treeKey); !ok || !bytes.Equal(original, key) {
+			t.Fatal("OriginalKey doesn't return the long key")
+		}
+		// the proof attests to the key hash
+		proof, err := cmt.GenerateProof(key)
+		if err != nil || !proof.Existence || !bytes.Equal(proof.Key, treeKey) {
+			t.Fatalf("GenerateProof: %v", err)
+		}
+		if !VerifyProofAgainstRoot(treeKey, proof, cmt.GetRoot(), nil) {
+			t.Fatal("proof doesn't verify for the tree key")
+		}
+	}
+	if !bytes.Equal(cmt.TreeKey(short), short) {
+		t.Fatal("short key hashed")
+	}
+
+	if err := cmt.Remove(long[0]); err != nil || cmt.Contains(long[0]) {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, ok := cmt.OriginalKey(cmt.TreeKey(long[0])); ok {
+		t.Fatal("removed long key still known")
+	}
+}
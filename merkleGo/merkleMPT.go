@@ -0,0 +1,301 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// MPT is an in-memory Ethereum Merkle Patricia Trie (the yellow paper's
+// modified trie: hex-prefix paths, RLP nodes, keccak256, nodes under 32 bytes
+// embedded in their parent), so its roots and proofs are those Ethereum
+// tooling computes for the same keys and values. Keys are used as given, not
+// hashed as in Ethereum's secure tries. It supports inserts and lookups only.
+type MPT struct {
+	root mptNode
+	size int
+}
+
+type mptNode interface{}
+
+type mptLeaf struct {
+	path  []byte // remaining nibbles
+	value []byte
+}
+
+type mptExtension struct {
+	path  []byte
+	child mptNode
+}
+
+type mptBranch struct {
+	children [16]mptNode
+	value    []byte
+}
+
+// NewMPT returns an empty trie
+func NewMPT() *MPT {
+	return &MPT{}
+}
+
+// ToMPT re-expresses the tree's data as an MPT, for Ethereum interop: every
+// key maps to the RLP encoding of its value (as Ethereum storage tries store
+// values), which keeps empty values representable. Key-only nodes map to the
+// RLP empty string, like empty values. Keys hashed by MaxKeyLength are written
+// as originally inserted. The MPT root is an unrelated commitment over the same
+// data: it never equals the CMT root, but it is reproducible from the data
+// alone, whatever the order of insertion.
+func (cmt *CartesianMerkleTree) ToMPT() (*MPT, error) {
+	mpt := NewMPT()
+	if cmt == nil {
+		return mpt, nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	var err error
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		err = mpt.Insert(cmt.originalKey(node.Key), rlpString(node.Value))
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mpt, nil
+}
+
+// Insert sets key to value, replacing any previous value. Empty values mean
+// deletion in Ethereum tries and are rejected.
+func (t *MPT) Insert(key, value []byte) error {
+	if len(value) == 0 {
+		return errors.New("mpt values cannot be empty")
+	}
+	var inserted bool
+	t.root, inserted = mptInsert(t.root, keyNibbles(key), cloneBytes(value))
+	if inserted {
+		t.size++
+	}
+	return nil
+}
+
+// Get returns the value stored under key
+func (t *MPT) Get(key []byte) ([]byte, bool) {
+	path := keyNibbles(key)
+	node := t.root
+	for {
+		switch n := node.(type) {
+		case nil:
+			return nil, false
+		case *mptLeaf:
+			if !bytes.Equal(n.path, path) {
+				return nil, false
+			}
+			return n.value, true
+		case *mptExtension:
+			if !bytes.HasPrefix(path, n.path) {
+				return nil, false
+			}
+			path, node = path[len(n.path):], n.child
+		case *mptBranch:
+			if len(path) == 0 {
+				return n.value, n.value != nil
+			}
+			path, node = path[1:], n.children[path[0]]
+		}
+	}
+}
+
+// Len returns the number of keys in the trie
+func (t *MPT) Len() int {
+	return t.size
+}
+
+// Root returns keccak256 of the root node's RLP encoding; for an empty trie
+// that is keccak256(RLP("")), Ethereum's empty trie root
+func (t *MPT) Root() []byte {
+	return keccak256(mptEncode(t.root))
+}
+
+// Prove returns the RLP encodings of the nodes on key's path, root first, in
+// the form of eth_getProof's proofs. Nodes embedded in their parent (under 32
+// bytes) are not listed separately. Absent keys get the path to where they
+// would be, a proof of absence.
+func (t *MPT) Prove(key []byte) [][]byte {
+	path := keyNibbles(key)
+	var proof [][]byte
+	node := t.root
+	for i := 0; node != nil; i++ {
+		if enc := mptEncode(node); i == 0 || len(enc) >= 32 {
+			proof = append(proof, enc)
+		}
+		switch n := node.(type) {
+		case *mptLeaf:
+			return proof
+		case *mptExtension:
+			if !bytes.HasPrefix(path, n.path) {
+				return proof
+			}
+			path, node = path[len(n.path):], n.child
+		case *mptBranch:
+			if len(path) == 0 {
+				return proof
+			}
+			path, node = path[1:], n.children[path[0]]
+		}
+	}
+	return proof
+}
+
+func mptInsert(node mptNode, path, value []byte) (mptNode, bool) {
+	switch n := node.(type) {
+	case nil:
+		return &mptLeaf{path: path, value: value}, true
+	case *mptLeaf:
+		c := commonPrefixLength(n.path, path)
+		if c == len(n.path) && c == len(path) {
+			n.value = value
+			return n, false
+		}
+		branch := &mptBranch{}
+		branch.put(n.path[c:], n.value)
+		branch.put(path[c:], value)
+		return wrapExtension(path[:c], branch), true
+	case *mptExtension:
+		c := commonPrefixLength(n.path, path)
+		if c == len(n.path) {
+			var inserted bool
+			n.child, inserted = mptInsert(n.child, path[c:], value)
+			return n, inserted
+		}
+		branch := &mptBranch{}
+		if rest := n.path[c+1:]; len(rest) == 0 {
+			branch.children[n.path[c]] = n.child
+		} else {
+			branch.children[n.path[c]] = &mptExtension{path: rest, child: n.child}
+		}
+		branch.put(path[c:], value)
+		return wrapExtension(path[:c], branch), true
+	case *mptBranch:
+		if len(path) == 0 {
+			inserted := n.value == nil
+			n.value = value
+			return n, inserted
+		}
+		var inserted bool
+		n.children[path[0]], inserted = mptInsert(n.children[path[0]], path[1:], value)
+		return n, inserted
+	}
+	return node, false
+}
+
+// put hangs value below the branch at path: in the branch itself when the
+// path is exhausted, as a leaf under the first nibble otherwise
+func (b *mptBranch) put(path, value []byte) {
+	if len(path) == 0 {
+		b.value = value
+		return
+	}
+	b.children[path[0]] = &mptLeaf{path: path[1:], value: value}
+}
+
+func wrapExtension(path []byte, child mptNode) mptNode {
+	if len(path) == 0 {
+		return child
+	}
+	return &mptExtension{path: append([]byte{}, path...), child: child}
+}
+
+// mptEncode returns node's RLP encoding
+func mptEncode(node mptNode) []byte {
+	switch n := node.(type) {
+	case *mptLeaf:
+		return rlpList(rlpString(hexPrefix(n.path, true)), rlpString(n.value))
+	case *mptExtension:
+		return rlpList(rlpString(hexPrefix(n.path, false)), mptReference(n.child))
+	case *mptBranch:
+		items := make([][]byte, 17)
+		for i, child := range n.children {
+			items[i] = mptReference(child)
+		}
+		items[16] = rlpString(n.value)
+		return rlpList(items...)
+	}
+	return rlpString(nil)
+}
+
+// mptReference is how a parent refers to node: its encoding inline when under
+// 32 bytes, the keccak256 of it otherwise
+func mptReference(node mptNode) []byte {
+	if node == nil {
+		return rlpString(nil)
+	}
+	enc := mptEncode(node)
+	if len(enc) < 32 {
+		return enc
+	}
+	return rlpString(keccak256(enc))
+}
+
+// keyNibbles splits key into its 4-bit nibbles, high first
+func keyNibbles(key []byte) []byte {
+	nibbles := make([]byte, 2*len(key))
+	for i, b := range key {
+		nibbles[2*i], nibbles[2*i+1] = b>>4, b&0x0f
+	}
+	return nibbles
+}
+
+// hexPrefix packs nibbles into bytes behind the flag nibble telling leaves from
+// extensions and odd from even lengths
+func hexPrefix(nibbles []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+	odd := len(nibbles) % 2
+	out := make([]byte, len(nibbles)/2+1)
+	if odd == 1 {
+		out[0] = (flag+1)<<4 | nibbles[0]
+	} else {
+		out[0] = flag << 4
+	}
+	for i := odd; i < len(nibbles); i += 2 {
+		out[1+(i-odd)/2] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return out
+}
+
+func rlpString(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(rlpLength(len(b), 0x80), b...)
+}
+
+func rlpList(items ...[]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+	return append(rlpLength(len(payload), 0xc0), payload...)
+}
+
+// rlpLength is the RLP header of a payload of n bytes, offset 0x80 for strings
+// and 0xc0 for lists
+func rlpLength(n int, offset byte) []byte {
+	if n < 56 {
+		return []byte{offset + byte(n)}
+	}
+	var be []byte
+	for v := n; v > 0; v >>= 8 {
+		be = append([]byte{byte(v)}, be...)
+	}
+	return append([]byte{offset + 55 + byte(len(be))}, be...)
+}
+
+func keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,73 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// Roots from go-ethereum's trie tests
+func TestMPTMatchesEthereum(t *testing.T) {
+	mpt := NewMPT()
+	if got := hex.EncodeToString(mpt.Root()); got != "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421" {
+		t.Fatalf("empty trie root %s", got)
+	}
+	for _, kv := range [][2]string{{"doe", "reindeer"}, {"dog", "puppy"}, {"dogglesworth", "cat"}} {
+		if err := mpt.Insert([]byte(kv[0]), []byte(kv[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hex.EncodeToString(mpt.Root()); got != "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3" {
+		t.Fatalf("root %s", got)
+	}
+	if err := mpt.Insert([]byte("doe"), nil); err == nil {
+		t.Fatal("empty value accepted")
+	}
+}
+
+func TestToMPT(t *testing.T) {
+	build := func(order []int) *CartesianMerkleTree {
+		cmt := NewCartesianMerkleTree()
+		for _, i := range order {
+			key := []byte(fmt.Sprintf("key-%d", i))
+			var err error
+			if i%3 == 0 {
+				_, err = cmt.Add(key)
+			} else {
+				_, err = cmt.AddKV(key, []byte(fmt.Sprintf("value-%d", i)))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return cmt
+	}
+	forward, backward := make([]int, 100), make([]int, 100)
+	for i := range forward {
+		forward[i], backward[i] = i, 99-i
+	}
+	cmt := build(forward)
+	mpt, err := cmt.ToMPT()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mpt.Len() != cmt.Size() {
+		t.Fatalf("MPT holds %d keys, tree %d", mpt.Len(), cmt.Size())
+	}
+	for _, key := range cmt.Keys() {
+		value, _ := cmt.Get(key)
+		got, ok := mpt.Get(key)
+		if !ok || !bytes.Equal(got, rlpString(value)) {
+			t.Fatalf("%s: MPT has %x, %v, want the RLP of %q", key, got, ok, value)
+		}
+	}
+
+	again, _ := build(backward).ToMPT()
+	if !bytes.Equal(again.Root(), mpt.Root()) {
+		t.Fatal("MPT root depends on the insertion order")
+	}
+	if empty, _ := NewCartesianMerkleTree().ToMPT(); !bytes.Equal(empty.Root(), NewMPT().Root()) {
+		t.Fatal("empty tree doesn't map to the empty trie")
+	}
+}
@@ -0,0 +1,40 @@
+package merkleGo
+
+import "fmt"
+
+// SetMeta attaches value to the node holding key under field. Metadata lives
+// on the node (it follows the key through rotations) but is never hashed, so
+// the root stays what on-chain verifiers expect. It is not exported by
+// MarshalJSON and is dropped when the key is removed.
+func (cmt *CartesianMerkleTree) SetMeta(key []byte, field string, value []byte) error {
+	if cmt == nil {
+		return ErrNilTree
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	node := cmt.find(key)
+	if node == nil {
+		return fmt.Errorf("key %x not found", key)
+	}
+	if node.Meta == nil {
+		node.Meta = make(map[string][]byte)
+	}
+	node.Meta[field] = cloneBytes(value)
+	return nil
+}
+
+// GetMeta returns the metadata stored by SetMeta, false if the key or field is absent.
+// The returned slice must not be modified.
+func (cmt *CartesianMerkleTree) GetMeta(key []byte, field string) ([]byte, bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	node := cmt.find(key)
+	if node == nil {
+		return nil, false
+	}
+	value, ok := node.Meta[field]
+	return value, ok
+}
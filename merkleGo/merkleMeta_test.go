@@ -0,0 +1,43 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMetaFollowsKeys(t *testing.T) {
+	keys := strKeys(100)
+	cmt := buildTree(t, keys[:50])
+	for _, key := range keys[:50] {
+		root := cmt.GetRoot()
+		if err := cmt.SetMeta(key, "owner", key); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(cmt.GetRoot(), root) {
+			t.Fatalf("SetMeta(%s) changed the root", key)
+		}
+	}
+
+	// inserts and removals rotate the annotated nodes around
+	fillTree(t, cmt, keys[50:])
+	for _, key := range keys[:25] {
+		if err := cmt.Remove(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(cmt.GetRoot(), buildTree(t, keys[25:]).GetRoot()) {
+		t.Fatal("metadata changed the root")
+	}
+	for i, key := range keys {
+		value, ok := cmt.GetMeta(key, "owner")
+		if want := i >= 25 && i < 50; ok != want || (ok && !bytes.Equal(value, key)) {
+			t.Fatalf("%s: meta %q, %v", key, value, ok)
+		}
+	}
+	if _, ok := cmt.GetMeta(keys[30], "other"); ok {
+		t.Fatal("unset field reported present")
+	}
+	if err := cmt.SetMeta([]byte("absent"), "owner", nil); err == nil {
+		t.Fatal("SetMeta on an absent key succeeded")
+	}
+}
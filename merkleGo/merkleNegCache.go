@@ -0,0 +1,66 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// negativeCache holds non-membership proofs by sha256(tree key) || root, see
+// NegativeCacheSize. It has its own lock since GenerateProof fills it while
+// only holding the tree's read lock.
+type negativeCache struct {
+	mu     sync.Mutex
+	proofs map[string]*Proof
+}
+
+func negativeCacheKey(key, root []byte) string {
+	h := sha256.Sum256(key)
+	return string(h[:]) + string(root)
+}
+
+// get returns a copy of the cached proof of key (a tree key), nil on a miss
+func (c *negativeCache) get(key, root []byte) *Proof {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.proofs[negativeCacheKey(key, root)]
+	if !ok {
+		return nil
+	}
+	return copyProof(cached)
+}
+
+// put stores a copy of proof, starting over once the cache holds max proofs
+func (c *negativeCache) put(key, root []byte, proof *Proof, max int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.proofs == nil || len(c.proofs) >= max {
+		c.proofs = make(map[string]*Proof)
+	}
+	c.proofs[negativeCacheKey(key, root)] = copyProof(proof)
+}
+
+// copyProof copies p's slices, so neither copy sees changes to the other's, and
+// keeps empty ones empty and nil ones nil, so the copy is exactly p
+func copyProof(p *Proof) *Proof {
+	c := *p
+	if p.Siblings != nil {
+		c.Siblings = make([][]byte, len(p.Siblings))
+		copy(c.Siblings, p.Siblings)
+	}
+	if p.Sizes != nil {
+		c.Sizes = make([]uint64, len(p.Sizes))
+		copy(c.Sizes, p.Sizes)
+	}
+	if p.Path != nil {
+		c.Path = make([]PathNode, len(p.Path))
+		copy(c.Path, p.Path)
+	}
+	return &c
+}
+
+// clear drops every cached proof, called whenever the root changes
+func (c *negativeCache) clear() {
+	c.mu.Lock()
+	c.proofs = nil
+	c.mu.Unlock()
+}
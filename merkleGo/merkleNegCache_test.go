@@ -0,0 +1,95 @@
+package merkleGo
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNegativeCacheInvalidation(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.NegativeCacheSize = 8
+	fillTree(t, cmt, strKeys(50))
+	absent := []byte("absent")
+	first, _ := cmt.GenerateProof(absent)
+	if len(cmt.negCache.proofs) != 1 {
+		t.Fatalf("%d cached proofs after one miss", len(cmt.negCache.proofs))
+	}
+	cached, _ := cmt.GenerateProof(absent)
+	if !proofsEqual(cached, first) || !cmt.VerifyNonMembership(absent, cached) {
+		t.Fatal("cached proof differs")
+	}
+	cached.Siblings[0] = nil // callers own the copy they get
+	if again, _ := cmt.GenerateProof(absent); !proofsEqual(again, first) {
+		t.Fatal("modifying a returned proof changed the cache")
+	}
+
+	// any root change drops the cache, including inserting a neighbour
+	fillTree(t, cmt, [][]byte{[]byte("absenu")})
+	if cmt.negCache.proofs != nil {
+		t.Fatal("cache survived a root change")
+	}
+	after, _ := cmt.GenerateProof(absent)
+	if !cmt.VerifyNonMembership(absent, after) || proofsEqual(after, first) {
+		t.Fatal("proof after the insert is stale")
+	}
+	fillTree(t, cmt, [][]byte{absent})
+	if proof, _ := cmt.GenerateProof(absent); !proof.Existence {
+		t.Fatal("cache hid an inserted key")
+	}
+
+	for i := 0; i < 20; i++ {
+		cmt.GenerateProof([]byte(fmt.Sprintf("missing-%d", i)))
+	}
+	if n := len(cmt.negCache.proofs); n > cmt.NegativeCacheSize {
+		t.Fatalf("cache grew to %d proofs", n)
+	}
+}
+
+func BenchmarkAbsentKeyProof(b *testing.B) {
+	for _, size := range []int{0, 64} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			cmt := NewCartesianMerkleTree()
+			cmt.NegativeCacheSize = size
+			fillTree(b, cmt, strKeys(100000))
+			absent := []byte("absent")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cmt.GenerateProof(absent)
+			}
+		})
+	}
+}
+
+// A cache hit returns exactly the proof a miss builds
+func TestNegativeCacheRoundTrip(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 100)
+	configs := map[string]func(cmt *CartesianMerkleTree){
+		"plain":       func(*CartesianMerkleTree) {},
+		"long keys":   func(cmt *CartesianMerkleTree) { cmt.MaxKeyLength = 40 },
+		"sizes, path": func(cmt *CartesianMerkleTree) { cmt.CommitSize, cmt.ProvePriorities = true, true },
+	}
+	for name, configure := range configs {
+		for _, n := range []int{0, 30} {
+			uncachedTree, cachedTree := NewCartesianMerkleTree(), NewCartesianMerkleTree()
+			configure(uncachedTree)
+			configure(cachedTree)
+			cachedTree.NegativeCacheSize = 8
+			fillTree(t, uncachedTree, strKeys(n))
+			fillTree(t, cachedTree, strKeys(n))
+			for _, key := range [][]byte{[]byte("absent"), long} {
+				want, _ := uncachedTree.GenerateProof(key)
+				miss, _ := cachedTree.GenerateProof(key)
+				hit, _ := cachedTree.GenerateProof(key)
+				if !reflect.DeepEqual(miss, want) || !reflect.DeepEqual(hit, want) {
+					t.Fatalf("%s, %d keys, %.10s: cached %+v, uncached %+v", name, n, key, hit, want)
+				}
+				if !cachedTree.VerifyNonMembership(key, hit) {
+					t.Fatalf("%s, %d keys, %.10s: cached proof doesn't verify", name, n, key)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package merkleGo
+
+// ProofSiblingOrder is the layout of Proof.Siblings (and Proof.Sizes, Proof.Path).
+//
+// CartesianMerkleTree.sol's getProof fills siblings top-down: one
+// (nodeKey, otherChildHash) pair per ancestor from the root, then the proven
+// node's (leftHash, rightHash). TopDown matches that array as is.
+type ProofSiblingOrder int
+
+const (
+	// TopDown lists the pairs from the root to the proven node (the contract layout)
+	TopDown ProofSiblingOrder = iota
+	// BottomUp lists the same pairs from the proven node up to the root.
+	// Each pair keeps its internal order, only the pairs are reversed.
+	BottomUp
+)
+
+// ReorderSiblings returns a copy of p with its siblings (sizes and path) converted
+// from one order to the other, so BottomUp proofs can be fed to the stateless
+// verifiers, which expect TopDown. p is returned as is when from == to.
+func ReorderSiblings(p *Proof, from, to ProofSiblingOrder) *Proof {
+	if p == nil || from == to {
+		return p
+	}
+	out := *p
+	out.Siblings = make([][]byte, 0, len(p.Siblings))
+	for i := len(p.Siblings) - 2; i >= 0; i -= 2 {
+		out.Siblings = append(out.Siblings, p.Siblings[i], p.Siblings[i+1])
+	}
+	if p.Sizes != nil {
+		out.Sizes = make([]uint64, len(p.Sizes))
+		for i, size := range p.Sizes {
+			out.Sizes[len(p.Sizes)-1-i] = size
+		}
+	}
+	if p.Path != nil {
+		out.Path = make([]PathNode, len(p.Path))
+		for i, node := range p.Path {
+			out.Path[len(p.Path)-1-i] = node
+		}
+	}
+	return &out
+}
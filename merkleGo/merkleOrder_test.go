@@ -0,0 +1,61 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestSiblingOrderMatchesContract(t *testing.T) {
+	f := loadSolidityFixture(t)
+	cmt := NewCartesianMerkleTreeSolidityV1()
+	cmt.SiblingOrder = BottomUp
+	for _, step := range f.Steps {
+		if step.Remove {
+			break
+		}
+		if _, err := cmt.Add(uintKey(step.Key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, wp := range f.Phases[0].Proofs {
+		key, _ := hex.DecodeString(wp.Key)
+		proof, err := cmt.GenerateProof(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Existence && !cmt.VerifyProof(key, proof) {
+			t.Fatalf("key %s: BottomUp proof doesn't verify", wp.Key)
+		}
+		topDown := ReorderSiblings(proof, BottomUp, TopDown)
+		for i, s := range topDown.Siblings {
+			if hex.EncodeToString(s) != wp.Siblings[i] {
+				t.Fatalf("key %s: reordered sibling %d is %x, contract has %s", wp.Key, i, s, wp.Siblings[i])
+			}
+		}
+		if len(proof.Siblings) > 2 && hex.EncodeToString(proof.Siblings[0]) == wp.Siblings[0] {
+			t.Fatalf("key %s: BottomUp proof starts like the contract's", wp.Key)
+		}
+	}
+}
+
+func TestReorderSiblingsRoundTrip(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.CommitSize = true
+	cmt.ProvePriorities = true
+	fillTree(t, cmt, strKeys(40))
+	for _, key := range [][]byte{[]byte("key-12"), []byte("absent")} {
+		proof, _ := cmt.GenerateProof(key)
+		bottomUp := ReorderSiblings(proof, TopDown, BottomUp)
+		n := len(proof.Sizes)
+		if bottomUp.Sizes[0] != proof.Sizes[n-1] || !reflect.DeepEqual(bottomUp.Path[0], proof.Path[n-1]) {
+			t.Fatalf("%s: sizes and path not reversed", key)
+		}
+		if back := ReorderSiblings(bottomUp, BottomUp, TopDown); !reflect.DeepEqual(back, proof) {
+			t.Fatalf("%s: round trip changed the proof", key)
+		}
+		if ReorderSiblings(proof, TopDown, TopDown) != proof {
+			t.Fatalf("%s: same-order reorder copied the proof", key)
+		}
+	}
+}
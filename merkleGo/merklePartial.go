@@ -0,0 +1,173 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// PartialCMT is the part of a tree revealed by a set of inclusion proofs:
+// the proven keys and the nodes on their paths, every other subtree being
+// known only by its hash. It can answer Contains and re-prove its keys
+// against the root it was built for. See BuildPartialFromProofs.
+type PartialCMT struct {
+	root   []byte
+	hasher func(a, b, c []byte) []byte
+	sized  bool
+	nodes  map[string]*partialNode // by node hash
+	parent map[string]string       // node hash -> parent hash
+	leaves map[string]*partialLeaf // by key
+}
+
+type partialNode struct {
+	entry    []byte
+	size     uint64    // CommitSize proofs only
+	children [2][]byte // child hashes, in no particular order
+}
+
+type partialLeaf struct {
+	key   []byte
+	value []byte
+	hash  []byte
+	pair  [2][]byte // the proven node's (left, right) child hashes, as in its proof
+}
+
+// BuildPartialFromProofs stitches inclusion proofs (TopDown, see
+// ReorderSiblings) into a PartialCMT and fails if any of them doesn't verify
+// against root. Proofs must all carry Sizes or none of them (CommitSize trees).
+// A nil hasher means the default 3-arg hasher.
+func BuildPartialFromProofs(root []byte, proofs []*Proof, hasher func(a, b, c []byte) []byte) (*PartialCMT, error) {
+	if len(root) == 0 {
+		return nil, errors.New("empty root")
+	}
+	if hasher == nil {
+		hasher = default3ArgHash
+	}
+	pt := &PartialCMT{
+		root:   root,
+		hasher: hasher,
+		nodes:  make(map[string]*partialNode),
+		parent: make(map[string]string),
+		leaves: make(map[string]*partialLeaf),
+	}
+	for i, p := range proofs {
+		if p == nil || !p.Existence {
+			return nil, fmt.Errorf("proof %d: not an inclusion proof", i)
+		}
+		n := len(p.Siblings)
+		if n < 2 || n%2 != 0 {
+			return nil, fmt.Errorf("proof %d: malformed siblings", i)
+		}
+		sized := len(p.Sizes) > 0
+		if i == 0 {
+			pt.sized = sized
+		} else if sized != pt.sized {
+			return nil, fmt.Errorf("proof %d: mixes sized and plain proofs", i)
+		}
+		if sized && len(p.Sizes) != n/2 {
+			return nil, fmt.Errorf("proof %d: %d sizes for %d nodes", i, len(p.Sizes), n/2)
+		}
+
+		// node hashes along the path, leaf first, exactly as foldSiblings computes them
+		depth := n / 2
+		hashes := make([][]byte, depth)
+		levels := make([]*partialNode, depth)
+		for d := depth - 1; d >= 0; d-- {
+			node := &partialNode{}
+			if d == depth-1 {
+				node.entry = nodeEntry(p.Key, p.Value)
+				node.children = [2][]byte{p.Siblings[n-2], p.Siblings[n-1]}
+			} else {
+				node.entry = p.Siblings[2*d]
+				node.children = [2][]byte{hashes[d+1], p.Siblings[2*d+1]}
+			}
+			if sized {
+				node.size = p.Sizes[d]
+			}
+			hashes[d] = pt.hash(node)
+			levels[d] = node
+		}
+		if !rootsEqual(hashes[0], root) {
+			return nil, fmt.Errorf("proof %d: key %x does not verify against the root", i, p.Key)
+		}
+
+		for d, node := range levels {
+			h := string(hashes[d])
+			if _, ok := pt.nodes[h]; !ok {
+				pt.nodes[h] = node
+			}
+			if d > 0 {
+				pt.parent[h] = string(hashes[d-1])
+			}
+		}
+		pt.leaves[string(p.Key)] = &partialLeaf{
+			key:   p.Key,
+			value: p.Value,
+			hash:  hashes[depth-1],
+			pair:  [2][]byte{p.Siblings[n-2], p.Siblings[n-1]},
+		}
+	}
+	return pt, nil
+}
+
+func (pt *PartialCMT) hash(node *partialNode) []byte {
+	if pt.sized {
+		return pt.hasher(sizedEntry(node.entry, node.size), node.children[0], node.children[1])
+	}
+	return pt.hasher(node.entry, node.children[0], node.children[1])
+}
+
+// Root returns the root the partial tree was built for
+func (pt *PartialCMT) Root() []byte {
+	return pt.root
+}
+
+// Contains reports whether key is one of the proven keys
+func (pt *PartialCMT) Contains(key []byte) bool {
+	_, ok := pt.leaves[string(key)]
+	return ok
+}
+
+// GenerateProof rebuilds the inclusion proof of a proven key from the partial
+// tree (TopDown). Unknown keys fail with ErrKeyNotFound: the partial tree can't
+// tell an absent key from one hidden in an unrevealed subtree.
+func (pt *PartialCMT) GenerateProof(key []byte) (*Proof, error) {
+	leaf, ok := pt.leaves[string(key)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %x", ErrKeyNotFound, key)
+	}
+	// collected leaf to root, reversed at the end
+	siblings := [][]byte{leaf.pair[1], leaf.pair[0]}
+	var sizes []uint64
+	if pt.sized {
+		sizes = append(sizes, pt.nodes[string(leaf.hash)].size)
+	}
+	cur := string(leaf.hash)
+	for {
+		up, ok := pt.parent[cur]
+		if !ok {
+			break
+		}
+		node := pt.nodes[up]
+		other := node.children[0]
+		if bytes.Equal(other, []byte(cur)) {
+			other = node.children[1]
+		}
+		siblings = append(siblings, other, node.entry)
+		if pt.sized {
+			sizes = append(sizes, node.size)
+		}
+		cur = up
+	}
+	reverseSlices(siblings)
+	for i, j := 0, len(sizes)-1; i < j; i, j = i+1, j-1 {
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+	}
+	return &Proof{Existence: true, Key: leaf.key, Value: leaf.value, Siblings: siblings, Sizes: sizes}, nil
+}
+
+func reverseSlices(s [][]byte) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
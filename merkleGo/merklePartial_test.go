@@ -0,0 +1,56 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildPartialFromProofs(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(100))
+		proven := [][]byte{[]byte("key-3"), []byte("key-42"), []byte("key-77"), cmt.Root.Key}
+		var proofs []*Proof
+		for _, key := range proven {
+			proof, _ := cmt.GenerateProof(key)
+			proofs = append(proofs, proof)
+		}
+		pt, err := BuildPartialFromProofs(cmt.GetRoot(), proofs, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt.Root(), cmt.GetRoot()) {
+			t.Fatal("partial tree has another root")
+		}
+		for i, key := range proven {
+			if !pt.Contains(key) {
+				t.Fatalf("CommitSize %v: %s missing", commitSize, key)
+			}
+			proof, err := pt.GenerateProof(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proofsEqual(proof, proofs[i]) || !VerifyProofAgainstRoot(key, proof, pt.Root(), nil) {
+				t.Fatalf("CommitSize %v: re-proof of %s differs or doesn't verify", commitSize, key)
+			}
+		}
+		if pt.Contains([]byte("key-4")) {
+			t.Fatal("unproven key reported present")
+		}
+		if _, err := pt.GenerateProof([]byte("key-4")); err == nil {
+			t.Fatal("proof of an unproven key")
+		}
+	}
+
+	cmt := buildTree(t, strKeys(20))
+	proof, _ := cmt.GenerateProof([]byte("key-1"))
+	other := buildTree(t, strKeys(21)).GetRoot()
+	if _, err := BuildPartialFromProofs(other, []*Proof{proof}, nil); err == nil {
+		t.Fatal("built from a proof of another root")
+	}
+	absent, _ := cmt.GenerateProof([]byte("absent"))
+	if _, err := BuildPartialFromProofs(cmt.GetRoot(), []*Proof{absent}, nil); err == nil {
+		t.Fatal("built from an exclusion proof")
+	}
+}
@@ -0,0 +1,78 @@
+package merkleGo
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrPoolClosed is returned by ProofPool.Generate once the pool is closed
+var ErrPoolClosed = errors.New("proof pool is closed")
+
+// ProofPool generates proofs on a fixed set of worker goroutines, so a burst
+// of requests queues up instead of spawning a goroutine each. Workers take the
+// tree's read lock per proof, like GenerateProof.
+type ProofPool struct {
+	cmt  *CartesianMerkleTree
+	jobs chan proofJob
+	// mu guards closed and keeps Close from closing jobs under a pending send
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type proofJob struct {
+	key   []byte
+	reply chan proofResult
+}
+
+type proofResult struct {
+	proof *Proof
+	err   error
+}
+
+// ProofPool starts workers goroutines (GOMAXPROCS if workers <= 0) serving
+// proofs of this tree. Close the pool to stop them.
+func (cmt *CartesianMerkleTree) ProofPool(workers int) *ProofPool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	pool := &ProofPool{cmt: cmt, jobs: make(chan proofJob)}
+	pool.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer pool.wg.Done()
+			for job := range pool.jobs {
+				proof, err := pool.cmt.GenerateProof(job.key)
+				job.reply <- proofResult{proof, err}
+			}
+		}()
+	}
+	return pool
+}
+
+// Generate waits for a free worker and returns its proof of key
+func (p *ProofPool) Generate(key []byte) (*Proof, error) {
+	reply := make(chan proofResult, 1)
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return nil, ErrPoolClosed
+	}
+	p.jobs <- proofJob{key: key, reply: reply}
+	p.mu.RUnlock()
+	res := <-reply
+	return res.proof, res.err
+}
+
+// Close stops the workers once the queued requests are served.
+// Later calls to Generate return ErrPoolClosed.
+func (p *ProofPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,42 @@
+package merkleGo
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestProofPool(t *testing.T) {
+	keys := strKeys(200)
+	cmt := buildTree(t, keys)
+	before := runtime.NumGoroutine()
+	pool := cmt.ProofPool(3)
+	if n := runtime.NumGoroutine() - before; n != 3 {
+		t.Fatalf("pool of 3 started %d goroutines", n)
+	}
+
+	var wg sync.WaitGroup
+	for c := 0; c < 20; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := c; i < len(keys); i += 20 {
+				proof, err := pool.Generate(keys[i])
+				if err != nil || !cmt.VerifyProof(keys[i], proof) {
+					t.Errorf("%s: %v", keys[i], err)
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	if n := runtime.NumGoroutine() - before; n != 3 {
+		t.Fatalf("%d pool goroutines after the burst", n)
+	}
+
+	pool.Close()
+	pool.Close()
+	if _, err := pool.Generate(keys[0]); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Generate after Close: %v", err)
+	}
+}
@@ -0,0 +1,442 @@
+package merkleGo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyNotFound is returned by GenerateInclusionProof for an absent key
+var ErrKeyNotFound = errors.New("key not found")
+
+// GenerateInclusionProof is GenerateProof for callers that need membership:
+// an absent key is an error (wrapping ErrKeyNotFound) instead of a proof with
+// Existence false.
+func (cmt *CartesianMerkleTree) GenerateInclusionProof(key []byte) (*Proof, error) {
+	proof, err := cmt.GenerateProof(key)
+	if err != nil {
+		return nil, err
+	}
+	if !proof.Existence {
+		return nil, fmt.Errorf("%w: %x", ErrKeyNotFound, key)
+	}
+	return proof, nil
+}
+
+// zeroHash stands for every empty child in proofs built by GenerateProofInto.
+// It is shared, so it must never be written to.
+var zeroHash = make([]byte, 32)
+
+// GenerateProofInto is GenerateProof writing into dst, whose Siblings and Sizes
+// are truncated and reused instead of reallocated, for servers generating many
+// proofs. Every other field is overwritten. Like GenerateProof's, the sibling
+// hashes are shared with the tree (and empty children with one another), so
+// they must not be modified; and dst is only valid until the next call that
+// reuses it, so don't retain it or hand it to other trees' calls meanwhile.
+// The negative cache is bypassed.
+func (cmt *CartesianMerkleTree) GenerateProofInto(key []byte, dst *Proof) error {
+	if dst == nil {
+		return errors.New("nil destination proof")
+	}
+	*dst = Proof{Key: key, Siblings: dst.Siblings[:0], Sizes: dst.Sizes[:0], Path: dst.Path[:0]}
+	if dst.Siblings == nil {
+		dst.Siblings = [][]byte{}
+	}
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if cmt.MaxProofDepth > 0 {
+		if depth, _ := cmt.pathDepth(key); depth > cmt.MaxProofDepth {
+			return fmt.Errorf("%w: %d > %d", ErrProofTooDeep, depth, cmt.MaxProofDepth)
+		}
+	}
+	if cmt.Root != nil {
+		cmt.collectProof(context.Background(), cmt.Root, key, dst, true)
+	}
+	if len(dst.Sizes) == 0 {
+		// GenerateProof leaves Sizes nil on plain trees
+		dst.Sizes = nil
+	}
+	if len(dst.Path) == 0 {
+		dst.Path = nil
+	}
+	if cmt.SelfVerifyProofs && dst.Existence && !rootsEqual(cmt.rebuildFromProof(dst.Key, dst), cmt.rootHash()) {
+		return fmt.Errorf("%w: key %x", ErrProofSelfCheck, key)
+	}
+	if cmt.SiblingOrder != TopDown {
+		reversePairs(dst)
+	}
+	return nil
+}
+
+// reversePairs is ReorderSiblings between TopDown and BottomUp, in place
+func reversePairs(p *Proof) {
+	for i, j := 0, len(p.Siblings)-2; i < j; i, j = i+2, j-2 {
+		p.Siblings[i], p.Siblings[j] = p.Siblings[j], p.Siblings[i]
+		p.Siblings[i+1], p.Siblings[j+1] = p.Siblings[j+1], p.Siblings[i+1]
+	}
+	for i, j := 0, len(p.Sizes)-1; i < j; i, j = i+1, j-1 {
+		p.Sizes[i], p.Sizes[j] = p.Sizes[j], p.Sizes[i]
+	}
+	for i, j := 0, len(p.Path)-1; i < j; i, j = i+1, j-1 {
+		p.Path[i], p.Path[j] = p.Path[j], p.Path[i]
+	}
+}
+
+// GenerateProofTo returns the proof of key up to the node anchorKey only,
+// verifiable (VerifyProofAgainstRoot) against that node's MerkleHash instead of
+// the root. Fails if anchorKey is absent or isn't an ancestor of key
+// (a node counts as its own ancestor).
+func (cmt *CartesianMerkleTree) GenerateProofTo(key, anchorKey []byte) (*Proof, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	anchor := cmt.find(anchorKey)
+	if anchor == nil {
+		return nil, fmt.Errorf("anchor key %x not found", anchorKey)
+	}
+	proof := &Proof{Key: key, Siblings: [][]byte{}}
+	cmt.generateProofHelper(anchor, key, proof)
+	if !proof.Existence {
+		return nil, fmt.Errorf("anchor %x is not an ancestor of key %x", anchorKey, key)
+	}
+	return proof, nil
+}
+
+// NodeHash returns the MerkleHash of the node holding key
+func (cmt *CartesianMerkleTree) NodeHash(key []byte) ([]byte, bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	node := cmt.find(key)
+	if node == nil {
+		return nil, false
+	}
+	return node.MerkleHash, true
+}
+
+// MinimizedProof is a Proof regrouped by the layout every proof shares (see
+// Proof.NonExistenceKey): one step per node above the last one, then the last
+// node's two child slots. A zero sibling always means "this child is empty",
+// and in this layout each slot that can hold one has a fixed meaning: the
+// off-path child of a path node (empty when that node has a single child) and
+// the last node's left and right children (empty on leaves, and on the
+// searched side of an exclusion proof's last node). An empty child is just a
+// nil slot, so no zero bytes and no positions are shipped: Expand re-derives
+// every zero from the slot it belongs to. Node entries are never elided.
+type MinimizedProof struct {
+	Existence       bool
+	Key             []byte
+	Value           []byte
+	Steps           []MinimizedStep // nodes above the last one, root first
+	Left, Right     []byte          // the last node's child hashes, nil when empty
+	Sizes           []uint64        // carried over untouched, see Proof.Sizes
+	NonExistenceKey []byte          // carried over untouched, see Proof.NonExistenceKey
+	Path            []PathNode      // carried over untouched, see Proof.Path
+}
+
+// MinimizedStep is one (entry, otherChildHash) pair of a proof
+type MinimizedStep struct {
+	Entry []byte
+	Other []byte // hash of the child off the search path, nil when it is empty
+}
+
+// MinimizeProof regroups p's siblings into steps, dropping every zero child
+// hash, see MinimizedProof. p's siblings must be TopDown (see ReorderSiblings).
+// A nil or malformed proof (see Proof.Kind) minimizes to nil.
+func MinimizeProof(p *Proof) *MinimizedProof {
+	if p.Kind() == Malformed {
+		return nil
+	}
+	m := &MinimizedProof{
+		Existence:       p.Existence,
+		Key:             p.Key,
+		Value:           p.Value,
+		Sizes:           p.Sizes,
+		NonExistenceKey: p.NonExistenceKey,
+		Path:            p.Path,
+	}
+	n := len(p.Siblings)
+	if n == 0 {
+		// an empty tree's exclusion proof
+		return m
+	}
+	for i := 0; i+2 < n; i += 2 {
+		m.Steps = append(m.Steps, MinimizedStep{Entry: p.Siblings[i], Other: nonZero(p.Siblings[i+1])})
+	}
+	m.Left, m.Right = nonZero(p.Siblings[n-2]), nonZero(p.Siblings[n-1])
+	return m
+}
+
+// nonZero returns h, or nil if h is the zero hash of an empty child
+func nonZero(h []byte) []byte {
+	if bytes.Equal(h, zeroHash) {
+		return nil
+	}
+	return h
+}
+
+// Expand puts a zero hash back into every empty slot and returns the original proof
+func (m *MinimizedProof) Expand() *Proof {
+	p := &Proof{
+		Existence:       m.Existence,
+		Key:             m.Key,
+		Value:           m.Value,
+		Sizes:           m.Sizes,
+		NonExistenceKey: m.NonExistenceKey,
+		Path:            m.Path,
+		Siblings:        [][]byte{},
+	}
+	orZero := func(h []byte) []byte {
+		if h == nil {
+			return make([]byte, 32)
+		}
+		return h
+	}
+	for _, step := range m.Steps {
+		p.Siblings = append(p.Siblings, step.Entry, orZero(step.Other))
+	}
+	// only an empty tree's exclusion proof has no last node: it names none
+	if m.Existence || m.NonExistenceKey != nil {
+		p.Siblings = append(p.Siblings, orZero(m.Left), orZero(m.Right))
+	}
+	return p
+}
+
+// Size returns the number of bytes the proof's siblings take: the entries and
+// non-empty child hashes, plus one bit per child slot telling whether it is
+// filled (rounded up to whole bytes), which is all an encoding needs to tell
+// the empty slots apart
+func (m *MinimizedProof) Size() int {
+	size := len(m.Left) + len(m.Right)
+	for _, step := range m.Steps {
+		size += len(step.Entry) + len(step.Other)
+	}
+	return size + (len(m.Steps)+2+7)/8
+}
+
+// VerifyMinimizedProof re-derives the empty children's zero hashes and verifies the result
+func (cmt *CartesianMerkleTree) VerifyMinimizedProof(key []byte, m *MinimizedProof) bool {
+	if m == nil {
+		return false
+	}
+	return cmt.VerifyProof(key, m.Expand())
+}
+
+// ProofLength returns how many entries GenerateProof(key).Siblings would hold,
+// and whether key is present, without building the proof.
+// Every node on the search path contributes exactly two entries.
+func (cmt *CartesianMerkleTree) ProofLength(key []byte) (int, bool) {
+	if cmt == nil {
+		return 0, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	depth, found := cmt.pathDepth(key)
+	return 2 * depth, found
+}
+
+// ProofStats summarises the sibling counts of the existence proofs of all keys
+type ProofStats struct {
+	Keys   int
+	Min    int
+	Max    int // always 2 * Height
+	Mean   float64
+	Median float64 // mean of the two middle counts when Keys is even
+}
+
+// ProofSizeStats returns min/max/mean/median of ProofLength over every key,
+// from one traversal of the tree. All zero for an empty tree.
+func (cmt *CartesianMerkleTree) ProofSizeStats() ProofStats {
+	if cmt == nil {
+		return ProofStats{}
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	// perDepth[d] counts the nodes at depth d+1
+	var perDepth []int
+	var walk func(node *TreapNode, depth int)
+	walk = func(node *TreapNode, depth int) {
+		if node == nil {
+			return
+		}
+		if depth > len(perDepth) {
+			perDepth = append(perDepth, 0)
+		}
+		perDepth[depth-1]++
+		walk(node.Left, depth+1)
+		walk(node.Right, depth+1)
+	}
+	walk(cmt.Root, 1)
+	if len(perDepth) == 0 {
+		return ProofStats{}
+	}
+
+	stats := ProofStats{Min: 2, Max: 2 * len(perDepth)}
+	total := 0
+	for i, n := range perDepth {
+		stats.Keys += n
+		total += n * 2 * (i + 1)
+	}
+	stats.Mean = float64(total) / float64(stats.Keys)
+
+	// k-th smallest sibling count (0-based), read off the depth histogram
+	nth := func(k int) int {
+		for i, n := range perDepth {
+			if k < n {
+				return 2 * (i + 1)
+			}
+			k -= n
+		}
+		return stats.Max
+	}
+	mid := stats.Keys / 2
+	if stats.Keys%2 == 1 {
+		stats.Median = float64(nth(mid))
+	} else {
+		stats.Median = float64(nth(mid-1)+nth(mid)) / 2
+	}
+	return stats
+}
+
+// pathDepth counts the nodes on the search path of key
+func (cmt *CartesianMerkleTree) pathDepth(key []byte) (int, bool) {
+	key = cmt.treeKey(key)
+	depth := 0
+	node := cmt.Root
+	for node != nil {
+		depth++
+		cmp := cmt.compareKeys(key, node.Key)
+		if cmp == 0 {
+			return depth, true
+		}
+		if cmp < 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return depth, false
+}
+
+// EachProof generates the proof of every key, in ascending key order, and hands
+// it to visit until visit returns false. Proofs are built in one traversal under a
+// single read lock, so they all verify against the returned root; writers wait
+// until the walk ends. They are the proofs GenerateProof returns, Path,
+// MaxProofDepth and SelfVerifyProofs included: the walk stops with the error
+// GenerateProof would give at the first key deeper than MaxProofDepth or whose
+// proof fails its self-check.
+func (cmt *CartesianMerkleTree) EachProof(visit func(key []byte, proof *Proof) bool) (root []byte, err error) {
+	if cmt == nil {
+		return nil, nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	err = cmt.walkProofs(func(node *TreapNode, proof *Proof) bool {
+		return visit(node.Key, ReorderSiblings(proof, TopDown, cmt.SiblingOrder))
+	})
+	return cmt.rootHash(), err
+}
+
+// AllProofs returns the inclusion proof of every key, keyed by string(key),
+// built in one traversal (see EachProof) rather than one descent per key.
+// It fails, returning no proofs, where EachProof does.
+func (cmt *CartesianMerkleTree) AllProofs() (map[string]*Proof, error) {
+	if cmt == nil {
+		return map[string]*Proof{}, nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	proofs := make(map[string]*Proof, sizeOf(cmt.Root))
+	err := cmt.walkProofs(func(node *TreapNode, proof *Proof) bool {
+		proofs[string(node.Key)] = ReorderSiblings(proof, TopDown, cmt.SiblingOrder)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proofs, nil
+}
+
+// walkProofs visits every node in key order with its TopDown inclusion proof,
+// until visit returns false. A single depth-first walk keeps the (entry,
+// other child) pairs of the current path on a stack, so each entry is hashed
+// once and each proof is a copy of the stack plus the node's children,
+// instead of a fresh descent per key. Proofs share the child hash slices.
+// It applies generateProofContext's MaxProofDepth and SelfVerifyProofs checks
+// and returns their error, ending the walk.
+func (cmt *CartesianMerkleTree) walkProofs(visit func(node *TreapNode, proof *Proof) bool) error {
+	var path [][]byte
+	var sizes []uint64
+	var nodes []PathNode
+	var err error
+	root := cmt.rootHash()
+	childHash := func(child *TreapNode) []byte {
+		if child == nil {
+			return make([]byte, 32)
+		}
+		return child.MerkleHash
+	}
+	var walk func(node *TreapNode, depth int) bool
+	walk = func(node *TreapNode, depth int) bool {
+		if node == nil {
+			return true
+		}
+		if cmt.MaxProofDepth > 0 && depth > cmt.MaxProofDepth {
+			err = fmt.Errorf("%w: %d > %d", ErrProofTooDeep, depth, cmt.MaxProofDepth)
+			return false
+		}
+		entry := cmt.entry(node.Key, node.Value)
+		if cmt.CommitSize {
+			sizes = append(sizes, uint64(node.Size))
+		}
+		if cmt.ProvePriorities {
+			nodes = append(nodes, cmt.pathNode(node))
+		}
+		left, right := childHash(node.Left), childHash(node.Right)
+
+		path = append(path, entry, right)
+		ok := walk(node.Left, depth+1)
+		path = path[:len(path)-2]
+		if ok {
+			proof := &Proof{Existence: true, Key: node.Key, Value: node.Value}
+			proof.Siblings = make([][]byte, len(path), len(path)+2)
+			copy(proof.Siblings, path)
+			proof.Siblings = append(proof.Siblings, left, right)
+			if cmt.CommitSize {
+				proof.Sizes = append([]uint64(nil), sizes...)
+			}
+			if cmt.ProvePriorities {
+				proof.Path = append([]PathNode(nil), nodes...)
+			}
+			if cmt.SelfVerifyProofs && !rootsEqual(cmt.rebuildFromProof(proof.Key, proof), root) {
+				err = fmt.Errorf("%w: key %x", ErrProofSelfCheck, node.Key)
+				return false
+			}
+			ok = visit(node, proof)
+		}
+		if ok {
+			path = append(path, entry, left)
+			ok = walk(node.Right, depth+1)
+			path = path[:len(path)-2]
+		}
+		if cmt.CommitSize {
+			sizes = sizes[:len(sizes)-1]
+		}
+		if cmt.ProvePriorities {
+			nodes = nodes[:len(nodes)-1]
+		}
+		return ok
+	}
+	walk(cmt.Root, 1)
+	return err
+}
@@ -0,0 +1,242 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ProofFormatVersion is the version MarshalBinary and MarshalJSON write.
+//
+// Version 1 is the JSON proof served before versioning (e.g. by /cmt/proof):
+// Existence, Key and Siblings, with no version field, and exclusion proofs
+// ending with the last node's (key, otherChildHash) pair. Version 2 adds the
+// version field, Value, Sizes, NonExistenceKey and Path, lays exclusion proofs
+// out like the contract (see Proof.NonExistenceKey), and introduces the binary
+// encoding, which has no version 1.
+const ProofFormatVersion = 2
+
+// ErrUnsupportedProofVersion is returned when decoding a proof written by a
+// newer (or unknown) format version
+var ErrUnsupportedProofVersion = errors.New("unsupported proof format version")
+
+const (
+	proofFlagExistence = 1 << iota
+	proofFlagValue
+	proofFlagNonExistenceKey
+)
+
+// MarshalBinary encodes the proof in the current format: a version byte, a
+// flags byte, then uvarint-length-prefixed fields
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	flags := byte(0)
+	if p.Existence {
+		flags |= proofFlagExistence
+	}
+	if p.Value != nil {
+		flags |= proofFlagValue
+	}
+	if p.NonExistenceKey != nil {
+		flags |= proofFlagNonExistenceKey
+	}
+	out := []byte{ProofFormatVersion, flags}
+	out = appendBytes(out, p.Key)
+	if p.Value != nil {
+		out = appendBytes(out, p.Value)
+	}
+	out = binary.AppendUvarint(out, uint64(len(p.Siblings)))
+	for _, s := range p.Siblings {
+		out = appendBytes(out, s)
+	}
+	out = binary.AppendUvarint(out, uint64(len(p.Sizes)))
+	for _, size := range p.Sizes {
+		out = binary.AppendUvarint(out, size)
+	}
+	if p.NonExistenceKey != nil {
+		out = appendBytes(out, p.NonExistenceKey)
+	}
+	out = binary.AppendUvarint(out, uint64(len(p.Path)))
+	for _, node := range p.Path {
+		out = appendBytes(out, node.Key)
+		if node.Value == nil {
+			out = append(out, 0)
+		} else {
+			out = appendBytes(append(out, 1), node.Value)
+		}
+		out = appendBytes(out, node.Priority)
+		out = binary.AppendUvarint(out, node.Weight)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary decodes MarshalBinary output
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("%w: %d bytes", ErrMalformedProof, len(data))
+	}
+	version, flags := data[0], data[1]
+	if version != ProofFormatVersion {
+		return fmt.Errorf("%w: binary version %d", ErrUnsupportedProofVersion, version)
+	}
+	r := &proofReader{data: data[2:]}
+	out := Proof{Existence: flags&proofFlagExistence != 0}
+	out.Key = r.bytes()
+	if flags&proofFlagValue != 0 {
+		out.Value = r.bytes()
+	}
+	out.Siblings = make([][]byte, r.count())
+	for i := range out.Siblings {
+		out.Siblings[i] = r.bytes()
+	}
+	if n := r.count(); n > 0 {
+		out.Sizes = make([]uint64, n)
+		for i := range out.Sizes {
+			out.Sizes[i] = r.uvarint()
+		}
+	}
+	if flags&proofFlagNonExistenceKey != 0 {
+		out.NonExistenceKey = r.bytes()
+	}
+	if n := r.count(); n > 0 {
+		out.Path = make([]PathNode, n)
+		for i := range out.Path {
+			node := &out.Path[i]
+			node.Key = r.bytes()
+			if r.byte() == 1 {
+				node.Value = r.bytes()
+			}
+			node.Priority = r.bytes()
+			node.Weight = r.uvarint()
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedProof, r.err)
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformedProof, len(r.data))
+	}
+	*p = out
+	return nil
+}
+
+// proofJSON is Proof's JSON form: its fields as encoding/json writes them by
+// default, plus the format version. Version 1 proofs have no version field and
+// a subset of the fields, so they decode into the same struct.
+type proofJSON struct {
+	Version int `json:"version"`
+	*proofFields
+}
+
+// proofFields is Proof without its JSON methods
+type proofFields Proof
+
+// MarshalJSON writes the proof's fields with the current format version
+func (p *Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proofJSON{Version: ProofFormatVersion, proofFields: (*proofFields)(p)})
+}
+
+// UnmarshalJSON reads MarshalJSON output of any known version, upgrading
+// version 1 proofs (see ProofFormatVersion): their exclusion proofs get the
+// NonExistenceKey and final children pair they imply. Fields a version lacks
+// stay zero.
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	in := proofJSON{proofFields: &proofFields{}}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	proof, err := in.upgrade()
+	if err != nil {
+		return err
+	}
+	*p = *proof
+	return nil
+}
+
+// upgrade checks the decoded version and brings the proof to the current one
+func (in proofJSON) upgrade() (*Proof, error) {
+	p := (*Proof)(in.proofFields)
+	switch in.Version {
+	case 0, 1:
+		// version 1, with or without the version field it never wrote
+		if p.Existence || len(p.Siblings) == 0 {
+			return p, nil
+		}
+		n := len(p.Siblings)
+		if n%2 != 0 {
+			return nil, fmt.Errorf("%w: version 1 exclusion proof with %d siblings", ErrMalformedProof, n)
+		}
+		// the last pair was the dead end's (key, otherChildHash); the empty
+		// child is on the missing key's side (version 1 trees used byte order)
+		last, other := p.Siblings[n-2], p.Siblings[n-1]
+		left, right := other, make([]byte, 32)
+		if bytes.Compare(p.Key, last) < 0 {
+			left, right = right, left
+		}
+		p.NonExistenceKey = last
+		p.Siblings = append(p.Siblings[:n-2:n-2], left, right)
+		return p, nil
+	case ProofFormatVersion:
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedProofVersion, in.Version)
+	}
+}
+
+func appendBytes(out, b []byte) []byte {
+	return append(binary.AppendUvarint(out, uint64(len(b))), b...)
+}
+
+// proofReader decodes UnmarshalBinary's fields, keeping the first error
+type proofReader struct {
+	data []byte
+	err  error
+}
+
+func (r *proofReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("truncated varint")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// count reads a length that must fit in what is left to read
+func (r *proofReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		if r.err == nil {
+			r.err = fmt.Errorf("count %d exceeds the remaining %d bytes", n, len(r.data))
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *proofReader) bytes() []byte {
+	n := r.count()
+	if r.err != nil {
+		return nil
+	}
+	b := append([]byte{}, r.data[:n]...)
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *proofReader) byte() byte {
+	if r.err == nil && len(r.data) == 0 {
+		r.err = errors.New("truncated flag")
+	}
+	if r.err != nil {
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
@@ -0,0 +1,145 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/proofs_v1.json holds proofs as the baseline tree served them,
+// before proofs carried a version
+func TestProofJSONDecodesVersion1(t *testing.T) {
+	raw, err := os.ReadFile("testdata/proofs_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Keys   []string
+		Root   string
+		Proofs map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	cmt := NewCartesianMerkleTree()
+	for _, key := range fixture.Keys {
+		fillTree(t, cmt, [][]byte{[]byte(key)})
+	}
+	if got := hex.EncodeToString(cmt.GetRoot()); got != fixture.Root {
+		t.Fatalf("root %s, version 1 tree had %s", got, fixture.Root)
+	}
+
+	for key, data := range fixture.Proofs {
+		var proof Proof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if proof.Existence {
+			if ok, err := cmt.VerifyProofDetailed([]byte(key), &proof); !ok {
+				t.Errorf("%s: version 1 inclusion proof doesn't verify: %v", key, err)
+			}
+			continue
+		}
+		want, _ := cmt.GenerateProof([]byte(key))
+		if !proofsEqual(&proof, want) {
+			t.Errorf("%s: upgraded exclusion proof %+v, want %+v", key, proof, *want)
+		}
+		if !cmt.VerifyNonMembership([]byte(key), &proof) {
+			t.Errorf("%s: upgraded exclusion proof doesn't verify", key)
+		}
+	}
+}
+
+func TestProofJSONVersion(t *testing.T) {
+	cmt := buildTree(t, strKeys(10))
+	proof, _ := cmt.GenerateProof([]byte("key-4"))
+	data, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":2`) {
+		t.Fatalf("no version in %s", data)
+	}
+	var back Proof
+	if err := json.Unmarshal(data, &back); err != nil || !reflect.DeepEqual(&back, proof) {
+		t.Fatalf("round trip: %v, %+v", err, back)
+	}
+
+	future := strings.Replace(string(data), `"version":2`, `"version":3`, 1)
+	if err := json.Unmarshal([]byte(future), &back); !errors.Is(err, ErrUnsupportedProofVersion) {
+		t.Fatalf("version 3: %v", err)
+	}
+
+	vp, err := cmt.GenerateProofWithValue([]byte("key-4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = json.Marshal(vp)
+	var vback ValueProof
+	if err := json.Unmarshal(data, &vback); err != nil || !reflect.DeepEqual(&vback, vp) {
+		t.Fatalf("value proof round trip: %v", err)
+	}
+}
+
+func TestProofBinaryRoundTrip(t *testing.T) {
+	plain := buildTree(t, strKeys(30))
+	sized := NewCartesianMerkleTree()
+	sized.CommitSize = true
+	sized.ProvePriorities = true
+	for i, key := range strKeys(30) {
+		if i%2 == 0 {
+			sized.AddKV(key, []byte{byte(i)})
+		} else {
+			sized.Add(key)
+		}
+	}
+
+	for _, cmt := range []*CartesianMerkleTree{plain, sized, NewCartesianMerkleTree()} {
+		for _, key := range []string{"key-0", "key-7", "key-12", "absent"} {
+			proof, err := cmt.GenerateProof([]byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := proof.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data[0] != ProofFormatVersion {
+				t.Fatalf("version byte %d", data[0])
+			}
+			var back Proof
+			if err := back.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%s: %v", key, err)
+			}
+			if !reflect.DeepEqual(&back, proof) {
+				t.Fatalf("%s: round trip gave %+v, want %+v", key, back, *proof)
+			}
+		}
+	}
+}
+
+func TestProofBinaryRejects(t *testing.T) {
+	proof, _ := buildTree(t, strKeys(10)).GenerateProof([]byte("key-1"))
+	data, _ := proof.MarshalBinary()
+	var back Proof
+
+	for _, version := range []byte{0, 1, ProofFormatVersion + 1} {
+		bad := append([]byte{version}, data[1:]...)
+		if err := back.UnmarshalBinary(bad); !errors.Is(err, ErrUnsupportedProofVersion) {
+			t.Errorf("version %d: %v", version, err)
+		}
+	}
+	for name, bad := range map[string][]byte{
+		"short":     data[:1],
+		"truncated": data[:len(data)-3],
+		"trailing":  append(append([]byte{}, data...), 0),
+	} {
+		if err := back.UnmarshalBinary(bad); !errors.Is(err, ErrMalformedProof) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
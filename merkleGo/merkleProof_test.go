@@ -0,0 +1,297 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMinimizeProof(t *testing.T) {
+	if MinimizeProof(nil) != nil || MinimizeProof(&Proof{Key: []byte("k"), Siblings: [][]byte{{1}}}) != nil {
+		t.Fatal("nil or malformed proof minimized to a proof")
+	}
+	empty, _ := NewCartesianMerkleTree().GenerateProof([]byte("key"))
+	if got := MinimizeProof(empty).Expand(); !proofsEqual(got, empty) || got.Siblings == nil {
+		t.Fatalf("empty tree's proof expands to %+v", got)
+	}
+
+	cmt := NewCartesianMerkleTree()
+	cmt.ProvePriorities = true
+	fillTree(t, cmt, strKeys(50))
+	for _, key := range append(strKeys(50), []byte("absent")) {
+		proof, _ := cmt.GenerateProof(key)
+		m := MinimizeProof(proof)
+		expanded := m.Expand()
+		if !proofsEqual(expanded, proof) || !reflect.DeepEqual(expanded.Path, proof.Path) {
+			t.Fatalf("%s: round trip changed the proof", key)
+		}
+		if proof.Existence && !cmt.VerifyMinimizedProof(key, m) {
+			t.Fatalf("%s: minimized proof doesn't verify", key)
+		}
+
+		// every zero child hash is gone, every entry kept; a bit per child
+		// slot (one per pair, and two for the last node) marks the empty ones
+		full, zeros := 0, 0
+		for _, s := range proof.Siblings {
+			full += len(s)
+			if bytes.Equal(s, make([]byte, 32)) {
+				zeros++
+			}
+		}
+		if want := full - 32*zeros + (len(proof.Siblings)/2+1+7)/8; m.Size() != want {
+			t.Fatalf("%s: Size %d, want %d", key, m.Size(), want)
+		}
+		if zeros > 0 && m.Size() >= full {
+			t.Fatalf("%s: minimized to %d bytes from %d", key, m.Size(), full)
+		}
+	}
+
+	// priorities that grow with the key make a left-leaning chain, every
+	// node on it has a single child
+	chain := NewCartesianMerkleTree()
+	chain.PriorityFunc = func(key []byte) []byte { return key }
+	keys := make([][]byte, 40)
+	for i := range keys {
+		keys[i] = uintKey(int64(i))
+	}
+	if fillTree(t, chain, keys).Height() != len(keys) {
+		t.Fatalf("chain of %d keys has height %d", len(keys), chain.Height())
+	}
+	proof, _ := chain.GenerateProof(keys[0])
+	m := MinimizeProof(proof)
+	if !chain.VerifyMinimizedProof(keys[0], m) {
+		t.Fatal("chain: minimized proof doesn't verify")
+	}
+	if full := 32 * len(proof.Siblings); m.Size() > full*6/10 {
+		t.Fatalf("chain: minimized to %d bytes from %d", m.Size(), full)
+	}
+}
+
+func TestEachProofMatchesGenerateProof(t *testing.T) {
+	configs := map[string]func(cmt *CartesianMerkleTree){
+		"plain": func(*CartesianMerkleTree) {},
+		"sized with path": func(cmt *CartesianMerkleTree) {
+			cmt.CommitSize = true
+			cmt.ProvePriorities = true
+		},
+		"bottom up": func(cmt *CartesianMerkleTree) { cmt.SiblingOrder = BottomUp },
+		"self verified": func(cmt *CartesianMerkleTree) {
+			cmt.SelfVerifyProofs = true
+			cmt.MaxProofDepth = 64
+		},
+	}
+	for name, configure := range configs {
+		cmt := NewCartesianMerkleTree()
+		configure(cmt)
+		fillTree(t, cmt, strKeys(100))
+		visited := 0
+		root, err := cmt.EachProof(func(key []byte, proof *Proof) bool {
+			visited++
+			want, err := cmt.GenerateProof(key)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !reflect.DeepEqual(proof, want) {
+				t.Fatalf("%s: EachProof(%s) differs from GenerateProof", name, key)
+			}
+			if !cmt.VerifyProof(key, proof) {
+				t.Fatalf("%s: proof of %s doesn't verify", name, key)
+			}
+			return true
+		})
+		if err != nil || visited != 100 || !reflect.DeepEqual(root, cmt.GetRoot()) {
+			t.Fatalf("%s: visited %d, root %x, %v", name, visited, root, err)
+		}
+	}
+}
+
+func TestEachProofChecks(t *testing.T) {
+	deep := buildTree(t, strKeys(100))
+	deep.MaxProofDepth = 3
+	if _, err := deep.EachProof(func([]byte, *Proof) bool { return true }); !errors.Is(err, ErrProofTooDeep) {
+		t.Fatalf("MaxProofDepth: %v", err)
+	}
+	if proofs, err := deep.AllProofs(); !errors.Is(err, ErrProofTooDeep) || proofs != nil {
+		t.Fatalf("AllProofs under MaxProofDepth: %d proofs, %v", len(proofs), err)
+	}
+
+	corrupt := buildTree(t, strKeys(100))
+	corrupt.SelfVerifyProofs = true
+	corrupt.Root.Left.MerkleHash = make([]byte, 32)
+	if _, err := corrupt.EachProof(func([]byte, *Proof) bool { return true }); !errors.Is(err, ErrProofSelfCheck) {
+		t.Fatalf("SelfVerifyProofs: %v", err)
+	}
+}
+
+func BenchmarkAllProofs(b *testing.B) {
+	cmt := buildTree(b, strKeys(10000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmt.AllProofs(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateProofPerKey(b *testing.B) {
+	keys := strKeys(10000)
+	cmt := buildTree(b, keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		proofs := make(map[string]*Proof, len(keys))
+		for _, key := range keys {
+			proof, err := cmt.GenerateProof(key)
+			if err != nil {
+				b.Fatal(err)
+			}
+			proofs[string(key)] = proof
+		}
+	}
+}
+
+func TestGenerateProofTo(t *testing.T) {
+	cmt := buildTree(t, strKeys(60))
+	root := cmt.GetRoot()
+	for _, key := range strKeys(60) {
+		// every node on the way down is an anchor for key
+		for node := cmt.Root; node != nil; {
+			proof, err := cmt.GenerateProofTo(key, node.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			anchor, _ := cmt.NodeHash(node.Key)
+			if !VerifyProofAgainstRoot(key, proof, anchor, nil) {
+				t.Fatalf("%s: proof to %s doesn't verify against the anchor", key, node.Key)
+			}
+			if node != cmt.Root && VerifyProofAgainstRoot(key, proof, root, nil) {
+				t.Fatalf("%s: proof to %s verifies against the root", key, node.Key)
+			}
+			cmp := bytes.Compare(key, node.Key)
+			if cmp == 0 {
+				break
+			}
+			if cmp < 0 {
+				node = node.Left
+			} else {
+				node = node.Right
+			}
+		}
+	}
+
+	leaf := cmt.Root
+	for leaf.Left != nil {
+		leaf = leaf.Left
+	}
+	if _, err := cmt.GenerateProofTo(cmt.Root.Key, leaf.Key); err == nil {
+		t.Fatal("proof anchored below the key")
+	}
+	if _, err := cmt.GenerateProofTo([]byte("key-1"), []byte("absent")); err == nil {
+		t.Fatal("proof anchored at an absent key")
+	}
+}
+
+func TestProofLength(t *testing.T) {
+	if n, ok := NewCartesianMerkleTree().ProofLength([]byte("key")); n != 0 || ok {
+		t.Fatalf("empty tree: %d, %v", n, ok)
+	}
+	cmt := buildTree(t, strKeys(80))
+	for _, key := range append(strKeys(90), []byte("absent")) {
+		proof, _ := cmt.GenerateProof(key)
+		n, ok := cmt.ProofLength(key)
+		if n != len(proof.Siblings) || ok != proof.Existence {
+			t.Fatalf("%s: ProofLength %d, %v, proof has %d siblings, existence %v", key, n, ok, len(proof.Siblings), proof.Existence)
+		}
+	}
+}
+
+func TestGenerateInclusionProof(t *testing.T) {
+	cmt := buildTree(t, strKeys(10))
+	proof, err := cmt.GenerateInclusionProof([]byte("key-4"))
+	if err != nil || !cmt.VerifyProof([]byte("key-4"), proof) {
+		t.Fatalf("present key: %v", err)
+	}
+	for name, tree := range map[string]*CartesianMerkleTree{"tree": cmt, "empty": NewCartesianMerkleTree()} {
+		if proof, err := tree.GenerateInclusionProof([]byte("absent")); proof != nil || !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("%s, absent key: %v, %v", name, proof, err)
+		}
+	}
+}
+
+func TestProofSizeStats(t *testing.T) {
+	if got := NewCartesianMerkleTree().ProofSizeStats(); got != (ProofStats{}) {
+		t.Fatalf("empty tree: %+v", got)
+	}
+	// depths 1, 2, 2, 3, 3, 3, 3
+	cmt := perfectTree(t)
+	want := ProofStats{Keys: 7, Min: 2, Max: 6, Mean: 34.0 / 7, Median: 6}
+	if got := cmt.ProofSizeStats(); got != want {
+		t.Fatalf("perfect tree: %+v, want %+v", got, want)
+	}
+	// depths 1, 2, 2, 3, 3, 3: the median is between a 4 and a 6
+	if err := cmt.Remove(uintKey(7)); err != nil {
+		t.Fatal(err)
+	}
+	want = ProofStats{Keys: 6, Min: 2, Max: 6, Mean: 28.0 / 6, Median: 5}
+	if got := cmt.ProofSizeStats(); got != want {
+		t.Fatalf("after a removal: %+v, want %+v", got, want)
+	}
+
+	random := buildTree(t, strKeys(300))
+	stats := random.ProofSizeStats()
+	total := 0
+	for _, key := range strKeys(300) {
+		n, _ := random.ProofLength(key)
+		total += n
+	}
+	if stats.Max != 2*random.Height() || stats.Mean != float64(total)/300 {
+		t.Fatalf("%+v: height %d, mean %.3f", stats, random.Height(), float64(total)/300)
+	}
+}
+
+// A proof reused across keys, growing and shrinking, matches GenerateProof's
+func TestGenerateProofInto(t *testing.T) {
+	keys := strKeys(200)
+	cmt := buildTree(t, keys)
+	if err := cmt.GenerateProofInto(keys[0], nil); err == nil {
+		t.Fatal("nil destination accepted")
+	}
+	var dst Proof
+	for _, key := range append(keys, []byte("absent")) {
+		if err := cmt.GenerateProofInto(key, &dst); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := cmt.GenerateProof(key)
+		if dst.Existence != want.Existence || len(dst.Siblings) != len(want.Siblings) {
+			t.Fatalf("%s: proof differs from GenerateProof's", key)
+		}
+		if dst.Existence && !cmt.VerifyProof(key, &dst) {
+			t.Fatalf("%s: reused proof doesn't verify", key)
+		}
+	}
+}
+
+func BenchmarkGenerateProof(b *testing.B) {
+	keys := strKeys(10000)
+	cmt := buildTree(b, keys)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cmt.GenerateProof(keys[i%len(keys)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateProofInto(b *testing.B) {
+	keys := strKeys(10000)
+	cmt := buildTree(b, keys)
+	var dst Proof
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cmt.GenerateProofInto(keys[i%len(keys)], &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
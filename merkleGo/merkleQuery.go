@@ -0,0 +1,397 @@
+package merkleGo
+
+import (
+	"bytes"
+	"math"
+	"sort"
+)
+
+// Contains reports whether key is in the tree
+func (cmt *CartesianMerkleTree) Contains(key []byte) bool {
+	if cmt == nil {
+		return false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return cmt.find(key) != nil
+}
+
+// Get returns the value stored under key. ok tells a present key with an empty
+// (or no) value apart from an absent key. The returned slice must not be modified.
+// With a ValueLoader the value is the loaded one, and ok is also false when
+// loading or checking it failed (LoadValue tells why).
+func (cmt *CartesianMerkleTree) Get(key []byte) (value []byte, ok bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	if cmt.ValueLoader != nil {
+		raw, _, err := cmt.LoadValue(key)
+		return raw, err == nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	node := cmt.find(key)
+	if node == nil {
+		return nil, false
+	}
+	return node.Value, true
+}
+
+// find returns the node holding key, or nil
+func (cmt *CartesianMerkleTree) find(key []byte) *TreapNode {
+	key = cmt.treeKey(key)
+	node := cmt.Root
+	for node != nil {
+		cmp := cmt.compareKeys(key, node.Key)
+		if cmp == 0 {
+			return node
+		}
+		if cmp < 0 {
+			node = node.Left
+		} else {
+			node = node.Right
+		}
+	}
+	return nil
+}
+
+// inOrder visits the subtree in ascending key order until visit returns false
+func inOrder(node *TreapNode, visit func(*TreapNode) bool) bool {
+	if node == nil {
+		return true
+	}
+	return inOrder(node.Left, visit) && visit(node) && inOrder(node.Right, visit)
+}
+
+// Size returns the number of keys in the tree
+func (cmt *CartesianMerkleTree) Size() int {
+	if cmt == nil {
+		return 0
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if cmt.Root == nil {
+		return 0
+	}
+	return cmt.Root.Size
+}
+
+// Keys returns every key in ascending order. The slices must not be modified.
+func (cmt *CartesianMerkleTree) Keys() [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return cmt.keys()
+}
+
+// KeysWithPrefix returns, in ascending order, every key starting with prefix,
+// e.g. a namespace of composite keys (see CompositeKey.Prefix). Only the
+// subtrees that can hold such keys are visited. Keys hashed by MaxKeyLength
+// lose their prefix. The slices must not be modified.
+func (cmt *CartesianMerkleTree) KeysWithPrefix(prefix []byte) [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if cmt.KeyEqual != nil || cmt.KeyLess != nil {
+		// the prefixed keys needn't be contiguous in a custom order
+		var out [][]byte
+		inOrder(cmt.Root, func(node *TreapNode) bool {
+			if bytes.HasPrefix(node.Key, prefix) {
+				out = append(out, node.Key)
+			}
+			return true
+		})
+		return out
+	}
+	var out [][]byte
+	var walk func(node *TreapNode)
+	walk = func(node *TreapNode) {
+		if node == nil {
+			return
+		}
+		match := bytes.HasPrefix(node.Key, prefix)
+		before := !match && bytes.Compare(node.Key, prefix) < 0
+		if !before {
+			walk(node.Left)
+		}
+		if match {
+			out = append(out, node.Key)
+		}
+		if before || match {
+			walk(node.Right)
+		}
+	}
+	walk(cmt.Root)
+	return out
+}
+
+// KeysByInsertionOrder returns every key in the order it was inserted, nil
+// unless TrackInsertionOrder is set. Keys inserted before it was set come
+// first, in key order. A re-keyed (Replace) key counts as newly inserted, and
+// UnmarshalJSON and TreeBuilder.Publish hand over the order the entries were
+// rebuilt in (key order for UnmarshalJSON). The slices must not be modified.
+func (cmt *CartesianMerkleTree) KeysByInsertionOrder() [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if !cmt.TrackInsertionOrder {
+		return nil
+	}
+	var nodes []*TreapNode
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		nodes = append(nodes, node)
+		return true
+	})
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Seq < nodes[j].Seq })
+	keys := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		keys[i] = node.Key
+	}
+	return keys
+}
+
+// Select returns the k-th smallest key (0-based), false if k is out of range
+func (cmt *CartesianMerkleTree) Select(k int) ([]byte, bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	node := cmt.Root
+	for node != nil {
+		leftSize := 0
+		if node.Left != nil {
+			leftSize = node.Left.Size
+		}
+		switch {
+		case k < leftSize:
+			node = node.Left
+		case k == leftSize:
+			return node.Key, true
+		default:
+			k -= leftSize + 1
+			node = node.Right
+		}
+	}
+	return nil, false
+}
+
+// Rank returns the 0-based position of key in sorted order, false if absent.
+// Select(Rank(key)) == key for every present key.
+func (cmt *CartesianMerkleTree) Rank(key []byte) (int, bool) {
+	if cmt == nil {
+		return 0, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	key = cmt.treeKey(key)
+	rank := 0
+	node := cmt.Root
+	for node != nil {
+		cmp := cmt.compareKeys(key, node.Key)
+		if cmp < 0 {
+			node = node.Left
+			continue
+		}
+		if node.Left != nil {
+			rank += node.Left.Size
+		}
+		if cmp == 0 {
+			return rank, true
+		}
+		rank++
+		node = node.Right
+	}
+	return 0, false
+}
+
+// ContainsExactly reports whether the tree holds exactly the given keys.
+// missing lists the given keys that are absent, extra the tree keys that were
+// not given, both in ascending order. Duplicates in keys are ignored.
+func (cmt *CartesianMerkleTree) ContainsExactly(keys [][]byte) (exact bool, missing, extra [][]byte) {
+	want := make([][]byte, len(keys))
+	for i, key := range keys {
+		want[i] = cmt.treeKey(key)
+	}
+	sort.Slice(want, func(i, j int) bool { return cmt.compareKeys(want[i], want[j]) < 0 })
+
+	var have [][]byte
+	if cmt != nil {
+		cmt.mu.RLock()
+		inOrder(cmt.Root, func(node *TreapNode) bool {
+			have = append(have, node.Key)
+			return true
+		})
+		cmt.mu.RUnlock()
+	}
+
+	i, j := 0, 0
+	for i < len(want) || j < len(have) {
+		if i > 0 && i < len(want) && cmt.compareKeys(want[i], want[i-1]) == 0 {
+			i++
+			continue
+		}
+		cmp := 0
+		switch {
+		case i == len(want):
+			cmp = 1
+		case j == len(have):
+			cmp = -1
+		default:
+			cmp = cmt.compareKeys(want[i], have[j])
+		}
+		switch {
+		case cmp < 0:
+			missing = append(missing, want[i])
+			i++
+		case cmp > 0:
+			extra = append(extra, have[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return len(missing) == 0 && len(extra) == 0, missing, extra
+}
+
+// ContainsAll reports whether every given key is in the tree. missing lists
+// the absent ones (as tree keys) in ascending order, duplicates ignored. The
+// keys are sorted and the tree is descended once for all of them: each node
+// splits the remaining keys between its subtrees, so shared upper paths are
+// walked once and subtrees no key falls into are skipped.
+func (cmt *CartesianMerkleTree) ContainsAll(keys [][]byte) (all bool, missing [][]byte) {
+	want := make([][]byte, len(keys))
+	for i, key := range keys {
+		want[i] = cmt.treeKey(key)
+	}
+	sort.Slice(want, func(i, j int) bool { return cmt.compareKeys(want[i], want[j]) < 0 })
+
+	var root *TreapNode
+	if cmt != nil {
+		cmt.mu.RLock()
+		defer cmt.mu.RUnlock()
+		root = cmt.Root
+	}
+	var walk func(node *TreapNode, want [][]byte)
+	walk = func(node *TreapNode, want [][]byte) {
+		if len(want) == 0 {
+			return
+		}
+		if node == nil {
+			for i, key := range want {
+				if i == 0 || cmt.compareKeys(key, want[i-1]) != 0 {
+					missing = append(missing, key)
+				}
+			}
+			return
+		}
+		lo := sort.Search(len(want), func(i int) bool { return cmt.compareKeys(want[i], node.Key) >= 0 })
+		hi := lo
+		for hi < len(want) && cmt.compareKeys(want[hi], node.Key) == 0 {
+			hi++
+		}
+		walk(node.Left, want[:lo])
+		walk(node.Right, want[hi:])
+	}
+	walk(root, want)
+	return len(missing) == 0, missing
+}
+
+// Height returns the number of nodes on the longest root-to-leaf path, 0 if empty
+func (cmt *CartesianMerkleTree) Height() int {
+	if cmt == nil {
+		return 0
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return height(cmt.Root)
+}
+
+func height(node *TreapNode) int {
+	if node == nil {
+		return 0
+	}
+	left, right := height(node.Left), height(node.Right)
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// BalanceFactor returns Height divided by the height of a perfectly balanced
+// tree of the same size, log2(n+1): 1 is optimal, a random treap stays
+// around 2-3. 0 for an empty tree.
+//
+// Removals don't degrade it: a treap's shape depends only on its keys and
+// their priorities (and weights), so after any sequence of Remove calls the
+// tree is identical, root included, to one built from the remaining keys.
+// Re-deriving priorities on removal would therefore buy no balance and only
+// break that equivalence with the contract.
+func (cmt *CartesianMerkleTree) BalanceFactor() float64 {
+	n := cmt.Size()
+	if n == 0 {
+		return 0
+	}
+	return float64(cmt.Height()) / math.Log2(float64(n+1))
+}
+
+// PriorityCollisions returns, in ascending order, every key whose priority is
+// shared with another key: a sign of a weak PriorityFunc. Ties are broken by
+// key (see outranks), so the shape stays unique, but it stops being the one a
+// verifier breaking ties differently would rebuild. nil if there are none.
+func (cmt *CartesianMerkleTree) PriorityCollisions() [][]byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	byPriority := make(map[string][][]byte)
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		p := string(node.Priority)
+		byPriority[p] = append(byPriority[p], node.Key)
+		return true
+	})
+	var out [][]byte
+	for _, keys := range byPriority {
+		if len(keys) > 1 {
+			out = append(out, keys...)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return cmt.compareKeys(out[i], out[j]) < 0 })
+	return out
+}
+
+// LCA returns the key of the lowest common ancestor of a and b, the node where
+// their search paths diverge (a itself if a is an ancestor of b, and for a == b).
+// found is false if either key is absent.
+func (cmt *CartesianMerkleTree) LCA(a, b []byte) (key []byte, found bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	a, b = cmt.treeKey(a), cmt.treeKey(b)
+	if cmt.find(a) == nil || cmt.find(b) == nil {
+		return nil, false
+	}
+	node := cmt.Root
+	for {
+		ca, cb := cmt.compareKeys(a, node.Key), cmt.compareKeys(b, node.Key)
+		switch {
+		case ca < 0 && cb < 0:
+			node = node.Left
+		case ca > 0 && cb > 0:
+			node = node.Right
+		default:
+			return node.Key, true
+		}
+	}
+}
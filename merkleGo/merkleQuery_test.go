@@ -0,0 +1,289 @@
+package merkleGo
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Removing a skewed sequence, the highest priorities first or a contiguous
+// key range, leaves the same shape a rebuild from the survivors would have,
+// which is all a rebalancing pass after Remove could produce
+func TestRemovalKeepsBalance(t *testing.T) {
+	skews := map[string]func(cmt *CartesianMerkleTree) []byte{
+		"root": func(cmt *CartesianMerkleTree) []byte { return cmt.Root.Key },
+		"smallest": func(cmt *CartesianMerkleTree) []byte {
+			keys := cmt.Keys()
+			sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+			return keys[0]
+		},
+	}
+	for name, next := range skews {
+		t.Run(name, func(t *testing.T) {
+			cmt := buildTree(t, strKeys(2000))
+			for cmt.Size() > 500 {
+				if err := cmt.Remove(next(cmt)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rebuilt := buildTree(t, cmt.Keys())
+			without, with := cmt.BalanceFactor(), rebuilt.BalanceFactor()
+			t.Logf("balance factor %.2f after removals, %.2f rebuilt", without, with)
+			if without != with || !bytes.Equal(cmt.GetRoot(), rebuilt.GetRoot()) {
+				t.Fatalf("removals left balance %.2f and root %x, a rebuild has %.2f and %x",
+					without, cmt.GetRoot(), with, rebuilt.GetRoot())
+			}
+			if without > 4 {
+				t.Fatalf("balance factor %.2f after removals", without)
+			}
+		})
+	}
+}
+
+func TestHeight(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	if cmt.Height() != 0 || cmt.BalanceFactor() != 0 {
+		t.Fatalf("empty tree: height %d, balance %.2f", cmt.Height(), cmt.BalanceFactor())
+	}
+	fillTree(t, cmt, strKeys(1))
+	if cmt.Height() != 1 || cmt.BalanceFactor() != 1 {
+		t.Fatalf("one key: height %d, balance %.2f", cmt.Height(), cmt.BalanceFactor())
+	}
+}
+
+func TestSelectRank(t *testing.T) {
+	keys := make([][]byte, 300)
+	for i := range keys {
+		keys[i] = uintKey(int64(3 * i)) // big-endian, so already sorted
+	}
+	cmt := buildTree(t, keys)
+	check := func(want [][]byte) {
+		t.Helper()
+		mustValidate(t, cmt)
+		for i, key := range want {
+			got, ok := cmt.Select(i)
+			if !ok || !bytes.Equal(got, key) {
+				t.Fatalf("Select(%d) = %x, want %x", i, got, key)
+			}
+			if rank, ok := cmt.Rank(key); !ok || rank != i {
+				t.Fatalf("Rank(%x) = %d, %v, want %d", key, rank, ok, i)
+			}
+		}
+		if _, ok := cmt.Select(len(want)); ok {
+			t.Fatal("Select past the end")
+		}
+		if _, ok := cmt.Select(-1); ok {
+			t.Fatal("Select(-1)")
+		}
+		if _, ok := cmt.Rank(uintKey(1)); ok {
+			t.Fatal("Rank of an absent key")
+		}
+	}
+	check(keys)
+
+	// removals rotate the removed keys down, every other key stays ranked
+	var kept [][]byte
+	for i, key := range keys {
+		if i%3 == 0 {
+			if err := cmt.Remove(key); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			kept = append(kept, key)
+		}
+	}
+	check(kept)
+}
+
+func TestGetEmptyValue(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for _, value := range [][]byte{{}, nil} {
+		key := []byte("empty")
+		if _, err := cmt.AddKV(key, value); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := cmt.Get(key); !ok || got == nil || len(got) != 0 {
+			t.Fatalf("AddKV(%v): Get gives %x, %v", value, got, ok)
+		}
+		proof, _ := cmt.GenerateProof(key)
+		if !cmt.VerifyProof(key, proof) || proof.Value == nil {
+			t.Fatalf("AddKV(%v): proof doesn't commit to the empty value", value)
+		}
+		if err := cmt.Remove(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, ok := cmt.Get([]byte("absent")); ok || got != nil {
+		t.Fatalf("absent key: %x, %v", got, ok)
+	}
+
+	// an empty value and no value are different commitments
+	keyOnly := buildTree(t, [][]byte{[]byte("k")})
+	if _, err := cmt.AddKV([]byte("k"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(keyOnly.GetRoot(), cmt.GetRoot()) {
+		t.Fatal("an empty value commits like a key-only node")
+	}
+}
+
+func TestContainsExactly(t *testing.T) {
+	keys := strKeys(10)
+	cmt := buildTree(t, keys)
+	str := func(keys [][]byte) []string {
+		out := []string{}
+		for _, key := range keys {
+			out = append(out, string(key))
+		}
+		return out
+	}
+	cases := []struct {
+		name           string
+		given          [][]byte
+		missing, extra []string
+	}{
+		{"exact", reversed(keys), []string{}, []string{}},
+		{"duplicates", append(append([][]byte{}, keys...), keys[3], keys[3]), []string{}, []string{}},
+		{"missing", append(append([][]byte{}, keys...), []byte("zz"), []byte("aa")), []string{"aa", "zz"}, []string{}},
+		{"extra", keys[2:], []string{}, []string{"key-0", "key-1"}},
+		{"both", append(append([][]byte{}, keys[1:]...), []byte("x")), []string{"x"}, []string{"key-0"}},
+	}
+	for _, c := range cases {
+		exact, missing, extra := cmt.ContainsExactly(c.given)
+		if exact != (len(c.missing)+len(c.extra) == 0) ||
+			!reflect.DeepEqual(str(missing), c.missing) || !reflect.DeepEqual(str(extra), c.extra) {
+			t.Errorf("%s: exact %v, missing %q, extra %q", c.name, exact, missing, extra)
+		}
+	}
+	if exact, _, _ := NewCartesianMerkleTree().ContainsExactly(nil); !exact {
+		t.Error("empty tree doesn't hold exactly no keys")
+	}
+}
+
+// perfectTree returns keys 1..7 shaped as a perfect tree: 4 at the root,
+// 2 and 6 below it, the odd keys as leaves
+func perfectTree(t *testing.T) *CartesianMerkleTree {
+	t.Helper()
+	levels := map[int64]byte{4: 3, 2: 2, 6: 2}
+	cmt := NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte {
+		return []byte{levels[BigIntFromKey(key).Int64()]}
+	}
+	for k := int64(1); k <= 7; k++ {
+		if _, err := cmt.Add(uintKey(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cmt.Height() != 3 || !bytes.Equal(cmt.Root.Key, uintKey(4)) {
+		t.Fatal("tree isn't the perfect one")
+	}
+	return cmt
+}
+
+func TestLCA(t *testing.T) {
+	cmt := perfectTree(t)
+	cases := []struct{ a, b, want int64 }{
+		{1, 3, 2}, // siblings
+		{5, 7, 6},
+		{1, 7, 4}, // across the root
+		{3, 5, 4},
+		{2, 3, 2}, // ancestor and descendant
+		{7, 4, 4},
+		{5, 5, 5}, // same key twice
+	}
+	for _, c := range cases {
+		got, ok := cmt.LCA(uintKey(c.a), uintKey(c.b))
+		if !ok || !bytes.Equal(got, uintKey(c.want)) {
+			t.Errorf("LCA(%d, %d) = %x, %v, want %d", c.a, c.b, got, ok, c.want)
+		}
+	}
+	if _, ok := cmt.LCA(uintKey(1), uintKey(8)); ok {
+		t.Error("LCA with an absent key")
+	}
+}
+
+func TestKeysByInsertionOrder(t *testing.T) {
+	keys := strKeys(50)
+	rand.New(rand.NewSource(1)).Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	cmt := NewCartesianMerkleTree()
+	cmt.TrackInsertionOrder = true
+	fillTree(t, cmt, keys)
+
+	if got := cmt.KeysByInsertionOrder(); !reflect.DeepEqual(got, keys) {
+		t.Fatalf("KeysByInsertionOrder = %q, want %q", got, keys)
+	}
+	sorted := cmt.Keys()
+	if !sort.SliceIsSorted(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 }) || len(sorted) != len(keys) {
+		t.Fatalf("Keys not sorted: %q", sorted)
+	}
+	// the sequence numbers stay out of the hash
+	if !bytes.Equal(cmt.GetRoot(), buildTree(t, keys).GetRoot()) {
+		t.Fatal("tracking insertion order changed the root")
+	}
+	if got := buildTree(t, keys).KeysByInsertionOrder(); got != nil {
+		t.Fatalf("untracked tree: %q", got)
+	}
+}
+
+func TestPriorityCollisions(t *testing.T) {
+	if keys := buildTree(t, strKeys(100)).PriorityCollisions(); keys != nil {
+		t.Fatalf("sha256 priorities collide: %q", keys)
+	}
+
+	// the first byte as priority: x1 and x2 collide, so do the z keys
+	cmt := NewCartesianMerkleTree()
+	cmt.PriorityFunc = func(key []byte) []byte { return key[:1] }
+	fillTree(t, cmt, [][]byte{[]byte("z3"), []byte("x2"), []byte("y1"), []byte("z1"), []byte("x1"), []byte("z2")})
+	mustValidate(t, cmt)
+	want := [][]byte{[]byte("x1"), []byte("x2"), []byte("z1"), []byte("z2"), []byte("z3")}
+	if got := cmt.PriorityCollisions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("PriorityCollisions = %q, want %q", got, want)
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	keys := strKeys(300)
+	cmt := buildTree(t, keys[:200])
+	query := append(append([][]byte{}, keys[150:250]...), keys[160], keys[240])
+	rand.New(rand.NewSource(2)).Shuffle(len(query), func(i, j int) { query[i], query[j] = query[j], query[i] })
+
+	// keys[240] is queried twice but reported once
+	want := keys[200:250]
+	all, missing := cmt.ContainsAll(query)
+	if all || !reflect.DeepEqual(missing, want) {
+		t.Fatalf("ContainsAll: %v, missing %q, want %q", all, missing, want)
+	}
+
+	if all, missing := cmt.ContainsAll(keys[:200]); !all || missing != nil {
+		t.Fatalf("present keys: %v, missing %q", all, missing)
+	}
+	if all, _ := cmt.ContainsAll(nil); !all {
+		t.Fatal("no keys aren't all contained")
+	}
+	if all, missing := NewCartesianMerkleTree().ContainsAll(keys[:2]); all || len(missing) != 2 {
+		t.Fatalf("empty tree: %v, missing %q", all, missing)
+	}
+}
+
+// 1000 of 10k keys, as one shared descent and as a loop of Contains
+func BenchmarkContainsAll(b *testing.B) {
+	keys := strKeys(10000)
+	cmt := buildTree(b, keys)
+	query := keys[4000:5000]
+	b.Run("shared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmt.ContainsAll(query)
+		}
+	})
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, key := range query {
+				if !cmt.Contains(key) {
+					b.Fatal("missing key")
+				}
+			}
+		}
+	})
+}
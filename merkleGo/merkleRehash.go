@@ -0,0 +1,81 @@
+package merkleGo
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Subtrees smaller than this are always rehashed on the calling goroutine,
+// below it the goroutine hand-off costs more than the hashing
+const parallelRehashThreshold = 1024
+
+// RecomputeHashes rebuilds every Size and MerkleHash bottom-up (post-order)
+// from the keys, values and shape, and returns the new root.
+func (cmt *CartesianMerkleTree) RecomputeHashes() []byte {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+	cmt.rehash(cmt.Root)
+	return cmt.rootHash()
+}
+
+// RecomputeHashesParallel is RecomputeHashes with independent subtrees rehashed
+// concurrently by at most workers goroutines (GOMAXPROCS if workers <= 0).
+// The root is identical to the sequential version.
+func (cmt *CartesianMerkleTree) RecomputeHashesParallel(workers int) []byte {
+	if cmt == nil {
+		return nil
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+	// the calling goroutine is one of the workers
+	slots := make(chan struct{}, workers-1)
+	cmt.rehashParallel(cmt.Root, slots)
+	return cmt.rootHash()
+}
+
+func (cmt *CartesianMerkleTree) rehash(node *TreapNode) {
+	if node == nil {
+		return
+	}
+	cmt.rehash(node.Left)
+	cmt.rehash(node.Right)
+	node.Size = subtreeSize(node)
+	node.MerkleHash = cmt.computeMerkleHash(node)
+}
+
+func (cmt *CartesianMerkleTree) rehashParallel(node *TreapNode, slots chan struct{}) {
+	if node == nil {
+		return
+	}
+	// Size may be stale here, it only steers the scheduling
+	if node.Size < parallelRehashThreshold {
+		cmt.rehash(node)
+		return
+	}
+	select {
+	case slots <- struct{}{}:
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			cmt.rehashParallel(node.Left, slots)
+		}()
+		cmt.rehashParallel(node.Right, slots)
+		wg.Wait()
+	default:
+		// no free worker, keep going on this one
+		cmt.rehashParallel(node.Left, slots)
+		cmt.rehashParallel(node.Right, slots)
+	}
+	node.Size = subtreeSize(node)
+	node.MerkleHash = cmt.computeMerkleHash(node)
+}
@@ -0,0 +1,53 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+// scramble overwrites every cached hash, as a corrupt or partial load would
+func scramble(node *TreapNode) {
+	if node == nil {
+		return
+	}
+	scramble(node.Left)
+	scramble(node.Right)
+	node.MerkleHash = []byte("stale")
+}
+
+func TestRecomputeHashesParallel(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(20000))
+		want := cmt.GetRoot()
+		for _, workers := range []int{0, 1, 2, 8} {
+			scramble(cmt.Root)
+			if got := cmt.RecomputeHashesParallel(workers); !bytes.Equal(got, want) {
+				t.Fatalf("CommitSize %v, %d workers: root %x, want %x", commitSize, workers, got, want)
+			}
+			mustValidate(t, cmt)
+		}
+		scramble(cmt.Root)
+		if got := cmt.RecomputeHashes(); !bytes.Equal(got, want) {
+			t.Fatalf("CommitSize %v: sequential root %x, want %x", commitSize, got, want)
+		}
+	}
+	if got := NewCartesianMerkleTree().RecomputeHashesParallel(4); got != nil {
+		t.Fatalf("empty tree rehashed to %x", got)
+	}
+}
+
+func BenchmarkRecomputeHashes(b *testing.B) {
+	cmt := buildTree(b, strKeys(200000))
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmt.RecomputeHashes()
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cmt.RecomputeHashesParallel(0)
+		}
+	})
+}
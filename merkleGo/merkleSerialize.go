@@ -0,0 +1,90 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// treeJSON is the JSON form of a tree: its entries in key order plus the root
+// they must rebuild to. Shapes are deterministic, so re-inserting the entries
+// (with their weights) into a tree with the same configuration reproduces it.
+type treeJSON struct {
+	Root    string      `json:"root"`
+	Entries []entryJSON `json:"entries"`
+}
+
+type entryJSON struct {
+	Key    string  `json:"key"`
+	Value  *string `json:"value,omitempty"` // absent for key-only nodes
+	Weight uint64  `json:"weight,omitempty"`
+}
+
+// MarshalJSON serializes every entry (hex encoded) and the root.
+// Keys hashed by MaxKeyLength are written as originally inserted.
+func (cmt *CartesianMerkleTree) MarshalJSON() ([]byte, error) {
+	out := treeJSON{Entries: []entryJSON{}}
+	if cmt != nil {
+		cmt.mu.RLock()
+		out.Root = hex.EncodeToString(cmt.rootHash())
+		inOrder(cmt.Root, func(node *TreapNode) bool {
+			entry := entryJSON{Key: hex.EncodeToString(cmt.originalKey(node.Key)), Weight: node.Weight}
+			if node.Value != nil {
+				value := hex.EncodeToString(node.Value)
+				entry.Value = &value
+			}
+			out.Entries = append(out.Entries, entry)
+			return true
+		})
+		cmt.mu.RUnlock()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON rebuilds the tree from MarshalJSON output and replaces the
+// current content in one step, only if every entry decodes and the rebuilt
+// root matches the serialized one. On error the tree is left untouched.
+func (cmt *CartesianMerkleTree) UnmarshalJSON(data []byte) error {
+	if cmt == nil {
+		return ErrNilTree
+	}
+	var in treeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	fresh := cmt.emptyLike()
+	for i, entry := range in.Entries {
+		key, err := hex.DecodeString(entry.Key)
+		if err != nil {
+			return fmt.Errorf("entry %d: key: %w", i, err)
+		}
+		var value []byte
+		if entry.Value != nil {
+			if value, err = hex.DecodeString(*entry.Value); err != nil {
+				return fmt.Errorf("entry %d: value: %w", i, err)
+			}
+			if value == nil {
+				value = []byte{}
+			}
+		}
+		inserted, err := fresh.AddWeighted(key, value, entry.Weight)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		if !inserted {
+			return fmt.Errorf("entry %d: key %s listed twice", i, entry.Key)
+		}
+	}
+	if got := hex.EncodeToString(fresh.GetRoot()); got != in.Root {
+		return fmt.Errorf("rebuilt root %s does not match serialized root %s", got, in.Root)
+	}
+
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+	cmt.Root = fresh.Root
+	cmt.seq = fresh.seq
+	cmt.longKeys = fresh.longKeys
+	cmt.logOp(opReplace, nil, cmt.rootHash(), 0)
+	return nil
+}
@@ -0,0 +1,46 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONRoundTripKeepsWeights(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(20) {
+		if _, err := cmt.AddWeighted(key, []byte{byte(i)}, uint64(i%3)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := json.Marshal(cmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := NewCartesianMerkleTree()
+	if err := json.Unmarshal(data, back); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back.GetRoot(), cmt.GetRoot()) {
+		t.Fatal("round trip changed the root")
+	}
+}
+
+func TestUnmarshalJSONRejectsDuplicateEntries(t *testing.T) {
+	data, _ := json.Marshal(buildTree(t, strKeys(3)))
+	var in treeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		t.Fatal(err)
+	}
+	in.Entries = append(in.Entries, in.Entries[0])
+	data, _ = json.Marshal(in)
+
+	cmt := buildTree(t, strKeys(1))
+	root := cmt.GetRoot()
+	if err := json.Unmarshal(data, cmt); err == nil {
+		t.Fatal("imported a key listed twice")
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) {
+		t.Fatal("a rejected import changed the tree")
+	}
+}
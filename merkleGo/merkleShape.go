@@ -0,0 +1,112 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidShape is returned by VerifyProofShape when the proof's path is not
+// the one the agreed priority function builds
+var ErrInvalidShape = errors.New("proof path violates the treap shape")
+
+// PathNode is a path node as carried by Proof.Path
+type PathNode struct {
+	Key      []byte
+	Value    []byte // as committed: after ValueHashFunc, nil for key-only nodes
+	Priority []byte
+	Weight   uint64
+}
+
+func (cmt *CartesianMerkleTree) pathNode(node *TreapNode) PathNode {
+	value := node.Value
+	if value != nil && cmt.ValueHashFunc != nil {
+		value = cmt.ValueHashFunc(value)
+	}
+	return PathNode{Key: node.Key, Value: value, Priority: node.Priority, Weight: node.Weight}
+}
+
+// VerifyProofShape checks, without the tree, that a TopDown proof's path is
+// shaped like the deterministic treap: every Path node is bound to its sibling
+// entry (the last node, whose pair holds its children, to Key or
+// NonExistenceKey), its priority is priorityFunc(key) (nil means sha256(key),
+// the default), every node outranks
+// its child on the path (weight, then priority, then the smaller key), and the
+// keys below each node lie on the side the proven key does. It catches a
+// server that built the tree with wrong priorities; it does not check the
+// root, so pair it with VerifyProofAgainstRoot.
+func VerifyProofShape(proof *Proof, priorityFunc func(key []byte) []byte) error {
+	return verifyShape(proof, priorityFunc, bytes.Compare)
+}
+
+// VerifyProofShape checks proof's path against the tree's PriorityFunc and key
+// order, see the VerifyProofShape function. The proof must come from a tree
+// with ProvePriorities; its siblings are taken in the tree's SiblingOrder.
+func (cmt *CartesianMerkleTree) VerifyProofShape(proof *Proof) error {
+	if cmt == nil {
+		return ErrNilTree
+	}
+	return verifyShape(ReorderSiblings(proof, cmt.SiblingOrder, TopDown), cmt.PriorityFunc, cmt.compareKeys)
+}
+
+func verifyShape(proof *Proof, priorityFunc func(key []byte) []byte, compare func(a, b []byte) int) error {
+	if proof == nil {
+		return fmt.Errorf("%w: nil proof", ErrMalformedProof)
+	}
+	if len(proof.Siblings)%2 != 0 {
+		return fmt.Errorf("%w: %d siblings", ErrMalformedProof, len(proof.Siblings))
+	}
+	if priorityFunc == nil {
+		priorityFunc = func(key []byte) []byte {
+			sum := sha256.Sum256(key)
+			return sum[:]
+		}
+	}
+	n := len(proof.Siblings) / 2
+	if len(proof.Path) != n {
+		return fmt.Errorf("%w: %d path nodes for %d sibling pairs", ErrMalformedProof, len(proof.Path), n)
+	}
+	// the last node's pair holds its children, not its entry
+	ancestors := n - 1
+
+	for i, node := range proof.Path {
+		if len(node.Key) == 0 {
+			return fmt.Errorf("%w: path node %d has no key", ErrMalformedProof, i)
+		}
+		if i < ancestors {
+			if !bytes.Equal(nodeEntry(node.Key, node.Value), proof.Siblings[2*i]) {
+				return fmt.Errorf("%w: path node %d doesn't match its sibling", ErrInvalidShape, i)
+			}
+		} else if proof.Existence && compare(node.Key, proof.Key) != 0 {
+			return fmt.Errorf("%w: last path node %x is not the proven key", ErrInvalidShape, node.Key)
+		}
+		if !bytes.Equal(priorityFunc(node.Key), node.Priority) {
+			return fmt.Errorf("%w: priority of %x is not the agreed one", ErrInvalidShape, node.Key)
+		}
+		if i > 0 {
+			parent := &TreapNode{Key: proof.Path[i-1].Key, Priority: proof.Path[i-1].Priority, Weight: proof.Path[i-1].Weight}
+			child := &TreapNode{Key: node.Key, Priority: node.Priority, Weight: node.Weight}
+			if !outranks(parent, child) {
+				return fmt.Errorf("%w: %x sits below %x, which it outranks", ErrInvalidShape, node.Key, parent.Key)
+			}
+		}
+	}
+	if !proof.Existence && n > 0 && !bytes.Equal(proof.Path[n-1].Key, proof.NonExistenceKey) {
+		return fmt.Errorf("%w: last path node %x is not the non-existence key", ErrInvalidShape, proof.Path[n-1].Key)
+	}
+
+	// the search for Key goes to one side of each ancestor, so must the rest of the path
+	for i := 0; i < ancestors; i++ {
+		side := compare(proof.Key, proof.Path[i].Key)
+		if side == 0 {
+			return fmt.Errorf("%w: proven key %x appears among its ancestors", ErrInvalidShape, proof.Key)
+		}
+		for _, below := range proof.Path[i+1:] {
+			if (compare(below.Key, proof.Path[i].Key) < 0) != (side < 0) {
+				return fmt.Errorf("%w: %x is on the wrong side of %x", ErrInvalidShape, below.Key, proof.Path[i].Key)
+			}
+		}
+	}
+	return nil
+}
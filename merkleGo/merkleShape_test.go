@@ -0,0 +1,64 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestVerifyProofShape(t *testing.T) {
+	keys := strKeys(40)
+	honest := NewCartesianMerkleTree()
+	honest.ProvePriorities = true
+	fillTree(t, honest, keys)
+	for _, key := range keys {
+		proof, _ := honest.GenerateProof(key)
+		if err := VerifyProofShape(proof, nil); err != nil {
+			t.Fatalf("%s: honest proof: %v", key, err)
+		}
+		if err := honest.VerifyProofShape(proof); err != nil {
+			t.Fatalf("%s: honest proof against the tree: %v", key, err)
+		}
+	}
+
+	// a server building with other priorities serves proofs that match its own
+	// root, but their shape gives it away
+	tampered := NewCartesianMerkleTree()
+	tampered.ProvePriorities = true
+	tampered.PriorityFunc = func(key []byte) []byte {
+		sum := sha256.Sum256(append([]byte("other"), key...))
+		return sum[:]
+	}
+	fillTree(t, tampered, keys)
+	caught, relabelled := 0, 0
+	for _, key := range keys {
+		proof, _ := tampered.GenerateProof(key)
+		if !VerifyProofAgainstRoot(key, proof, tampered.GetRoot(), nil) {
+			t.Fatalf("%s: tampered tree's proof doesn't match its root", key)
+		}
+		if err := VerifyProofShape(proof, nil); errors.Is(err, ErrInvalidShape) {
+			caught++
+		} else if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+
+		// claiming the agreed priorities doesn't help: the heap order breaks,
+		// though a single path may happen to stay ordered
+		lying := *proof
+		lying.Path = append([]PathNode{}, proof.Path...)
+		for i := range lying.Path {
+			sum := sha256.Sum256(lying.Path[i].Key)
+			lying.Path[i].Priority = sum[:]
+		}
+		if errors.Is(VerifyProofShape(&lying, nil), ErrInvalidShape) {
+			relabelled++
+		}
+	}
+	if caught != len(keys) || relabelled == 0 {
+		t.Fatalf("%d of %d tampered proofs caught, %d relabelled ones", caught, len(keys), relabelled)
+	}
+
+	if err := VerifyProofShape(&Proof{Existence: true, Key: keys[0], Siblings: [][]byte{{1}, {2}}}, nil); err == nil {
+		t.Fatal("proof without a path accepted")
+	}
+}
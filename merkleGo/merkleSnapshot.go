@@ -0,0 +1,175 @@
+package merkleGo
+
+import (
+	"time"
+)
+
+// TreeSnapshot is a frozen copy of a tree at one version, for serving
+// historical proofs. See Snapshot and SetSnapshotPolicy.
+type TreeSnapshot struct {
+	Version int
+	Root    []byte
+	Taken   time.Time
+	tree    *CartesianMerkleTree
+}
+
+// Tree returns the frozen copy, with the configuration of the original tree.
+// It is independent of the live tree but shared by every caller: don't modify it.
+func (s *TreeSnapshot) Tree() *CartesianMerkleTree {
+	return s.tree
+}
+
+// GenerateProof returns the proof of key as of the snapshot
+func (s *TreeSnapshot) GenerateProof(key []byte) (*Proof, error) {
+	return s.tree.GenerateProof(key)
+}
+
+// snapshotPolicy triggers snapshots on root changes, see SetSnapshotPolicy
+type snapshotPolicy struct {
+	everyN  int
+	everyT  time.Duration
+	keep    int
+	changes int       // root changes since the last snapshot
+	since   time.Time // last snapshot, or when the policy was set
+}
+
+// SetSnapshotPolicy takes a snapshot automatically once everyN root changes
+// happened since the previous one, or on the first root change once everyT
+// elapsed since it, or since the policy was set (an unchanged tree needs no
+// new snapshot). A zero everyN or
+// everyT disables that trigger. Only the keep most recent snapshots are retained,
+// keep <= 0 keeps them all. Time is read from Clock.
+func (cmt *CartesianMerkleTree) SetSnapshotPolicy(everyN int, everyT time.Duration, keep int) {
+	if cmt == nil {
+		return
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	cmt.snapshotPolicy = snapshotPolicy{everyN: everyN, everyT: everyT, keep: keep, since: cmt.now()}
+	cmt.pruneSnapshots()
+}
+
+// Snapshot records a frozen copy of the current tree and returns it
+func (cmt *CartesianMerkleTree) Snapshot() *TreeSnapshot {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	return cmt.snapshot()
+}
+
+// Snapshots returns the retained snapshots, oldest first
+func (cmt *CartesianMerkleTree) Snapshots() []*TreeSnapshot {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return append([]*TreeSnapshot(nil), cmt.snapshots...)
+}
+
+// SnapshotAt returns the retained snapshot taken at version, see Version
+func (cmt *CartesianMerkleTree) SnapshotAt(version int) (*TreeSnapshot, bool) {
+	if cmt == nil {
+		return nil, false
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	for i := len(cmt.snapshots) - 1; i >= 0; i-- {
+		if cmt.snapshots[i].Version == version {
+			return cmt.snapshots[i], true
+		}
+	}
+	return nil, false
+}
+
+func (cmt *CartesianMerkleTree) now() time.Time {
+	if cmt.Clock != nil {
+		return cmt.Clock()
+	}
+	return time.Now()
+}
+
+// Clone returns an independent copy of the tree, configuration included, taken
+// under the read lock. Unlike Snapshot it is not retained by the tree, so it
+// suits one-off long reads (e.g. streaming every proof) that must not hold the
+// lock: the copy can be walked at leisure while writers go on.
+func (cmt *CartesianMerkleTree) Clone() *CartesianMerkleTree {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	return cmt.frozenCopy()
+}
+
+// frozenCopy copies the tree; nodes are updated in place (and recycled once
+// removed), so the copy must not share any node with the live tree
+func (cmt *CartesianMerkleTree) frozenCopy() *CartesianMerkleTree {
+	frozen := cmt.emptyLike()
+	frozen.Root = deepCopy(cmt.Root)
+	frozen.version = cmt.version
+	if len(cmt.longKeys) > 0 {
+		frozen.longKeys = make(map[string][]byte, len(cmt.longKeys))
+		for treeKey, key := range cmt.longKeys {
+			frozen.longKeys[treeKey] = key
+		}
+	}
+	return frozen
+}
+
+// snapshot records a frozen copy of the tree, see frozenCopy
+func (cmt *CartesianMerkleTree) snapshot() *TreeSnapshot {
+	frozen := cmt.frozenCopy()
+	s := &TreeSnapshot{Version: cmt.version, Root: cmt.rootHash(), Taken: cmt.now(), tree: frozen}
+	cmt.snapshots = append(cmt.snapshots, s)
+	cmt.snapshotPolicy.changes = 0
+	cmt.snapshotPolicy.since = s.Taken
+	cmt.pruneSnapshots()
+	return s
+}
+
+// onRootChange applies the snapshot policy, called by recordRoot
+func (cmt *CartesianMerkleTree) onRootChange() {
+	p := &cmt.snapshotPolicy
+	if p.everyN <= 0 && p.everyT <= 0 {
+		return
+	}
+	p.changes++
+	due := p.everyN > 0 && p.changes >= p.everyN
+	if !due && p.everyT > 0 {
+		due = cmt.now().Sub(p.since) >= p.everyT
+	}
+	if due {
+		cmt.snapshot()
+	}
+}
+
+func (cmt *CartesianMerkleTree) pruneSnapshots() {
+	keep := cmt.snapshotPolicy.keep
+	if keep > 0 && len(cmt.snapshots) > keep {
+		drop := len(cmt.snapshots) - keep
+		// clear the dropped entries so the copies can be collected
+		for i := 0; i < drop; i++ {
+			cmt.snapshots[i] = nil
+		}
+		cmt.snapshots = append(cmt.snapshots[:0], cmt.snapshots[drop:]...)
+	}
+}
+
+func deepCopy(node *TreapNode) *TreapNode {
+	if node == nil {
+		return nil
+	}
+	n := copyNode(node)
+	if node.Meta != nil {
+		n.Meta = make(map[string][]byte, len(node.Meta))
+		for field, value := range node.Meta {
+			n.Meta[field] = value
+		}
+	}
+	n.Left = deepCopy(node.Left)
+	n.Right = deepCopy(node.Right)
+	return n
+}
@@ -0,0 +1,93 @@
+package merkleGo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSnapshotPolicy(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cmt := NewCartesianMerkleTree()
+	cmt.Clock = func() time.Time { return now }
+	cmt.SetSnapshotPolicy(3, time.Minute, 2)
+	versions := func() []int {
+		var out []int
+		for _, s := range cmt.Snapshots() {
+			out = append(out, s.Version)
+		}
+		return out
+	}
+	add := func(key string) {
+		t.Helper()
+		if _, err := cmt.Add([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add("a")
+	add("b")
+	if len(cmt.Snapshots()) != 0 {
+		t.Fatal("snapshot before 3 changes")
+	}
+	add("c") // third change
+	add("c") // duplicate, no change
+	if got := fmt.Sprint(versions()); got != "[3]" {
+		t.Fatalf("after 3 changes: snapshots at %s", got)
+	}
+
+	now = now.Add(2 * time.Minute) // the clock alone takes no snapshot
+	if len(cmt.Snapshots()) != 1 {
+		t.Fatal("snapshot without a change")
+	}
+	add("d") // first change past the interval
+	if got := fmt.Sprint(versions()); got != "[3 4]" {
+		t.Fatalf("after the interval: snapshots at %s", got)
+	}
+	add("e")
+	add("f")
+	add("g") // third change since version 4, the oldest snapshot is pruned
+	if got := fmt.Sprint(versions()); got != "[4 7]" {
+		t.Fatalf("after pruning: snapshots at %s", got)
+	}
+
+	s, ok := cmt.SnapshotAt(4)
+	if !ok || !s.Taken.Equal(now) {
+		t.Fatalf("snapshot at 4: %v, taken %v", ok, s.Taken)
+	}
+	if err := cmt.Remove([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	proof, _ := s.GenerateProof([]byte("a"))
+	if !proof.Existence || !VerifyProofAgainstRoot([]byte("a"), proof, s.Root, nil) {
+		t.Fatal("snapshot lost a key removed from the live tree")
+	}
+	if bytes.Equal(s.Root, cmt.GetRoot()) {
+		t.Fatal("snapshot follows the live tree")
+	}
+}
+
+func TestClone(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.MaxKeyLength = 40
+	long := bytes.Repeat([]byte{'l'}, 100)
+	fillTree(t, cmt, append(strKeys(20), long))
+	clone := cmt.Clone()
+	if !bytes.Equal(clone.GetRoot(), cmt.GetRoot()) || clone.Version() != cmt.Version() || len(cmt.Snapshots()) != 0 {
+		t.Fatal("clone differs, or was retained as a snapshot")
+	}
+	if original, ok := clone.OriginalKey(clone.TreeKey(long)); !ok || !bytes.Equal(original, long) {
+		t.Fatal("clone lost the long key")
+	}
+
+	root := clone.GetRoot()
+	fillTree(t, cmt, [][]byte{[]byte("new")})
+	if err := cmt.Remove([]byte("key-3")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clone.GetRoot(), root) || clone.Contains([]byte("new")) || !clone.Contains([]byte("key-3")) {
+		t.Fatal("writes to the tree reached the clone")
+	}
+	mustValidate(t, clone)
+}
@@ -0,0 +1,36 @@
+package merkleGo
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+// PrioritySolidityV1 is the node priority of the CartesianMerkleTree.sol
+// library shipped with this repository (contracts/src), whose _newNode sets
+// priority = bytes16(keccak256(abi.encodePacked(key))): the first 16 bytes of
+// keccak256 over the raw 32-byte key. On-chain keys are bytes32, so pass keys
+// in that form (KeyFromBigInt); other lengths are hashed as given.
+//
+// It is the only contract version in this tree; later library versions that
+// change the derivation need their own preset.
+func PrioritySolidityV1(key []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(key)
+	return h.Sum(nil)[:16]
+}
+
+// NewCartesianMerkleTreeSolidityV1 returns a tree that reproduces the shapes and
+// roots of the contract PrioritySolidityV1 describes, used with its default
+// hasher: PrioritySolidityV1 priorities and keccak256(entry || lower child ||
+// higher child) node hashes over 32-byte keys. Proofs verify statelessly with
+// FactoryHasher(sha3.NewLegacyKeccak256, nil).
+func NewCartesianMerkleTreeSolidityV1() *CartesianMerkleTree {
+	return &CartesianMerkleTree{
+		PriorityFunc: PrioritySolidityV1,
+		HashFactory:  sha3.NewLegacyKeccak256,
+	}
+}
+
+func init() {
+	RegisterHashFunc("solidity-v1", PrioritySolidityV1)
+	RegisterHashFactory("keccak256", sha3.NewLegacyKeccak256)
+}
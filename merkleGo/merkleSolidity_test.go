@@ -0,0 +1,108 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// solidityFixture is testdata/solidity_v1.json: roots and proofs of the
+// contracts/src/CartesianMerkleTree.sol library (default hasher), pinned by
+// contracts/test/CartesianMerkleTreeFixtures.t.sol. Keys are uint256s.
+type solidityFixture struct {
+	Priorities map[int64]string
+	Steps      []struct {
+		Remove bool
+		Key    int64
+		Root   string
+	}
+	// Phases are the states after the adds and after the removals
+	Phases []struct {
+		Root   string
+		Proofs []struct {
+			Key             string
+			Existence       bool
+			NonExistenceKey string
+			Siblings        []string
+		}
+	}
+}
+
+func loadSolidityFixture(t *testing.T) solidityFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/solidity_v1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f solidityFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestPrioritySolidityV1MatchesContract(t *testing.T) {
+	for k, want := range loadSolidityFixture(t).Priorities {
+		if got := hex.EncodeToString(PrioritySolidityV1(uintKey(k))); got != want {
+			t.Errorf("priority of %d: %s, contract has %s", k, got, want)
+		}
+	}
+}
+
+func TestSolidityV1MatchesContract(t *testing.T) {
+	f := loadSolidityFixture(t)
+	cmt := NewCartesianMerkleTreeSolidityV1()
+	phase := 0
+	for i, step := range f.Steps {
+		if step.Remove && phase == 0 {
+			checkSolidityPhase(t, cmt, f, phase)
+			phase++
+		}
+		var err error
+		if step.Remove {
+			err = cmt.Remove(uintKey(step.Key))
+		} else {
+			_, err = cmt.Add(uintKey(step.Key))
+		}
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if got := hex.EncodeToString(cmt.GetRoot()); got != step.Root {
+			t.Fatalf("step %d (key %d, remove %v): root %s, contract has %s", i, step.Key, step.Remove, got, step.Root)
+		}
+	}
+	checkSolidityPhase(t, cmt, f, phase)
+}
+
+func checkSolidityPhase(t *testing.T, cmt *CartesianMerkleTree, f solidityFixture, phase int) {
+	t.Helper()
+	want := f.Phases[phase]
+	if got := hex.EncodeToString(cmt.GetRoot()); got != want.Root {
+		t.Fatalf("phase %d: root %s, contract has %s", phase, got, want.Root)
+	}
+	for _, wp := range want.Proofs {
+		key, _ := hex.DecodeString(wp.Key)
+		proof, err := cmt.GenerateProof(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Existence != wp.Existence || len(proof.Siblings) != len(wp.Siblings) {
+			t.Fatalf("phase %d, key %s: existence %v with %d siblings, contract has %v with %d",
+				phase, wp.Key, proof.Existence, len(proof.Siblings), wp.Existence, len(wp.Siblings))
+		}
+		for i, s := range proof.Siblings {
+			if hex.EncodeToString(s) != wp.Siblings[i] {
+				t.Fatalf("phase %d, key %s: sibling %d is %x, contract has %s", phase, wp.Key, i, s, wp.Siblings[i])
+			}
+		}
+		// the contract reports bytes32(0) where the Go proof has no key
+		nonExistenceKey := hex.EncodeToString(make([]byte, 32))
+		if proof.NonExistenceKey != nil {
+			nonExistenceKey = hex.EncodeToString(proof.NonExistenceKey)
+		}
+		if nonExistenceKey != wp.NonExistenceKey {
+			t.Fatalf("phase %d, key %s: non-existence key %s, contract has %s", phase, wp.Key, nonExistenceKey, wp.NonExistenceKey)
+		}
+	}
+}
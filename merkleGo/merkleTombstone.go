@@ -0,0 +1,72 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Tombstone is a portable record that Key was present under PreRoot and
+// was removed by the transition PreRoot -> PostRoot.
+// Digest binds the three together so the record can be stored or signed as one value.
+type Tombstone struct {
+	Key      []byte
+	Proof    *Proof // inclusion proof of Key against PreRoot
+	PreRoot  []byte
+	PostRoot []byte // nil when the removal emptied the tree
+	Digest   []byte
+}
+
+// RemoveWithTombstone removes key and returns a Tombstone proving it was there before.
+// The proof, the removal and both roots are taken under the same write lock.
+func (cmt *CartesianMerkleTree) RemoveWithTombstone(key []byte) (*Tombstone, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	if len(key) == 0 {
+		return nil, errors.New("key cannot be empty")
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+
+	proof := cmt.generateProof(key)
+	if !proof.Existence {
+		return nil, fmt.Errorf("key %x not found", key)
+	}
+	preRoot := cmt.rootHash()
+	if err := cmt.removeKey(key); err != nil {
+		return nil, err
+	}
+	t := &Tombstone{
+		Key:      proof.Key,
+		Proof:    proof,
+		PreRoot:  preRoot,
+		PostRoot: cmt.rootHash(),
+	}
+	t.Digest = t.digest()
+	return t, nil
+}
+
+// Verify checks the digest and the inclusion proof against PreRoot.
+// A nil hasher means the default 3-arg hasher.
+func (t *Tombstone) Verify(hasher func(a, b, c []byte) []byte) bool {
+	if t == nil || !bytes.Equal(t.Digest, t.digest()) {
+		return false
+	}
+	return VerifyProofAgainstRoot(t.Key, t.Proof, t.PreRoot, hasher)
+}
+
+// digest = sha256(len(key) || key || preRoot || postRoot)
+func (t *Tombstone) digest() []byte {
+	var keyLen [4]byte
+	binary.BigEndian.PutUint32(keyLen[:], uint32(len(t.Key)))
+	h := sha256.New()
+	h.Write(keyLen[:])
+	h.Write(t.Key)
+	h.Write(t.PreRoot)
+	h.Write(t.PostRoot)
+	return h.Sum(nil)
+}
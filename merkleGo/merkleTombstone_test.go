@@ -0,0 +1,55 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRemoveWithTombstone(t *testing.T) {
+	cmt := buildTree(t, strKeys(30))
+	for _, key := range strKeys(30) {
+		pre := cmt.GetRoot()
+		ts, err := cmt.RemoveWithTombstone(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ts.PreRoot, pre) || !bytes.Equal(ts.PostRoot, cmt.GetRoot()) || cmt.Contains(key) {
+			t.Fatalf("%s: tombstone roots %x -> %x, tree went %x -> %x", key, ts.PreRoot, ts.PostRoot, pre, cmt.GetRoot())
+		}
+		if !ts.Verify(nil) {
+			t.Fatalf("%s: tombstone doesn't verify against the pre-removal root", key)
+		}
+	}
+	if cmt.Size() != 0 {
+		t.Fatal("tree not emptied")
+	}
+	if _, err := cmt.RemoveWithTombstone([]byte("key-1")); err == nil {
+		t.Fatal("tombstone for an absent key")
+	}
+}
+
+func TestTombstoneRejectsTampering(t *testing.T) {
+	cmt := buildTree(t, strKeys(10))
+	ts, err := cmt.RemoveWithTombstone([]byte("key-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := buildTree(t, strKeys(11)).GetRoot()
+	tampered := map[string]func(ts *Tombstone){
+		"pre root":  func(ts *Tombstone) { ts.PreRoot = other },
+		"post root": func(ts *Tombstone) { ts.PostRoot = other },
+		"key":       func(ts *Tombstone) { ts.Key = []byte("key-4") },
+		"digest":    func(ts *Tombstone) { ts.Digest = other },
+	}
+	for name, tamper := range tampered {
+		copied := *ts
+		tamper(&copied)
+		if copied.Verify(nil) {
+			t.Errorf("%s: tampered tombstone verifies", name)
+		}
+	}
+	var none *Tombstone
+	if none.Verify(nil) {
+		t.Fatal("nil tombstone verifies")
+	}
+}
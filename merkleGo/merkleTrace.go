@@ -0,0 +1,39 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// RootHex returns the root hash hex encoded, "" for an empty tree
+func (cmt *CartesianMerkleTree) RootHex() string {
+	return hex.EncodeToString(cmt.GetRoot())
+}
+
+// ProofTrace returns, hex encoded, every hash VerifyProof recomputes for key
+// on its way up: the proven node's hash first, then each ancestor's, ending with
+// the root. Line it up against an on-chain trace to find the first divergence.
+func (cmt *CartesianMerkleTree) ProofTrace(key []byte) ([]string, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	proof := cmt.generateProof(key)
+	if !proof.Existence {
+		return nil, fmt.Errorf("key %x not found", key)
+	}
+	// The siblings from pair d onwards are exactly the proof of key inside the
+	// subtree of its d-th ancestor, so folding them yields that ancestor's hash.
+	depth := len(proof.Siblings) / 2
+	trace := make([]string, 0, depth)
+	for d := depth - 1; d >= 0; d-- {
+		sub := &Proof{Existence: true, Key: proof.Key, Value: proof.Value, Siblings: proof.Siblings[2*d:]}
+		if proof.Sizes != nil {
+			sub.Sizes = proof.Sizes[d:]
+		}
+		trace = append(trace, hex.EncodeToString(cmt.rebuildFromProof(proof.Key, sub)))
+	}
+	return trace, nil
+}
@@ -0,0 +1,34 @@
+package merkleGo
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestProofTrace(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(50))
+		for _, key := range strKeys(50) {
+			trace, err := cmt.ProofTrace(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			depth, _ := cmt.pathDepth(key)
+			if len(trace) != depth || trace[len(trace)-1] != cmt.RootHex() {
+				t.Fatalf("CommitSize %v, %s: %d entries ending %s, want %d ending %s",
+					commitSize, key, len(trace), trace[len(trace)-1], depth, cmt.RootHex())
+			}
+			if node, _ := cmt.NodeHash(key); trace[0] != hex.EncodeToString(node) {
+				t.Fatalf("CommitSize %v, %s: trace starts at %s, the node hashes to %x", commitSize, key, trace[0], node)
+			}
+		}
+	}
+	if _, err := buildTree(t, strKeys(3)).ProofTrace([]byte("absent")); err == nil {
+		t.Fatal("trace of an absent key")
+	}
+	if NewCartesianMerkleTree().RootHex() != "" {
+		t.Fatal("empty tree has a root")
+	}
+}
@@ -0,0 +1,186 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Validate checks the tree's structural invariants: keys are in search-tree
+// order, no node outranks its parent (the heap order insert and remove keep,
+// see outranks), and every node's cached Size and MerkleHash match its
+// children. A violation means proofs or lookups can't be trusted, or the tree
+// no longer has the shape a verifier reproduces from its keys (see
+// VerifyProofShape), and the error names the first offending key. The heap
+// order is not checked in SizeBalanced trees, whose shape priorities don't
+// determine.
+func (cmt *CartesianMerkleTree) Validate() error {
+	if cmt == nil {
+		return nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if _, err := cmt.validate(cmt.Root, nil, nil); err != nil {
+		return err
+	}
+	if cmt.Balancing == SizeBalanced {
+		return nil
+	}
+	return cmt.validateHeap(cmt.Root)
+}
+
+// validate checks node's subtree, whose keys must lie strictly between lo and
+// hi (nil for unbounded), and returns its size
+func (cmt *CartesianMerkleTree) validate(node *TreapNode, lo, hi []byte) (int, error) {
+	if node == nil {
+		return 0, nil
+	}
+	if len(node.Key) == 0 {
+		return 0, errors.New("node with an empty key")
+	}
+	if (lo != nil && cmt.compareKeys(node.Key, lo) <= 0) || (hi != nil && cmt.compareKeys(node.Key, hi) >= 0) {
+		return 0, fmt.Errorf("key %x is out of search-tree order", node.Key)
+	}
+	left, err := cmt.validate(node.Left, lo, node.Key)
+	if err != nil {
+		return 0, err
+	}
+	right, err := cmt.validate(node.Right, node.Key, hi)
+	if err != nil {
+		return 0, err
+	}
+	if node.Size != left+right+1 {
+		return 0, fmt.Errorf("key %x caches size %d, has %d", node.Key, node.Size, left+right+1)
+	}
+	if !bytes.Equal(node.MerkleHash, cmt.computeMerkleHash(node)) {
+		return 0, fmt.Errorf("key %x caches a stale hash", node.Key)
+	}
+	return node.Size, nil
+}
+
+// validateHeap checks that no node in node's subtree outranks its parent
+func (cmt *CartesianMerkleTree) validateHeap(node *TreapNode) error {
+	if node == nil {
+		return nil
+	}
+	for _, child := range []*TreapNode{node.Left, node.Right} {
+		if child != nil && cmt.outranks(child, node) {
+			return fmt.Errorf("key %x outranks its parent %x", child.Key, node.Key)
+		}
+	}
+	if err := cmt.validateHeap(node.Left); err != nil {
+		return err
+	}
+	return cmt.validateHeap(node.Right)
+}
+
+// VerifySelfRoot cross-checks the incrementally maintained hashes: it rebuilds
+// a shadow tree from scratch out of the sorted entries and their priorities
+// and weights (the treap shape they determine) and reports whether it matches
+// the live tree, root (GetRoot) and every interior MerkleHash included. false
+// means some node caches a wrong hash or sits where the heap order doesn't put
+// it. It errors when no shadow can be built: keys out of
+// order, or a SizeBalanced tree, whose shape priorities don't determine.
+func (cmt *CartesianMerkleTree) VerifySelfRoot() (bool, error) {
+	if cmt == nil {
+		return true, nil
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+	if cmt.Balancing == SizeBalanced {
+		return false, errors.New("self-root verification needs TreapBalancing")
+	}
+
+	// Cartesian tree construction over the sorted entries: the right spine
+	// stays on the stack, a node outranking its top takes it as left child
+	var spine []*TreapNode
+	var last []byte
+	var err error
+	inOrder(cmt.Root, func(node *TreapNode) bool {
+		if last != nil && cmt.compareKeys(last, node.Key) >= 0 {
+			err = fmt.Errorf("key %x is out of search-tree order", node.Key)
+			return false
+		}
+		last = node.Key
+		shadow := &TreapNode{Key: node.Key, Value: node.Value, Priority: node.Priority, Weight: node.Weight}
+		var child *TreapNode
+		for len(spine) > 0 && outranks(shadow, spine[len(spine)-1]) {
+			child = spine[len(spine)-1]
+			spine = spine[:len(spine)-1]
+		}
+		shadow.Left = child
+		if len(spine) > 0 {
+			spine[len(spine)-1].Right = shadow
+		}
+		spine = append(spine, shadow)
+		return true
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(spine) == 0 {
+		return cmt.Root == nil, nil
+	}
+	shadow := spine[0]
+	cmt.rehash(shadow)
+	return sameHashes(cmt.Root, shadow), nil
+}
+
+// sameHashes reports whether both subtrees have the same shape and hashes
+func sameHashes(a, b *TreapNode) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return bytes.Equal(a.MerkleHash, b.MerkleHash) && sameHashes(a.Left, b.Left) && sameHashes(a.Right, b.Right)
+}
+
+// Direction is the direction of a rotation, see forceRotateAt
+type Direction int
+
+const (
+	// RotateLeft lifts the node's right child into its place
+	RotateLeft Direction = iota
+	// RotateRight lifts the node's left child into its place
+	RotateRight
+)
+
+// forceRotateAt rotates the node holding key in dir whatever the priorities,
+// a hook for exercising the rotation code deterministically: the key set,
+// search-tree order and cached sizes and hashes must survive it, while the
+// heap order (hence the root, and Validate) is deliberately broken. It fails if key is absent or has no child to lift.
+func (cmt *CartesianMerkleTree) forceRotateAt(key []byte, dir Direction) error {
+	if cmt == nil {
+		return ErrNilTree
+	}
+	cmt.mu.Lock()
+	defer cmt.mu.Unlock()
+	defer cmt.recordRoot()
+
+	var err error
+	cmt.Root, err = cmt.rotateAt(cmt.Root, cmt.treeKey(key), dir)
+	return err
+}
+
+func (cmt *CartesianMerkleTree) rotateAt(node *TreapNode, key []byte, dir Direction) (*TreapNode, error) {
+	if node == nil {
+		return nil, fmt.Errorf("key %x not found", key)
+	}
+	var err error
+	switch cmp := cmt.compareKeys(key, node.Key); {
+	case cmp < 0:
+		node.Left, err = cmt.rotateAt(node.Left, key, dir)
+	case cmp > 0:
+		node.Right, err = cmt.rotateAt(node.Right, key, dir)
+	case dir == RotateLeft && node.Right != nil:
+		return cmt.rotateLeft(node), nil
+	case dir == RotateRight && node.Left != nil:
+		return cmt.rotateRight(node), nil
+	default:
+		return node, fmt.Errorf("key %x has no child to rotate up", key)
+	}
+	if err != nil {
+		return node, err
+	}
+	node.MerkleHash = cmt.computeMerkleHash(node)
+	return node, nil
+}
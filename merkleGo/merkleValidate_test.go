@@ -0,0 +1,140 @@
+package merkleGo
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForceRotateKeepsSearchTree(t *testing.T) {
+	keys := strKeys(40)
+	for _, key := range keys {
+		for _, dir := range []Direction{RotateLeft, RotateRight} {
+			cmt := buildTree(t, keys)
+			root, before := cmt.GetRoot(), cmt.Keys()
+
+			node := cmt.find(key)
+			lifted, back := node.Right, RotateRight
+			if dir == RotateRight {
+				lifted, back = node.Left, RotateLeft
+			}
+			if lifted == nil {
+				if err := cmt.forceRotateAt(key, dir); err == nil {
+					t.Fatalf("%s: rotated with no child to lift", key)
+				}
+				continue
+			}
+			liftedKey := lifted.Key
+
+			if err := cmt.forceRotateAt(key, dir); err != nil {
+				t.Fatalf("%s: %v", key, err)
+			}
+			if _, err := cmt.validate(cmt.Root, nil, nil); err != nil {
+				t.Fatalf("%s: rotation broke the search tree: %v", key, err)
+			}
+			if !reflect.DeepEqual(cmt.Keys(), before) {
+				t.Fatalf("%s: rotation changed the key set", key)
+			}
+			err := cmt.Validate()
+			if err == nil || !strings.Contains(err.Error(), "outranks its parent") {
+				t.Fatalf("%s: Validate after a forced rotation: %v", key, err)
+			}
+			if bytes.Equal(cmt.GetRoot(), root) {
+				t.Fatalf("%s: forced rotation kept the root", key)
+			}
+
+			// the opposite rotation at the lifted node undoes it
+			if err := cmt.forceRotateAt(liftedKey, back); err != nil {
+				t.Fatalf("%s: rotating back: %v", key, err)
+			}
+			mustValidate(t, cmt)
+			if !bytes.Equal(cmt.GetRoot(), root) {
+				t.Fatalf("%s: rotating back didn't restore the root", key)
+			}
+		}
+	}
+}
+
+func TestForceRotateAbsentKey(t *testing.T) {
+	cmt := buildTree(t, strKeys(5))
+	root := cmt.GetRoot()
+	if err := cmt.forceRotateAt([]byte("absent"), RotateLeft); err == nil {
+		t.Fatal("rotated an absent key")
+	}
+	if err := NewCartesianMerkleTree().forceRotateAt([]byte("key-0"), RotateRight); err == nil {
+		t.Fatal("rotated in an empty tree")
+	}
+	mustValidate(t, cmt)
+	if !bytes.Equal(cmt.GetRoot(), root) {
+		t.Fatal("a failed rotation changed the root")
+	}
+}
+
+func TestValidateHeapOrder(t *testing.T) {
+	weighted := NewCartesianMerkleTree()
+	for i, key := range strKeys(30) {
+		if _, err := weighted.AddWeighted(key, nil, uint64(i%4)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustValidate(t, weighted)
+
+	// a SizeBalanced shape ignores priorities, so only the search tree is checked
+	balanced := NewCartesianMerkleTree()
+	balanced.Balancing = SizeBalanced
+	mustValidate(t, fillTree(t, balanced, strKeys(30)))
+
+	cmt := buildTree(t, strKeys(30))
+	cmt.Root.Priority = make([]byte, len(cmt.Root.Priority))
+	if err := cmt.Validate(); err == nil {
+		t.Fatal("Validate missed a root that its children outrank")
+	}
+}
+
+func TestVerifySelfRoot(t *testing.T) {
+	cmt := buildTree(t, strKeys(100))
+	if ok, err := cmt.VerifySelfRoot(); !ok || err != nil {
+		t.Fatalf("sound tree: %v, %v", ok, err)
+	}
+
+	// an interior node two levels down, its parent's and the root's hashes
+	// left as they were
+	node := cmt.Root.Left.Right
+	if node == nil || node.Left == nil && node.Right == nil {
+		t.Fatal("no interior node at depth 2")
+	}
+	saved := node.MerkleHash
+	node.MerkleHash = append([]byte{}, saved...)
+	node.MerkleHash[0] ^= 1
+	root := cmt.GetRoot()
+	if ok, err := cmt.VerifySelfRoot(); ok || err != nil {
+		t.Fatalf("corrupted interior hash: %v, %v", ok, err)
+	}
+	if !bytes.Equal(cmt.GetRoot(), root) {
+		t.Fatal("VerifySelfRoot changed the root")
+	}
+	node.MerkleHash = saved
+
+	// a priority out of heap order
+	saved = node.Priority
+	node.Priority = bytes.Repeat([]byte{0xff}, len(saved))
+	if ok, err := cmt.VerifySelfRoot(); ok || err != nil {
+		t.Fatalf("priority out of heap order: %v, %v", ok, err)
+	}
+	node.Priority = saved
+
+	// keys out of order can't be rebuilt from
+	node.Key, cmt.Root.Key = cmt.Root.Key, node.Key
+	if _, err := cmt.VerifySelfRoot(); err == nil {
+		t.Fatal("keys out of order accepted")
+	}
+	node.Key, cmt.Root.Key = cmt.Root.Key, node.Key
+
+	if ok, err := cmt.VerifySelfRoot(); !ok || err != nil {
+		t.Fatalf("restored tree: %v, %v", ok, err)
+	}
+	if ok, err := NewCartesianMerkleTree().VerifySelfRoot(); !ok || err != nil {
+		t.Fatalf("empty tree: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,103 @@
+package merkleGo
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+)
+
+// ValueProof is an inclusion proof carrying the proven node's committed entry
+// (LeafHash, see nodeEntry) next to its key and value, so a stateless verifier
+// can check the value as well as the path without recomputing anything first.
+type ValueProof struct {
+	*Proof
+	LeafHash []byte
+}
+
+// valueProofJSON flattens the proof's fields next to LeafHash, as the embedded
+// Proof would be without its own MarshalJSON taking over
+type valueProofJSON struct {
+	proofJSON
+	LeafHash []byte
+}
+
+// MarshalJSON writes the proof's fields (see Proof.MarshalJSON) and LeafHash
+func (v *ValueProof) MarshalJSON() ([]byte, error) {
+	proof := v.Proof
+	if proof == nil {
+		proof = &Proof{}
+	}
+	return json.Marshal(valueProofJSON{
+		proofJSON: proofJSON{Version: ProofFormatVersion, proofFields: (*proofFields)(proof)},
+		LeafHash:  v.LeafHash,
+	})
+}
+
+// UnmarshalJSON reads MarshalJSON output
+func (v *ValueProof) UnmarshalJSON(data []byte) error {
+	in := valueProofJSON{proofJSON: proofJSON{proofFields: &proofFields{}}}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	proof, err := in.upgrade()
+	if err != nil {
+		return err
+	}
+	v.Proof = proof
+	v.LeafHash = in.LeafHash
+	return nil
+}
+
+// GenerateProofWithValue is GenerateInclusionProof with the leaf hash attached:
+// an absent key is an error wrapping ErrKeyNotFound. On ValueHashFunc trees the
+// leaf hash commits to the hashed value while Value stays the stored one.
+func (cmt *CartesianMerkleTree) GenerateProofWithValue(key []byte) (*ValueProof, error) {
+	proof, err := cmt.GenerateInclusionProof(key)
+	if err != nil {
+		return nil, err
+	}
+	return &ValueProof{Proof: proof, LeafHash: cmt.LeafHash(proof.Key, proof.Value)}, nil
+}
+
+// LeafHash returns the entry a node holding key and value commits to: the key
+// itself for a nil value, otherwise sha256 of the length-prefixed pair, with the
+// value first passed through ValueHashFunc. key must be the tree key (see TreeKey).
+func (cmt *CartesianMerkleTree) LeafHash(key, value []byte) []byte {
+	if cmt == nil {
+		return nodeEntry(key, value)
+	}
+	return cmt.entry(key, value)
+}
+
+// VerifyValueProof checks, without the tree, that proof.LeafHash is the entry of
+// key and proof.Value and that it folds to root. Tampering with either the value
+// or the leaf hash fails it. A nil hasher means the default 3-arg hasher, and
+// siblings must be TopDown (see ReorderSiblings). Like VerifyProofAgainstRoot,
+// it knows nothing of ValueHashFunc, so such trees' proofs don't verify here.
+func VerifyValueProof(key []byte, proof *ValueProof, root []byte, hasher func(a, b, c []byte) []byte) bool {
+	if proof == nil || proof.Proof == nil || len(key) == 0 {
+		return false
+	}
+	if subtle.ConstantTimeCompare(nodeEntry(key, proof.Value), proof.LeafHash) != 1 {
+		return false
+	}
+	return VerifyLeafHashAgainstRoot(proof.LeafHash, proof.Proof, root, hasher)
+}
+
+// VerifyValueProof checks proof against the tree's current root, using the
+// tree's hasher, sibling order and ValueHashFunc.
+func (cmt *CartesianMerkleTree) VerifyValueProof(key []byte, proof *ValueProof) bool {
+	if cmt == nil || proof == nil || proof.Proof == nil || len(key) == 0 {
+		return false
+	}
+	key = cmt.treeKey(key)
+	if cmt.compareKeys(key, proof.Key) != 0 {
+		return false
+	}
+	if subtle.ConstantTimeCompare(cmt.entry(key, proof.Value), proof.LeafHash) != 1 {
+		return false
+	}
+	cmt.mu.RLock()
+	root := cmt.rootHash()
+	cmt.mu.RUnlock()
+	return VerifyLeafHashAgainstRoot(proof.LeafHash, ReorderSiblings(proof.Proof, cmt.SiblingOrder, TopDown), root, cmt.hash3)
+}
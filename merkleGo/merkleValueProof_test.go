@@ -0,0 +1,54 @@
+package merkleGo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValueProof(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i := 0; i < 20; i++ {
+		if _, err := cmt.AddKV([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := cmt.GetRoot()
+	key := []byte("key-4")
+	proof, err := cmt.GenerateProofWithValue(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(proof.Value) != "value-4" || !VerifyValueProof(key, proof, root, nil) || !cmt.VerifyValueProof(key, proof) {
+		t.Fatalf("proof of %s = %s doesn't verify", key, proof.Value)
+	}
+
+	// a new value alone, or with its matching leaf hash, fails
+	tampered := &ValueProof{Proof: &Proof{}, LeafHash: proof.LeafHash}
+	*tampered.Proof = *proof.Proof
+	tampered.Value = []byte("value-5")
+	if VerifyValueProof(key, tampered, root, nil) || cmt.VerifyValueProof(key, tampered) {
+		t.Fatal("tampered value verifies")
+	}
+	tampered.LeafHash = cmt.LeafHash(key, tampered.Value)
+	if VerifyValueProof(key, tampered, root, nil) || cmt.VerifyValueProof(key, tampered) {
+		t.Fatal("tampered value and leaf hash verify")
+	}
+	if VerifyValueProof([]byte("key-5"), proof, root, nil) {
+		t.Fatal("proof verifies for another key")
+	}
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ValueProof
+	if err := json.Unmarshal(data, &decoded); err != nil || !VerifyValueProof(key, &decoded, root, nil) {
+		t.Fatalf("decoded proof doesn't verify: %v", err)
+	}
+
+	if _, err := cmt.GenerateProofWithValue([]byte("absent")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("proof of an absent key: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package merkleGo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// VerifiableProofType is the "type" of proofs exported by ToVerifiableProof
+const VerifiableProofType = "CartesianMerkleProof"
+
+// VerifiableProof is a JSON-LD shaped proof for verifiable-credential tooling.
+// Every byte field is a multibase string (base16, "f" prefix).
+type VerifiableProof struct {
+	Context         []string `json:"@context"`
+	Type            string   `json:"type"`
+	Root            string   `json:"root"`
+	Key             string   `json:"key"`
+	Value           string   `json:"value,omitempty"`
+	Existence       bool     `json:"existence"`
+	Siblings        []string `json:"siblings"`
+	Sizes           []uint64 `json:"sizes,omitempty"`
+	NonExistenceKey string   `json:"nonExistenceKey,omitempty"`
+}
+
+// ToVerifiableProof wraps the proof and the root it verifies against
+func (p *Proof) ToVerifiableProof(root []byte) VerifiableProof {
+	vp := VerifiableProof{
+		Context:   []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:      VerifiableProofType,
+		Root:      multibaseEncode(root),
+		Key:       multibaseEncode(p.Key),
+		Existence: p.Existence,
+		Siblings:  make([]string, len(p.Siblings)),
+		Sizes:     p.Sizes,
+	}
+	if p.Value != nil {
+		vp.Value = multibaseEncode(p.Value)
+	}
+	if p.NonExistenceKey != nil {
+		vp.NonExistenceKey = multibaseEncode(p.NonExistenceKey)
+	}
+	for i, s := range p.Siblings {
+		vp.Siblings[i] = multibaseEncode(s)
+	}
+	return vp
+}
+
+// FromVerifiableProof parses a VerifiableProof back into a Proof and its root
+func FromVerifiableProof(vp VerifiableProof) (*Proof, []byte, error) {
+	if vp.Type != VerifiableProofType {
+		return nil, nil, fmt.Errorf("unexpected proof type %q", vp.Type)
+	}
+	root, err := multibaseDecode(vp.Root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("root: %w", err)
+	}
+	key, err := multibaseDecode(vp.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key: %w", err)
+	}
+	proof := &Proof{
+		Existence: vp.Existence,
+		Key:       key,
+		Siblings:  make([][]byte, len(vp.Siblings)),
+		Sizes:     vp.Sizes,
+	}
+	if vp.Value != "" {
+		if proof.Value, err = multibaseDecode(vp.Value); err != nil {
+			return nil, nil, fmt.Errorf("value: %w", err)
+		}
+	}
+	if vp.NonExistenceKey != "" {
+		if proof.NonExistenceKey, err = multibaseDecode(vp.NonExistenceKey); err != nil {
+			return nil, nil, fmt.Errorf("nonExistenceKey: %w", err)
+		}
+	}
+	for i, s := range vp.Siblings {
+		if proof.Siblings[i], err = multibaseDecode(s); err != nil {
+			return nil, nil, fmt.Errorf("sibling %d: %w", i, err)
+		}
+	}
+	return proof, root, nil
+}
+
+func multibaseEncode(b []byte) string {
+	return "f" + hex.EncodeToString(b)
+}
+
+// multibaseDecode accepts base16 ("f"/"F") and base64url ("u") multibase strings
+func multibaseDecode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty multibase string")
+	}
+	switch s[0] {
+	case 'f', 'F':
+		return hex.DecodeString(s[1:])
+	case 'u':
+		return base64.RawURLEncoding.DecodeString(s[1:])
+	default:
+		return nil, fmt.Errorf("unsupported multibase prefix %q", s[0])
+	}
+}
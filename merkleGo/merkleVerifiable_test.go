@@ -0,0 +1,65 @@
+package merkleGo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestVerifiableProofRoundTrip(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	for i, key := range strKeys(20) {
+		if _, err := cmt.AddKV(key, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range [][]byte{[]byte("key-4"), []byte("absent")} {
+		proof, _ := cmt.GenerateProof(key)
+		data, err := json.Marshal(proof.ToVerifiableProof(cmt.GetRoot()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vp VerifiableProof
+		if err := json.Unmarshal(data, &vp); err != nil {
+			t.Fatal(err)
+		}
+		parsed, root, err := FromVerifiableProof(vp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proofsEqual(parsed, proof) || !bytes.Equal(root, cmt.GetRoot()) {
+			t.Fatalf("%s: round trip changed the proof", key)
+		}
+		if parsed.Existence && !VerifyProofAgainstRoot(key, parsed, root, nil) {
+			t.Fatalf("%s: parsed proof doesn't verify against its root", key)
+		}
+	}
+}
+
+func TestFromVerifiableProofRejects(t *testing.T) {
+	cmt := buildTree(t, strKeys(5))
+	proof, _ := cmt.GenerateProof([]byte("key-1"))
+	vp := proof.ToVerifiableProof(cmt.GetRoot())
+
+	b64 := vp
+	b64.Root = "u" + base64.RawURLEncoding.EncodeToString(cmt.GetRoot())
+	if _, root, err := FromVerifiableProof(b64); err != nil || !bytes.Equal(root, cmt.GetRoot()) {
+		t.Fatalf("base64url root: %x, %v", root, err)
+	}
+
+	bad := map[string]func(vp *VerifiableProof){
+		"type":    func(vp *VerifiableProof) { vp.Type = "MerkleProof" },
+		"prefix":  func(vp *VerifiableProof) { vp.Root = "z" + vp.Root[1:] },
+		"hex":     func(vp *VerifiableProof) { vp.Key = "fzz" },
+		"sibling": func(vp *VerifiableProof) { vp.Siblings = append([]string{""}, vp.Siblings...) },
+	}
+	for name, mutate := range bad {
+		v := vp
+		v.Siblings = append([]string{}, vp.Siblings...)
+		mutate(&v)
+		if _, _, err := FromVerifiableProof(v); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Root format versions, telling a verifier which reconstruction logic applies
+const (
+	// RootVersionPlain: hash3(entry, leftHash, rightHash)
+	RootVersionPlain uint32 = 1
+	// RootVersionSized: hash3(entry || size, leftHash, rightHash), see CommitSize
+	RootVersionSized uint32 = 2
+)
+
+// VersionedRoot is a root tagged with the hashing scheme that produced it: the
+// format version, the 3-arg hasher and the value hash. Functions can't be
+// serialized, so the hashers are identified by fingerprints, their output on a
+// fixed probe (see HasherFingerprint and ValueHashFingerprint).
+type VersionedRoot struct {
+	Version uint32
+	Root    []byte
+	// Hasher fingerprints the tree's 3-arg hasher, DomainSeparator included
+	Hasher []byte
+	// Domain is the tree's DomainSeparator, nil when unset
+	Domain []byte
+	// ValueHash fingerprints ValueHashFunc, nil when values are committed raw
+	ValueHash []byte
+}
+
+// VersionedRoot returns the current root tagged with the tree's scheme
+func (cmt *CartesianMerkleTree) VersionedRoot() VersionedRoot {
+	v := VersionedRoot{Version: cmt.rootVersion(), Root: cmt.GetRoot(), Hasher: HasherFingerprint(default3ArgHash)}
+	if cmt != nil {
+		v.Hasher = HasherFingerprint(cmt.hash3)
+		v.Domain = cmt.DomainSeparator
+		v.ValueHash = ValueHashFingerprint(cmt.ValueHashFunc)
+	}
+	return v
+}
+
+func (cmt *CartesianMerkleTree) rootVersion() uint32 {
+	if cmt != nil && cmt.CommitSize {
+		return RootVersionSized
+	}
+	return RootVersionPlain
+}
+
+// fingerprintProbe is the input the hashers are fingerprinted on
+var fingerprintProbe = []byte("merkleTrees/VersionedRoot")
+
+// HasherFingerprint identifies a 3-arg hasher by its output on a fixed probe,
+// for comparison with VersionedRoot.Hasher
+func HasherFingerprint(hasher func(a, b, c []byte) []byte) []byte {
+	return hasher(fingerprintProbe, make([]byte, 32), make([]byte, 32))
+}
+
+// ValueHashFingerprint identifies a ValueHashFunc by its output on a fixed
+// probe, for comparison with VersionedRoot.ValueHash. nil for a nil function.
+func ValueHashFingerprint(valueHash func(value []byte) []byte) []byte {
+	if valueHash == nil {
+		return nil
+	}
+	return valueHash(fingerprintProbe)
+}
+
+// VerifyProofVersioned picks the reconstruction logic from root.Version and
+// rejects proofs whose format doesn't match it, as well as unknown versions.
+// A nil hasher means the default 3-arg hasher, domain separated by root.Domain
+// when set. The hasher and valueHash (which pre-hashes the proof's value, as
+// the tree's ValueHashFunc; nil for raw values) must match root's fingerprints.
+func VerifyProofVersioned(key []byte, proof *Proof, root VersionedRoot, hasher func(a, b, c []byte) []byte, valueHash func(value []byte) []byte) (bool, error) {
+	if proof == nil {
+		return false, errors.New("nil proof")
+	}
+	switch root.Version {
+	case RootVersionPlain:
+		if len(proof.Sizes) > 0 {
+			return false, errors.New("proof carries subtree sizes but the root is not size-committed")
+		}
+	case RootVersionSized:
+		if len(proof.Sizes) == 0 {
+			return false, errors.New("size-committed root but the proof carries no subtree sizes")
+		}
+	default:
+		return false, fmt.Errorf("unknown root version %d", root.Version)
+	}
+	if hasher == nil {
+		hasher = default3ArgHash
+		if root.Domain != nil {
+			hasher = DomainHasher(root.Domain)
+		}
+	}
+	if root.Hasher != nil && !bytes.Equal(HasherFingerprint(hasher), root.Hasher) {
+		return false, errors.New("hasher does not match the root's")
+	}
+	if !bytes.Equal(ValueHashFingerprint(valueHash), root.ValueHash) {
+		return false, errors.New("value hash does not match the root's")
+	}
+	if valueHash != nil && proof.Value != nil {
+		hashed := *proof
+		hashed.Value = valueHash(proof.Value)
+		proof = &hashed
+	}
+	return VerifyProofAgainstRoot(key, proof, root.Root, hasher), nil
+}
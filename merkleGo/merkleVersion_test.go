@@ -0,0 +1,90 @@
+package merkleGo
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func versionedProof(t *testing.T, cmt *CartesianMerkleTree) (*Proof, VersionedRoot) {
+	t.Helper()
+	for i, key := range strKeys(20) {
+		if _, err := cmt.AddKV(key, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proof, err := cmt.GenerateProof([]byte("key-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof, cmt.VersionedRoot()
+}
+
+func TestVersionedRootScheme(t *testing.T) {
+	plain := NewCartesianMerkleTree()
+	sized := NewCartesianMerkleTree()
+	sized.CommitSize = true
+	plainProof, plainRoot := versionedProof(t, plain)
+	sizedProof, sizedRoot := versionedProof(t, sized)
+	if plainRoot.Version != RootVersionPlain || sizedRoot.Version != RootVersionSized {
+		t.Fatalf("versions %d and %d", plainRoot.Version, sizedRoot.Version)
+	}
+
+	if ok, err := VerifyProofVersioned([]byte("key-3"), plainProof, plainRoot, nil, nil); !ok || err != nil {
+		t.Fatalf("plain: %v, %v", ok, err)
+	}
+	if ok, err := VerifyProofVersioned([]byte("key-3"), sizedProof, sizedRoot, nil, nil); !ok || err != nil {
+		t.Fatalf("sized: %v, %v", ok, err)
+	}
+	if _, err := VerifyProofVersioned([]byte("key-3"), plainProof, sizedRoot, nil, nil); err == nil {
+		t.Fatal("plain proof accepted by a sized root")
+	}
+	if _, err := VerifyProofVersioned([]byte("key-3"), sizedProof, plainRoot, nil, nil); err == nil {
+		t.Fatal("sized proof accepted by a plain root")
+	}
+	plainRoot.Version = 99
+	if _, err := VerifyProofVersioned([]byte("key-3"), plainProof, plainRoot, nil, nil); err == nil {
+		t.Fatal("unknown version accepted")
+	}
+}
+
+func TestVersionedRootHashers(t *testing.T) {
+	domain := NewCartesianMerkleTreeWithDomain([]byte("app"))
+	proof, root := versionedProof(t, domain)
+	if string(root.Domain) != "app" {
+		t.Fatalf("domain %q", root.Domain)
+	}
+	if ok, err := VerifyProofVersioned([]byte("key-3"), proof, root, nil, nil); !ok || err != nil {
+		t.Fatalf("domain from the root: %v, %v", ok, err)
+	}
+	if _, err := VerifyProofVersioned([]byte("key-3"), proof, root, default3ArgHash, nil); err == nil {
+		t.Fatal("hasher without the domain accepted")
+	}
+
+	keccak := NewCartesianMerkleTreeWithHashFactory(sha3.NewLegacyKeccak256)
+	proof, root = versionedProof(t, keccak)
+	if _, err := VerifyProofVersioned([]byte("key-3"), proof, root, nil, nil); err == nil {
+		t.Fatal("default hasher accepted for a keccak root")
+	}
+	if ok, err := VerifyProofVersioned([]byte("key-3"), proof, root, FactoryHasher(sha3.NewLegacyKeccak256, nil), nil); !ok || err != nil {
+		t.Fatalf("keccak: %v, %v", ok, err)
+	}
+
+	valueHash := func(value []byte) []byte {
+		h := sha256.Sum256(value)
+		return h[:]
+	}
+	hashed := NewCartesianMerkleTree()
+	hashed.ValueHashFunc = valueHash
+	proof, root = versionedProof(t, hashed)
+	if root.ValueHash == nil {
+		t.Fatal("no value hash fingerprint")
+	}
+	if _, err := VerifyProofVersioned([]byte("key-3"), proof, root, nil, nil); err == nil {
+		t.Fatal("raw values accepted for a value-hashing root")
+	}
+	if ok, err := VerifyProofVersioned([]byte("key-3"), proof, root, nil, valueHash); !ok || err != nil {
+		t.Fatalf("value hash: %v, %v", ok, err)
+	}
+}
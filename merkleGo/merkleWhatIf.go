@@ -0,0 +1,78 @@
+package merkleGo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RootWithout returns the root the tree would have after removing keys,
+// leaving the tree untouched. Only the nodes on the affected paths are copied.
+// It fails if any key is absent.
+func (cmt *CartesianMerkleTree) RootWithout(keys [][]byte) ([]byte, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	root := cmt.Root
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errors.New("key cannot be empty")
+		}
+		var removed bool
+		root, removed = cmt.removeCopy(root, cmt.treeKey(key))
+		if !removed {
+			return nil, fmt.Errorf("key %x not found", key)
+		}
+	}
+	if root == nil {
+		return nil, nil
+	}
+	return root.MerkleHash, nil
+}
+
+// removeCopy is remove on a persistent tree: nodes that would be mutated are
+// copied first, so the original nodes (and the live tree) never change
+func (cmt *CartesianMerkleTree) removeCopy(node *TreapNode, key []byte) (*TreapNode, bool) {
+	if node == nil {
+		return nil, false
+	}
+	var removed bool
+	n := copyNode(node)
+	cmp := cmt.compareKeys(key, node.Key)
+	if cmp < 0 {
+		n.Left, removed = cmt.removeCopy(node.Left, key)
+	} else if cmp > 0 {
+		n.Right, removed = cmt.removeCopy(node.Right, key)
+	} else {
+		removed = true
+		if n.Left == nil {
+			return n.Right, true
+		}
+		if n.Right == nil {
+			return n.Left, true
+		}
+		// the rotation rewires the promoted child too, so it needs its own copy
+		if cmt.promoteRight(n) {
+			n.Right = copyNode(n.Right)
+			n = cmt.rotateLeft(n)
+			n.Left, _ = cmt.removeCopy(n.Left, key)
+		} else {
+			n.Left = copyNode(n.Left)
+			n = cmt.rotateRight(n)
+			n.Right, _ = cmt.removeCopy(n.Right, key)
+		}
+	}
+	if !removed {
+		return node, false
+	}
+	n.Size = subtreeSize(n)
+	n.MerkleHash = cmt.computeMerkleHash(n)
+	return n, true
+}
+
+func copyNode(node *TreapNode) *TreapNode {
+	n := *node
+	return &n
+}
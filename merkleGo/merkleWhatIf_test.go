@@ -0,0 +1,45 @@
+package merkleGo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRootWithoutMatchesRemove(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(60))
+		before := cmt.GetRoot()
+		for _, drop := range [][][]byte{
+			{[]byte("key-0")},
+			{cmt.Root.Key, []byte("key-7"), []byte("key-59")},
+			strKeys(60),
+		} {
+			got, err := cmt.RootWithout(drop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clone := NewCartesianMerkleTree()
+			clone.CommitSize = commitSize
+			fillTree(t, clone, strKeys(60))
+			for _, key := range drop {
+				if err := clone.Remove(key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(got, clone.GetRoot()) {
+				t.Fatalf("CommitSize %v, without %d keys: %x, removing gives %x", commitSize, len(drop), got, clone.GetRoot())
+			}
+			if !bytes.Equal(cmt.GetRoot(), before) {
+				t.Fatal("RootWithout changed the tree")
+			}
+		}
+		mustValidate(t, cmt)
+	}
+
+	cmt := buildTree(t, strKeys(5))
+	if _, err := cmt.RootWithout([][]byte{[]byte("key-1"), []byte("absent")}); err == nil {
+		t.Fatal("RootWithout an absent key succeeded")
+	}
+}
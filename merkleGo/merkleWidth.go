@@ -0,0 +1,41 @@
+package merkleGo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// checkSiblingWidths rejects a TopDown proof whose child-hash siblings aren't
+// hashes: each must be width bytes (the hasher's output, i.e. the root's
+// length) or the 32 zero bytes standing for an empty child, whatever the
+// hasher. Node-key siblings are exempt, except that they can't be empty and,
+// when maxEntry > 0, can't be longer than it.
+func checkSiblingWidths(proof *Proof, width, maxEntry int) error {
+	last := len(proof.Siblings) - 2
+	for i, s := range proof.Siblings {
+		if i%2 == 1 || (proof.Existence && i >= last) {
+			if len(s) != width && !(len(s) == len(zeroHash) && bytes.Equal(s, zeroHash)) {
+				return fmt.Errorf("%w: sibling %d is %d bytes, want a %d-byte hash", ErrMalformedProof, i, len(s), width)
+			}
+			continue
+		}
+		if len(s) == 0 || (maxEntry > 0 && len(s) > maxEntry) {
+			return fmt.Errorf("%w: node key sibling %d is %d bytes", ErrMalformedProof, i, len(s))
+		}
+	}
+	return nil
+}
+
+// maxEntryWidth bounds the node-key siblings of the tree's proofs: with
+// MaxKeyLength, keys and value entries are at most max(MaxKeyLength, 32) bytes
+// (see TreeKey and nodeEntry). 0 means unbounded.
+func (cmt *CartesianMerkleTree) maxEntryWidth() int {
+	if cmt.MaxKeyLength <= 0 {
+		return 0
+	}
+	if cmt.MaxKeyLength < sha256.Size {
+		return sha256.Size
+	}
+	return cmt.MaxKeyLength
+}
@@ -0,0 +1,55 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Siblings of the wrong width are rejected as malformed, before any root is
+// computed and compared
+func TestSiblingWidths(t *testing.T) {
+	cmt := NewCartesianMerkleTree()
+	cmt.MaxKeyLength = 40
+	keys := strKeys(50)
+	fillTree(t, cmt, keys)
+	root := cmt.GetRoot()
+	var key []byte
+	var proof *Proof
+	for _, k := range keys {
+		if p, _ := cmt.GenerateProof(k); len(p.Siblings) >= 6 {
+			key, proof = k, p
+			break
+		}
+	}
+	if proof == nil {
+		t.Fatal("no proof with two ancestors")
+	}
+
+	with := func(i int, sibling []byte) *Proof {
+		p := *proof
+		p.Siblings = append([][]byte{}, proof.Siblings...)
+		p.Siblings[i] = sibling
+		return &p
+	}
+	cases := map[string]*Proof{
+		"truncated hash":      with(1, proof.Siblings[1][:31]),
+		"oversized hash":      with(1, append(append([]byte{}, proof.Siblings[1]...), 0)),
+		"truncated last hash": with(len(proof.Siblings)-1, proof.Siblings[len(proof.Siblings)-1][:16]),
+		"empty node key":      with(0, []byte{}),
+		"oversized node key":  with(0, bytes.Repeat([]byte{'k'}, 41)),
+	}
+	for name, bad := range cases {
+		if ok, err := cmt.VerifyProofDetailed(key, bad); ok || !errors.Is(err, ErrMalformedProof) {
+			t.Errorf("%s: %v, %v", name, ok, err)
+		}
+		if VerifyProofAgainstRoot(key, bad, root, nil) {
+			t.Errorf("%s: verifies against the root", name)
+		}
+	}
+
+	// a well-formed sibling of the right width gets as far as the root comparison
+	if _, err := cmt.VerifyProofDetailed(key, with(1, make([]byte, 32))); !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("zero hash sibling: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package merkleGo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// UpdateWitness is the authentication path of a present key, enough for a
+// light client to recompute the root after changing that key's value
+type UpdateWitness struct {
+	Key      []byte
+	Siblings [][]byte // same layout as Proof.Siblings
+	Sizes    []uint64 // same as Proof.Sizes, set for CommitSize trees
+}
+
+// UpdateWitness returns the witness needed to apply a value update to key offline
+func (cmt *CartesianMerkleTree) UpdateWitness(key []byte) (*UpdateWitness, error) {
+	proof, err := cmt.GenerateProof(key)
+	if err != nil {
+		return nil, err
+	}
+	if !proof.Existence {
+		return nil, fmt.Errorf("key %x not found", key)
+	}
+	return &UpdateWitness{Key: proof.Key, Siblings: proof.Siblings, Sizes: proof.Sizes}, nil
+}
+
+// ApplyUpdate returns the root the tree would have after Update(witness.Key, newValue),
+// without access to the tree. A nil hasher means the default 3-arg hasher.
+func ApplyUpdate(witness *UpdateWitness, newValue []byte, hasher func(a, b, c []byte) []byte) ([]byte, error) {
+	if witness == nil || len(witness.Key) == 0 {
+		return nil, errors.New("witness has no key")
+	}
+	if hasher == nil {
+		hasher = default3ArgHash
+	}
+	var root []byte
+	if len(witness.Sizes) > 0 {
+		root = foldSizedSiblings(nodeEntry(witness.Key, newValue), witness.Siblings, witness.Sizes, hasher)
+	} else {
+		root = foldSiblings(nodeEntry(witness.Key, newValue), witness.Siblings, hasher)
+	}
+	if root == nil {
+		return nil, errors.New("malformed witness siblings")
+	}
+	return root, nil
+}
+
+// InsertWitness is the search path of an absent key, enough for a light client
+// to recompute the root after inserting that key, rotations included. The
+// insert's rotations amount to splitting the subtree the new node lands on
+// along the same path, so every node a rotation moves is on the path, and
+// every subtree it re-parents is one of the path's off-path children.
+type InsertWitness struct {
+	Key      []byte // tree key to insert (see TreeKey)
+	Priority []byte // its priority; the verifier may recompute it from Key
+	Path     []InsertStep
+	// CommitSize is set for CommitSize trees, whose entries commit to sizes
+	CommitSize bool
+}
+
+// InsertStep is one node of an InsertWitness path, root first
+type InsertStep struct {
+	Entry     []byte // the node's committed entry, as in proof siblings
+	Priority  []byte
+	Weight    uint64
+	Right     bool   // the search goes right: the node's key is smaller than Key
+	Other     []byte // hash of the child off the search path
+	OtherSize uint64 // its size, meaningful for CommitSize trees only
+}
+
+// GenerateInsertWitness returns the witness needed to apply Add(key) offline,
+// see ComputeRootAfterInsert. It fails if key is present, if its priority is
+// invalid, and on SizeBalanced trees, whose inserts depend on more than the path.
+func (cmt *CartesianMerkleTree) GenerateInsertWitness(key []byte) (*InsertWitness, error) {
+	if cmt == nil {
+		return nil, ErrNilTree
+	}
+	if len(key) == 0 {
+		return nil, errors.New("key cannot be empty")
+	}
+	if cmt.Balancing == SizeBalanced {
+		return nil, errors.New("insert witnesses need TreapBalancing")
+	}
+	cmt.mu.RLock()
+	defer cmt.mu.RUnlock()
+
+	key = cmt.treeKey(key)
+	if cmt.find(key) != nil {
+		return nil, fmt.Errorf("key %x already present", key)
+	}
+	priority, err := cmt.priority(key)
+	if err != nil {
+		return nil, err
+	}
+	w := &InsertWitness{Key: key, Priority: priority, CommitSize: cmt.CommitSize}
+	for node := cmt.Root; node != nil; {
+		step := InsertStep{
+			Entry:    cmt.entry(node.Key, node.Value),
+			Priority: node.Priority,
+			Weight:   node.Weight,
+			Right:    cmt.compareKeys(key, node.Key) > 0,
+		}
+		other, next := node.Left, node.Right
+		if !step.Right {
+			other, next = node.Right, node.Left
+		}
+		step.Other = make([]byte, 32)
+		if other != nil {
+			step.Other = other.MerkleHash
+			step.OtherSize = uint64(other.Size)
+		}
+		w.Path = append(w.Path, step)
+		node = next
+	}
+	return w, nil
+}
+
+// PreviousRoot returns the root the witness path commits to, which a light
+// client must check against the root it trusts before using the witness.
+// A nil hasher means the default 3-arg hasher.
+func (w *InsertWitness) PreviousRoot(hasher func(a, b, c []byte) []byte) []byte {
+	if hasher == nil {
+		hasher = default3ArgHash
+	}
+	if len(w.Path) == 0 {
+		return nil
+	}
+	hash, _ := w.fold(w.Path, make([]byte, 32), 0, hasher)
+	return hash
+}
+
+// ComputeRootAfterInsert returns the root the tree would have after Add(key),
+// key-only, without access to the tree. key must be the witness's (tree) key.
+// A nil hasher means the default 3-arg hasher. The new node lands above the
+// first path node it outranks (higher weight, then priority, then smaller
+// key, as the tree decides), and the path below it is split into its subtrees.
+func ComputeRootAfterInsert(witness *InsertWitness, key []byte, hasher func(a, b, c []byte) []byte) ([]byte, error) {
+	return ComputeRootAfterInsertKV(witness, key, nil, hasher)
+}
+
+// ComputeRootAfterInsertKV is ComputeRootAfterInsert for AddKV(key, value),
+// with value committed as is (trees with ValueHashFunc need it pre-hashed)
+func ComputeRootAfterInsertKV(witness *InsertWitness, key, value []byte, hasher func(a, b, c []byte) []byte) ([]byte, error) {
+	if witness == nil || len(witness.Key) == 0 || len(witness.Priority) == 0 {
+		return nil, errors.New("witness has no key")
+	}
+	if !bytes.Equal(key, witness.Key) {
+		return nil, fmt.Errorf("witness is for key %x, not %x", witness.Key, key)
+	}
+	if hasher == nil {
+		hasher = default3ArgHash
+	}
+
+	// the new node goes above the first node it outranks, with weight 0
+	at := len(witness.Path)
+	for i, step := range witness.Path {
+		if step.Weight > 0 {
+			continue
+		}
+		cmp := bytes.Compare(witness.Priority, step.Priority)
+		if cmp > 0 || (cmp == 0 && !step.Right) {
+			at = i
+			break
+		}
+	}
+
+	// split the path below into the nodes smaller than key, which chain down
+	// the new node's left subtree through their right children, and the larger
+	// ones, chaining down the right subtree through their left children
+	var left, right []InsertStep
+	for _, step := range witness.Path[at:] {
+		if step.Right {
+			left = append(left, step)
+		} else {
+			right = append(right, step)
+		}
+	}
+	zero := make([]byte, 32)
+	leftHash, leftSize := witness.fold(left, zero, 0, hasher)
+	rightHash, rightSize := witness.fold(right, zero, 0, hasher)
+
+	entry := nodeEntry(key, value)
+	size := leftSize + rightSize + 1
+	if witness.CommitSize {
+		entry = sizedEntry(entry, size)
+	}
+	root, _ := witness.fold(witness.Path[:at], hasher(entry, leftHash, rightHash), size, hasher)
+	return root, nil
+}
+
+// fold hashes path bottom-up over the subtree hash (of size size) hanging below
+// its last node on the search side, returning the first node's hash and size
+func (w *InsertWitness) fold(path []InsertStep, hash []byte, size uint64, hasher func(a, b, c []byte) []byte) ([]byte, uint64) {
+	for i := len(path) - 1; i >= 0; i-- {
+		step := path[i]
+		size += step.OtherSize + 1
+		entry := step.Entry
+		if w.CommitSize {
+			entry = sizedEntry(entry, size)
+		}
+		if step.Right {
+			hash = hasher(entry, step.Other, hash)
+		} else {
+			hash = hasher(entry, hash, step.Other)
+		}
+	}
+	return hash, size
+}
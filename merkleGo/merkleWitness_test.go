@@ -0,0 +1,98 @@
+package merkleGo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestApplyUpdateMatchesUpdate(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		for i, key := range strKeys(40) {
+			if _, err := cmt.AddKV(key, []byte{byte(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, key := range strKeys(40)[:10] {
+			witness, err := cmt.UpdateWitness(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ApplyUpdate(witness, []byte("new"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cmt.Update(key, []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, cmt.GetRoot()) {
+				t.Fatalf("CommitSize %v, %s: ApplyUpdate gives %x, Update %x", commitSize, key, got, cmt.GetRoot())
+			}
+		}
+	}
+
+	cmt := buildTree(t, strKeys(5))
+	if _, err := cmt.UpdateWitness([]byte("absent")); err == nil {
+		t.Fatal("witness for an absent key")
+	}
+	if _, err := ApplyUpdate(&UpdateWitness{}, nil, nil); err == nil {
+		t.Fatal("applied a witness without a key")
+	}
+}
+
+// The offline root matches Add's, whether the new key lands as a leaf or is
+// rotated up above part of its path
+func TestComputeRootAfterInsert(t *testing.T) {
+	for _, commitSize := range []bool{false, true} {
+		cmt := NewCartesianMerkleTree()
+		cmt.CommitSize = commitSize
+		fillTree(t, cmt, strKeys(30))
+		var leaves, rotated int
+		for i := 0; i < 60; i++ {
+			key := []byte(fmt.Sprintf("new-%d", i))
+			witness, err := cmt.GenerateInsertWitness(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(witness.PreviousRoot(nil), cmt.GetRoot()) {
+				t.Fatalf("CommitSize %v, %s: witness doesn't commit to the current root", commitSize, key)
+			}
+			var got []byte
+			if i%2 == 0 {
+				got, err = ComputeRootAfterInsert(witness, key, nil)
+			} else {
+				got, err = ComputeRootAfterInsertKV(witness, key, []byte("value"), nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i%2 == 0 {
+				fillTree(t, cmt, [][]byte{key})
+			} else if _, err := cmt.AddKV(key, []byte("value")); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, cmt.GetRoot()) {
+				t.Fatalf("CommitSize %v, %s: computed %x, Add gives %x", commitSize, key, got, cmt.GetRoot())
+			}
+			if node := cmt.find(key); node.Left == nil && node.Right == nil {
+				leaves++
+			} else {
+				rotated++
+			}
+		}
+		if leaves == 0 || rotated == 0 {
+			t.Fatalf("CommitSize %v: %d leaf inserts, %d rotated", commitSize, leaves, rotated)
+		}
+	}
+
+	cmt := buildTree(t, strKeys(5))
+	if _, err := cmt.GenerateInsertWitness([]byte("key-1")); err == nil {
+		t.Fatal("witness for a present key")
+	}
+	witness, _ := cmt.GenerateInsertWitness([]byte("new"))
+	if _, err := ComputeRootAfterInsert(witness, []byte("other"), nil); err == nil {
+		t.Fatal("witness applied to another key")
+	}
+}